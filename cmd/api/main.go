@@ -64,16 +64,24 @@ func main() {
 	// ── Initialize repositories ───────────────────────────────────────────────
 	userRepo := repository.NewUserRepository(firebaseClient)
 	productRepo := repository.NewProductRepository(firebaseClient)
+	categoryRepo := repository.NewCategoryRepository(firebaseClient)
+	inventoryRepo := repository.NewInventoryRepository(firebaseClient)
 	orderRepo := repository.NewOrderRepository(firebaseClient)
 	discountRepo := repository.NewDiscountRepository(firebaseClient)
+	promotionRepo := repository.NewPromotionRepository(firebaseClient)
 	reviewRepo := repository.NewReviewRepository(firebaseClient)
 	locationRepo := repository.NewLocationRepository(firebaseClient)
 	galleryRepo := repository.NewGalleryRepository(firebaseClient)
 	siteConfigRepo := repository.NewSiteConfigRepository(firebaseClient)
 	cartRepo := repository.NewCartRepository(firebaseClient)
+	cartReminderRepo := repository.NewCartReminderRepository(firebaseClient)
 	passwordResetRepo := repository.NewPasswordResetRepository(firebaseClient)
 	dashboardRepo := repository.NewDashboardRepository(firebaseClient)
 	notificationRepo := repository.NewNotificationRepository(firebaseClient)
+	stockSubscriptionRepo := repository.NewStockSubscriptionRepository(firebaseClient)
+	priceHistoryRepo := repository.NewPriceHistoryRepository(firebaseClient)
+	wishlistRepo := repository.NewWishlistRepository(firebaseClient)
+	customerPurchaseRepo := repository.NewCustomerPurchaseRepository(firebaseClient)
 
 	// ── SSE Hub ───────────────────────────────────────────────────────────────
 	// Se crea antes que los services para poder inyectarlo en notificationService
@@ -91,23 +99,64 @@ func main() {
 		FrontendURL:  cfg.FrontendURL,
 	})
 	passwordResetService := services.NewPasswordResetService(passwordResetRepo, userRepo, emailService, cfg)
-	productService := services.NewProductService(productRepo)
-	cartService := services.NewCartService(cartRepo, productRepo)
-	dashboardService := services.NewDashboardService(dashboardRepo, orderRepo, userRepo)
-	discountService := services.NewDiscountService(discountRepo)
+	cacheService := services.NewCacheService(redisClient, cfg)
+	productSearchIndex := services.NewProductSearchIndex(productRepo)
+	categoryService := services.NewCategoryService(categoryRepo, productRepo, productSearchIndex, cacheService)
+	promotionService := services.NewPromotionService(promotionRepo, categoryRepo, cacheService, cfg)
+	cartService := services.NewCartService(cartRepo, productRepo, orderRepo, promotionService, cfg)
+	dashboardService := services.NewDashboardService(dashboardRepo, orderRepo, userRepo, discountRepo, cacheService)
+	discountService := services.NewDiscountService(discountRepo, orderRepo, categoryRepo)
 	notificationService := services.NewNotificationService(notificationRepo, sseHub) // ← sseHub inyectado
+	stockAlertService := services.NewStockAlertService(
+		stockSubscriptionRepo,
+		productRepo,
+		userRepo,
+		notificationService,
+		emailService,
+		cfg,
+	)
+	wishlistService := services.NewWishlistService(wishlistRepo, productRepo, userRepo, notificationService, cfg)
+	inventoryService := services.NewInventoryService(
+		productRepo,
+		inventoryRepo,
+		userRepo,
+		notificationService,
+		emailService,
+		stockAlertService,
+		wishlistService,
+		cacheService,
+		cfg,
+	)
+	checkoutService := services.NewCheckoutService(productRepo, orderRepo, discountService, promotionService, cfg)
+	productService := services.NewProductService(productRepo, categoryRepo, dashboardRepo, priceHistoryRepo, inventoryService, wishlistService, productSearchIndex, cacheService, cfg)
+	cartRecoveryService := services.NewCartRecoveryService(
+		cartRepo,
+		cartReminderRepo,
+		orderRepo,
+		userRepo,
+		discountService,
+		emailService,
+		dashboardService,
+		cfg,
+	)
 	orderService := services.NewOrderService(
 		orderRepo,
 		productRepo,
+		inventoryService,
 		cartRepo,
 		dashboardService,
 		discountService,
+		checkoutService,
+		cartRecoveryService,
 		notificationService,
+		customerPurchaseRepo,
 	)
-	reviewService := services.NewReviewService(reviewRepo, productRepo)
-	locationService := services.NewLocationService(locationRepo)
+	recommendationService := services.NewRecommendationService(orderRepo, productRepo, cartRepo, cfg)
+	productImportService := services.NewProductImportService(productService, inventoryService, productRepo, categoryRepo)
+	reviewService := services.NewReviewService(reviewRepo, productRepo, cacheService)
+	locationService := services.NewLocationService(locationRepo, cacheService)
 	galleryService := services.NewGalleryService(galleryRepo)
-	siteConfigService := services.NewSiteConfigService(siteConfigRepo)
+	siteConfigService := services.NewSiteConfigService(siteConfigRepo, cacheService)
 	wompiService := services.NewWompiService()
 
 	// Initialize upload service (Cloudinary)
@@ -118,10 +167,18 @@ func main() {
 	}
 
 	// ── Initialize handlers ───────────────────────────────────────────────────
-	authHandler := handlers.NewAuthHandler(authService, passwordResetService)
+	authHandler := handlers.NewAuthHandler(authService, passwordResetService, cartService)
 	productHandler := handlers.NewProductHandler(productService)
-	orderHandler := handlers.NewOrderHandler(orderService)
+	productImportHandler := handlers.NewProductImportHandler(productImportService)
+	categoryHandler := handlers.NewCategoryHandler(categoryService)
+	inventoryHandler := handlers.NewInventoryHandler(inventoryService)
+	stockAlertHandler := handlers.NewStockAlertHandler(stockAlertService)
+	recommendationHandler := handlers.NewRecommendationHandler(recommendationService, cartService)
+	wishlistHandler := handlers.NewWishlistHandler(wishlistService)
+	orderHandler := handlers.NewOrderHandler(orderService, cartService)
+	checkoutHandler := handlers.NewCheckoutHandler(checkoutService)
 	discountHandler := handlers.NewDiscountHandler(discountService)
+	promotionHandler := handlers.NewPromotionHandler(promotionService)
 	reviewHandler := handlers.NewReviewHandler(reviewService)
 	locationHandler := handlers.NewLocationHandler(locationService)
 	galleryHandler := handlers.NewGalleryHandler(galleryService, uploadService)
@@ -144,7 +201,7 @@ func main() {
 	// Setup routes
 	setupRoutes(
 		router, cfg, firebaseClient, redisClient,
-		authHandler, productHandler, orderHandler, discountHandler,
+		authHandler, productHandler, productImportHandler, categoryHandler, inventoryHandler, stockAlertHandler, recommendationHandler, wishlistHandler, orderHandler, checkoutHandler, discountHandler, promotionHandler,
 		reviewHandler, locationHandler, galleryHandler, siteConfigHandler,
 		cartHandler, dashboardHandler,
 		wompiHandler,
@@ -161,6 +218,17 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// ── Background jobs ───────────────────────────────────────────────────────
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	go inventoryService.StartLowStockDigest(jobsCtx)
+	go stockAlertService.StartRestockAlerts(jobsCtx)
+	go recommendationService.StartRefresh(jobsCtx)
+	go wishlistService.StartSaleAlerts(jobsCtx)
+	go cartService.StartGuestCartCleanup(jobsCtx)
+	go cartRecoveryService.StartReminders(jobsCtx)
+
 	// Start server in a goroutine
 	go func() {
 		logger.Infof("Starting server on port %s", cfg.Port)
@@ -177,6 +245,7 @@ func main() {
 	<-quit
 
 	logger.Info("Shutting down server...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -195,8 +264,16 @@ func setupRoutes(
 	redis database.RedisClient,
 	authHandler *handlers.AuthHandler,
 	productHandler *handlers.ProductHandler,
+	productImportHandler *handlers.ProductImportHandler,
+	categoryHandler *handlers.CategoryHandler,
+	inventoryHandler *handlers.InventoryHandler,
+	stockAlertHandler *handlers.StockAlertHandler,
+	recommendationHandler *handlers.RecommendationHandler,
+	wishlistHandler *handlers.WishlistHandler,
 	orderHandler *handlers.OrderHandler,
+	checkoutHandler *handlers.CheckoutHandler,
 	discountHandler *handlers.DiscountHandler,
+	promotionHandler *handlers.PromotionHandler,
 	reviewHandler *handlers.ReviewHandler,
 	locationHandler *handlers.LocationHandler,
 	galleryHandler *handlers.GalleryHandler,
@@ -250,12 +327,25 @@ func setupRoutes(
 			auth.POST("/reset-password", authHandler.ResetPassword)
 		}
 
+		// Baja de emails de marketing (public, enlace firmado)
+		v1.POST("/users/unsubscribe", authHandler.Unsubscribe)
+
+		// Cancelar aviso de reposición (public, enlace firmado)
+		v1.POST("/stock-alerts/cancel", stockAlertHandler.CancelByToken)
+
 		// ── Users (protected) ─────────────────────────────────────────────────
 		users := v1.Group("/users")
 		users.Use(middleware.AuthMiddleware(cfg))
 		{
 			users.GET("/me", authHandler.GetProfile)
 			users.PUT("/me", authHandler.UpdateProfile)
+			users.GET("/me/stock-alerts", stockAlertHandler.GetMySubscriptions)
+			users.DELETE("/me/stock-alerts/:id", stockAlertHandler.Unsubscribe)
+			users.GET("/me/wishlist", wishlistHandler.GetMyWishlist)
+			users.POST("/me/wishlist/items", wishlistHandler.AddItem)
+			users.DELETE("/me/wishlist/items/:productId", wishlistHandler.RemoveItem)
+			users.POST("/me/wishlist/share", wishlistHandler.EnableSharing)
+			users.DELETE("/me/wishlist/share", wishlistHandler.DisableSharing)
 
 			adminUsers := users.Group("")
 			adminUsers.Use(middleware.RequireAdmin())
@@ -272,19 +362,57 @@ func setupRoutes(
 			products.GET("", productHandler.GetAllProducts)
 			products.GET("/featured", productHandler.GetFeaturedProducts)
 			products.GET("/search", productHandler.SearchProducts)
+			products.GET("/slug/:slug", productHandler.GetProductBySlug)
 			products.GET("/:id", productHandler.GetProduct)
+			products.GET("/:id/related", recommendationHandler.GetRelated)
+			products.POST("/:id/stock-alerts", middleware.OptionalAuth(cfg), stockAlertHandler.Subscribe)
 
 			adminProducts := products.Group("")
 			adminProducts.Use(middleware.AuthMiddleware(cfg))
 			adminProducts.Use(middleware.RequireAdmin())
 			{
 				adminProducts.POST("", productHandler.CreateProduct)
+				adminProducts.POST("/backfill-slugs", productHandler.BackfillSlugs)
+				adminProducts.POST("/import", productImportHandler.ImportProducts)
+				adminProducts.GET("/export", productImportHandler.ExportProducts)
 				adminProducts.PUT("/:id", productHandler.UpdateProduct)
 				adminProducts.DELETE("/:id", productHandler.DeleteProduct)
-				adminProducts.PATCH("/:id/stock", productHandler.UpdateStock)
+				adminProducts.PATCH("/:id/stock", inventoryHandler.AdjustStock)
+				adminProducts.GET("/:id/inventory", inventoryHandler.GetMovements)
+				adminProducts.GET("/:id/price-history", productHandler.GetPriceHistory)
+				adminProducts.GET("/low-stock", inventoryHandler.GetLowStockReport)
+				adminProducts.GET("/:id/stock-alerts", stockAlertHandler.GetProductSubscriptions)
 			}
 		}
 
+		// ── Wishlists (enlaces públicos) ──────────────────────────────────────
+		v1.GET("/wishlists/shared/:token", wishlistHandler.GetSharedWishlist)
+
+		// ── Categories ────────────────────────────────────────────────────────
+		categories := v1.Group("/categories")
+		{
+			categories.GET("", categoryHandler.GetCategoryTree)
+
+			adminCategories := categories.Group("")
+			adminCategories.Use(middleware.AuthMiddleware(cfg))
+			adminCategories.Use(middleware.RequireAdmin())
+			{
+				adminCategories.GET("/all", categoryHandler.GetAllCategories)
+				adminCategories.POST("", categoryHandler.CreateCategory)
+				adminCategories.POST("/migrate", categoryHandler.MigrateProductCategories)
+				adminCategories.PUT("/:id", categoryHandler.UpdateCategory)
+				adminCategories.DELETE("/:id", categoryHandler.DeleteCategory)
+			}
+
+			categories.GET("/:id", categoryHandler.GetCategory)
+		}
+
+		// ── Checkout ──────────────────────────────────────────────────────────
+		checkout := v1.Group("/checkout")
+		{
+			checkout.POST("/quote", middleware.OptionalAuth(cfg), checkoutHandler.Quote)
+		}
+
 		// ── Orders ────────────────────────────────────────────────────────────
 		orders := v1.Group("/orders")
 		{
@@ -311,7 +439,7 @@ func setupRoutes(
 		// ── Discounts ─────────────────────────────────────────────────────────
 		discounts := v1.Group("/discounts")
 		{
-			discounts.POST("/validate", discountHandler.ValidateDiscountCode)
+			discounts.POST("/validate", middleware.OptionalAuth(cfg), discountHandler.ValidateDiscountCode)
 
 			adminDiscounts := discounts.Group("")
 			adminDiscounts.Use(middleware.AuthMiddleware(cfg))
@@ -322,9 +450,27 @@ func setupRoutes(
 				adminDiscounts.GET("/:id", discountHandler.GetDiscountCode)
 				adminDiscounts.PUT("/:id", discountHandler.UpdateDiscountCode)
 				adminDiscounts.DELETE("/:id", discountHandler.DeleteDiscountCode)
+
+				adminDiscounts.GET("/batches", discountHandler.GetAllDiscountBatches)
+				adminDiscounts.POST("/batches", discountHandler.GenerateDiscountBatch)
+				adminDiscounts.GET("/batches/:id", discountHandler.GetDiscountBatch)
+				adminDiscounts.GET("/batches/:id/export", discountHandler.ExportDiscountBatch)
+				adminDiscounts.PATCH("/batches/:id/deactivate", discountHandler.DeactivateDiscountBatch)
 			}
 		}
 
+		// ── Promotions ────────────────────────────────────────────────────────
+		promotions := v1.Group("/promotions")
+		promotions.Use(middleware.AuthMiddleware(cfg))
+		promotions.Use(middleware.RequireAdmin())
+		{
+			promotions.GET("", promotionHandler.GetAllPromotions)
+			promotions.POST("", promotionHandler.CreatePromotion)
+			promotions.GET("/:id", promotionHandler.GetPromotion)
+			promotions.PUT("/:id", promotionHandler.UpdatePromotion)
+			promotions.DELETE("/:id", promotionHandler.DeletePromotion)
+		}
+
 		// ── Reviews ───────────────────────────────────────────────────────────
 		reviews := v1.Group("/reviews")
 		{
@@ -335,6 +481,7 @@ func setupRoutes(
 			adminReviews.Use(middleware.RequireAdmin())
 			{
 				adminReviews.GET("", reviewHandler.GetAllReviews)
+				adminReviews.POST("/backfill-ratings", reviewHandler.BackfillRatings)
 				adminReviews.GET("/:id", reviewHandler.GetReview)
 				adminReviews.PUT("/:id", reviewHandler.UpdateReview)
 				adminReviews.DELETE("/:id", reviewHandler.DeleteReview)
@@ -395,15 +542,16 @@ func setupRoutes(
 		}
 
 		// ── Cart ──────────────────────────────────────────────────────────────
+		// Usuarios autenticados usan su carrito; invitados, el de su token de carrito
 		cart := v1.Group("/cart")
-		cart.Use(middleware.AuthMiddleware(cfg))
+		cart.Use(middleware.OptionalAuth(cfg))
 		{
 			cart.GET("", cartHandler.GetCart)
+			cart.GET("/suggestions", recommendationHandler.GetCartSuggestions)
 			cart.POST("/items", cartHandler.AddItem)
 			cart.PUT("/items/:productId", cartHandler.UpdateItemQuantity)
 			cart.DELETE("/items/:productId", cartHandler.RemoveItem)
 			cart.DELETE("", cartHandler.ClearCart)
-			cart.POST("/sync", cartHandler.SyncCart)
 		}
 
 		// ── Payments (Wompi) ──────────────────────────────────────────────────
@@ -439,6 +587,7 @@ func setupRoutes(
 			dashboard.GET("/products/monthly", dashboardHandler.GetTopProductsMonthly)
 			dashboard.GET("/products/yearly", dashboardHandler.GetTopProductsYearly)
 			dashboard.GET("/summary", dashboardHandler.GetSummary)
+			dashboard.GET("/discounts", dashboardHandler.GetDiscountReport)
 			dashboard.POST("/recalculate", dashboardHandler.RecalculateMonth)
 		}
 
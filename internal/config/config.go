@@ -75,6 +75,42 @@ type Config struct {
 
 	// Render
 	RenderExternalURL string
+
+	// Inventario
+	LowStockDigestHour   int // Hora local (0-23) del resumen diario de stock bajo; -1 lo desactiva
+	LowStockVelocityDays int // Ventana en días para calcular la velocidad de venta
+
+	// Avisos de reposición
+	RestockAlertBatchSize       int // Máximo de suscriptores avisados por producto en cada tanda
+	RestockAlertIntervalMinutes int // Minutos mínimos entre tandas del mismo producto
+
+	// Caché (segundos; 0 desactiva esa caché)
+	CacheCatalogTTLSeconds   int // Listados y destacados del catálogo
+	CacheProductTTLSeconds   int // Detalle de producto
+	CacheConfigTTLSeconds    int // Ubicaciones, carrusel y "Sobre Nosotros"
+	CacheDashboardTTLSeconds int // Resumen del dashboard
+
+	// Listas de deseos
+	WishlistSaleCheckMinutes int // Cada cuántos minutos se buscan ofertas nuevas para avisar; 0 lo desactiva
+
+	// Recomendaciones
+	RecommendationsWindowDays   int // Días de pedidos que alimentan el modelo de "comprados juntos"
+	RecommendationsRefreshHours int // Cada cuántas horas se recalcula el modelo
+
+	// Carritos de invitado
+	GuestCartTTLDays int // Días sin actividad tras los que vence un carrito anónimo
+
+	// Checkout
+	ShippingFlatRate int // Costo de envío por pedido (COP)
+	FreeShippingFrom int // Subtotal con descuento desde el que el envío es gratis; 0 nunca
+	TaxRatePercent   int // IVA incluido en los precios, solo para informarlo en el desglose
+
+	// Carritos abandonados
+	AbandonedCartHours           int // Horas sin tocar el carrito antes de enviar el recordatorio; 0 lo desactiva
+	AbandonedCartDiscountPercent int // Descuento del código de un solo uso incluido en el recordatorio; 0 sin código
+
+	// Promociones
+	StoreTimezone string // Zona horaria de la tienda para las promociones por día y hora
 }
 
 func LoadConfig() (*Config, error) {
@@ -148,6 +184,42 @@ func LoadConfig() (*Config, error) {
 
 		// Render
 		RenderExternalURL: getEnv("RENDER_EXTERNAL_URL", ""),
+
+		// Inventario
+		LowStockDigestHour:   getEnvAsInt("LOW_STOCK_DIGEST_HOUR", 8),
+		LowStockVelocityDays: getEnvAsInt("LOW_STOCK_VELOCITY_DAYS", 30),
+
+		// Avisos de reposición
+		RestockAlertBatchSize:       getEnvAsInt("RESTOCK_ALERT_BATCH_SIZE", 20),
+		RestockAlertIntervalMinutes: getEnvAsInt("RESTOCK_ALERT_INTERVAL_MINUTES", 15),
+
+		// Caché
+		CacheCatalogTTLSeconds:   getEnvAsInt("CACHE_CATALOG_TTL_SECONDS", 60),
+		CacheProductTTLSeconds:   getEnvAsInt("CACHE_PRODUCT_TTL_SECONDS", 300),
+		CacheConfigTTLSeconds:    getEnvAsInt("CACHE_CONFIG_TTL_SECONDS", 3600),
+		CacheDashboardTTLSeconds: getEnvAsInt("CACHE_DASHBOARD_TTL_SECONDS", 60),
+
+		// Listas de deseos
+		WishlistSaleCheckMinutes: getEnvAsInt("WISHLIST_SALE_CHECK_MINUTES", 60),
+
+		// Recomendaciones
+		RecommendationsWindowDays:   getEnvAsInt("RECOMMENDATIONS_WINDOW_DAYS", 180),
+		RecommendationsRefreshHours: getEnvAsInt("RECOMMENDATIONS_REFRESH_HOURS", 6),
+
+		// Carritos de invitado
+		GuestCartTTLDays: getEnvAsInt("GUEST_CART_TTL_DAYS", 30),
+
+		// Checkout
+		ShippingFlatRate: getEnvAsInt("SHIPPING_FLAT_RATE", 0),
+		FreeShippingFrom: getEnvAsInt("FREE_SHIPPING_FROM", 0),
+		TaxRatePercent:   getEnvAsInt("TAX_RATE_PERCENT", 0),
+
+		// Carritos abandonados
+		AbandonedCartHours:           getEnvAsInt("ABANDONED_CART_HOURS", 24),
+		AbandonedCartDiscountPercent: getEnvAsInt("ABANDONED_CART_DISCOUNT_PERCENT", 0),
+
+		// Promociones
+		StoreTimezone: getEnv("STORE_TIMEZONE", "America/Bogota"),
 	}
 
 	// Validate critical configurations
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 
 	"github.com/cheoscafe/backend/internal/models"
@@ -13,12 +14,14 @@ import (
 type AuthHandler struct {
 	authService          *services.AuthService
 	passwordResetService *services.PasswordResetService
+	cartService          *services.CartService
 }
 
-func NewAuthHandler(authService *services.AuthService, passwordResetService *services.PasswordResetService) *AuthHandler {
+func NewAuthHandler(authService *services.AuthService, passwordResetService *services.PasswordResetService, cartService *services.CartService) *AuthHandler {
 	return &AuthHandler{
 		authService:          authService,
 		passwordResetService: passwordResetService,
+		cartService:          cartService,
 	}
 }
 
@@ -44,6 +47,8 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	h.mergeGuestCart(c, user.ID)
+
 	utils.SuccessResponse(c, http.StatusCreated, "Usuario registrado exitosamente", user)
 }
 
@@ -92,9 +97,30 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		true,                        // httpOnly
 	)
 
+	h.mergeGuestCart(c, response.User.ID)
+
 	utils.SuccessResponse(c, http.StatusOK, "Login exitoso", response)
 }
 
+// mergeGuestCart fusiona el carrito de invitado (si lo hay) en el del usuario
+// y descarta el token. Un error aquí no debe impedir el login.
+func (h *AuthHandler) mergeGuestCart(c *gin.Context, userID uuid.UUID) {
+	token := readCartToken(c)
+	if token == "" {
+		return
+	}
+
+	c.SetCookie(cartTokenCookie, "", -1, "/", "", false, true)
+
+	guestID, err := h.cartService.ParseGuestToken(token)
+	if err != nil {
+		return
+	}
+	if _, err := h.cartService.MergeGuestCart(c.Request.Context(), userID, guestID); err != nil {
+		log.Printf("[Cart] Error fusionando carrito de invitado %s en usuario %s: %v", guestID, userID, err)
+	}
+}
+
 // RefreshToken maneja la renovacion de tokens
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	var req struct {
@@ -300,3 +326,26 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 
 	utils.SuccessResponse(c, http.StatusOK, "Contrasena actualizada exitosamente", nil)
 }
+
+// Unsubscribe da de baja al usuario de los emails de marketing (enlace del email, sin sesion)
+func (h *AuthHandler) Unsubscribe(c *gin.Context) {
+	var req models.UnsubscribeRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos invalidos", err.Error())
+		return
+	}
+
+	// Validar
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	if err := h.authService.Unsubscribe(c.Request.Context(), req.Token); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Ya no recibiras emails de marketing", nil)
+}
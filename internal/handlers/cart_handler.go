@@ -18,31 +18,79 @@ func NewCartHandler(cartService *services.CartService) *CartHandler {
 	return &CartHandler{cartService: cartService}
 }
 
-// getUserID extrae el user_id del contexto (puesto por AuthMiddleware)
-func (h *CartHandler) getUserID(c *gin.Context) (uuid.UUID, bool) {
-	userIDInterface, exists := c.Get("user_id")
-	if !exists {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "No autenticado", nil)
-		return uuid.Nil, false
+// El token del carrito de invitado viaja en una cookie HttpOnly o, para clientes
+// sin cookies, en un header; siempre se devuelve (renovado) en ambos.
+const (
+	cartTokenCookie = "cart_token"
+	cartTokenHeader = "X-Cart-Token"
+)
+
+// getOwner identifica el carrito de la petición: el del usuario autenticado
+// (puesto por OptionalAuth) o el del invitado según su token de carrito.
+// Un invitado sin token válido recibe uno nuevo.
+func (h *CartHandler) getOwner(c *gin.Context) (models.CartOwner, bool) {
+	return resolveCartOwner(c, h.cartService)
+}
+
+func resolveCartOwner(c *gin.Context, cartService *services.CartService) (models.CartOwner, bool) {
+	if userIDInterface, exists := c.Get("user_id"); exists {
+		userID, ok := userIDInterface.(uuid.UUID)
+		if !ok {
+			utils.ErrorResponse(c, http.StatusBadRequest, "ID de usuario inválido", nil)
+			return models.CartOwner{}, false
+		}
+		return models.CartOwner{UserID: userID}, true
 	}
 
-	userID, ok := userIDInterface.(uuid.UUID)
-	if !ok {
-		utils.ErrorResponse(c, http.StatusBadRequest, "ID de usuario inválido", nil)
-		return uuid.Nil, false
+	guestID, err := cartService.ParseGuestToken(readCartToken(c))
+	if err != nil {
+		guestID = uuid.New()
+	}
+
+	// Renovar el token en cada petición para que venza por inactividad
+	token, err := cartService.GuestToken(guestID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al generar token de carrito", err.Error())
+		return models.CartOwner{}, false
+	}
+	c.SetCookie(cartTokenCookie, token, int(cartService.GuestCartTTL().Seconds()), "/", "", false, true)
+	c.Header(cartTokenHeader, token)
+
+	return models.CartOwner{GuestID: guestID}, true
+}
+
+// readCartToken lee el token de carrito de invitado de la cookie o del header
+func readCartToken(c *gin.Context) string {
+	if token, err := c.Cookie(cartTokenCookie); err == nil && token != "" {
+		return token
+	}
+	return c.GetHeader(cartTokenHeader)
+}
+
+// getVariantID lee el query param opcional ?variant_id= para identificar la variante del item
+func (h *CartHandler) getVariantID(c *gin.Context) (*uuid.UUID, bool) {
+	variantIDStr := c.Query("variant_id")
+	if variantIDStr == "" {
+		return nil, true
+	}
+
+	variantID, err := uuid.Parse(variantIDStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID de variante inválido", err.Error())
+		return nil, false
 	}
 
-	return userID, true
+	return &variantID, true
 }
 
-// GetCart obtiene el carrito del usuario autenticado
+// GetCart obtiene el carrito del usuario autenticado o del invitado
 func (h *CartHandler) GetCart(c *gin.Context) {
-	userID, ok := h.getUserID(c)
+	owner, ok := h.getOwner(c)
 	if !ok {
 		return
 	}
 
-	cart, err := h.cartService.GetCart(c.Request.Context(), userID)
+	cart, err := h.cartService.GetCart(c.Request.Context(), owner)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener carrito", err.Error())
 		return
@@ -53,7 +101,7 @@ func (h *CartHandler) GetCart(c *gin.Context) {
 
 // AddItem agrega un producto al carrito
 func (h *CartHandler) AddItem(c *gin.Context) {
-	userID, ok := h.getUserID(c)
+	owner, ok := h.getOwner(c)
 	if !ok {
 		return
 	}
@@ -69,7 +117,7 @@ func (h *CartHandler) AddItem(c *gin.Context) {
 		return
 	}
 
-	cart, err := h.cartService.AddItem(c.Request.Context(), userID, &req)
+	cart, err := h.cartService.AddItem(c.Request.Context(), owner, &req)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Error al agregar al carrito", err.Error())
 		return
@@ -80,7 +128,7 @@ func (h *CartHandler) AddItem(c *gin.Context) {
 
 // UpdateItemQuantity actualiza la cantidad de un producto en el carrito
 func (h *CartHandler) UpdateItemQuantity(c *gin.Context) {
-	userID, ok := h.getUserID(c)
+	owner, ok := h.getOwner(c)
 	if !ok {
 		return
 	}
@@ -92,6 +140,11 @@ func (h *CartHandler) UpdateItemQuantity(c *gin.Context) {
 		return
 	}
 
+	variantID, ok := h.getVariantID(c)
+	if !ok {
+		return
+	}
+
 	var req models.UpdateCartItemRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", err.Error())
@@ -103,7 +156,7 @@ func (h *CartHandler) UpdateItemQuantity(c *gin.Context) {
 		return
 	}
 
-	cart, err := h.cartService.UpdateItemQuantity(c.Request.Context(), userID, productID, &req)
+	cart, err := h.cartService.UpdateItemQuantity(c.Request.Context(), owner, productID, variantID, &req)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Error al actualizar cantidad", err.Error())
 		return
@@ -114,7 +167,7 @@ func (h *CartHandler) UpdateItemQuantity(c *gin.Context) {
 
 // RemoveItem elimina un producto del carrito
 func (h *CartHandler) RemoveItem(c *gin.Context) {
-	userID, ok := h.getUserID(c)
+	owner, ok := h.getOwner(c)
 	if !ok {
 		return
 	}
@@ -126,7 +179,12 @@ func (h *CartHandler) RemoveItem(c *gin.Context) {
 		return
 	}
 
-	cart, err := h.cartService.RemoveItem(c.Request.Context(), userID, productID)
+	variantID, ok := h.getVariantID(c)
+	if !ok {
+		return
+	}
+
+	cart, err := h.cartService.RemoveItem(c.Request.Context(), owner, productID, variantID)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al eliminar del carrito", err.Error())
 		return
@@ -137,42 +195,15 @@ func (h *CartHandler) RemoveItem(c *gin.Context) {
 
 // ClearCart vacía el carrito completo
 func (h *CartHandler) ClearCart(c *gin.Context) {
-	userID, ok := h.getUserID(c)
+	owner, ok := h.getOwner(c)
 	if !ok {
 		return
 	}
 
-	if err := h.cartService.ClearCart(c.Request.Context(), userID); err != nil {
+	if err := h.cartService.ClearCart(c.Request.Context(), owner); err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al vaciar carrito", err.Error())
 		return
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, "Carrito vaciado", nil)
 }
-
-// SyncCart fusiona el carrito de invitado con el guardado
-func (h *CartHandler) SyncCart(c *gin.Context) {
-	userID, ok := h.getUserID(c)
-	if !ok {
-		return
-	}
-
-	var req models.SyncCartRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", err.Error())
-		return
-	}
-
-	if err := utils.ValidateStruct(&req); err != nil {
-		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
-		return
-	}
-
-	cart, err := h.cartService.SyncCart(c.Request.Context(), userID, &req)
-	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al sincronizar carrito", err.Error())
-		return
-	}
-
-	utils.SuccessResponse(c, http.StatusOK, "Carrito sincronizado", cart)
-}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/cheoscafe/backend/internal/services"
+	"github.com/cheoscafe/backend/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CategoryHandler struct {
+	categoryService *services.CategoryService
+}
+
+func NewCategoryHandler(categoryService *services.CategoryService) *CategoryHandler {
+	return &CategoryHandler{
+		categoryService: categoryService,
+	}
+}
+
+// GetCategoryTree obtiene el árbol de categorías activas (público)
+func (h *CategoryHandler) GetCategoryTree(c *gin.Context) {
+	tree, err := h.categoryService.GetCategoryTree(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener categorías", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Categorías obtenidas exitosamente", tree)
+}
+
+// GetAllCategories obtiene todas las categorías en lista plana (solo admin)
+func (h *CategoryHandler) GetAllCategories(c *gin.Context) {
+	categories, err := h.categoryService.GetAllCategories(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener categorías", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Categorías obtenidas exitosamente", categories)
+}
+
+// GetCategory obtiene una categoría por ID
+func (h *CategoryHandler) GetCategory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID de categoría inválido", err.Error())
+		return
+	}
+
+	category, err := h.categoryService.GetCategory(c.Request.Context(), id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Categoría no encontrada", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Categoría obtenida exitosamente", category)
+}
+
+// CreateCategory crea una nueva categoría (solo admin)
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	var req models.CreateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", err.Error())
+		return
+	}
+
+	category, err := h.categoryService.CreateCategory(c.Request.Context(), &req)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Error al crear categoría", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Categoría creada exitosamente", category)
+}
+
+// UpdateCategory actualiza una categoría (solo admin)
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID de categoría inválido", err.Error())
+		return
+	}
+
+	var req models.UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", err.Error())
+		return
+	}
+
+	category, err := h.categoryService.UpdateCategory(c.Request.Context(), id, &req)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Error al actualizar categoría", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Categoría actualizada exitosamente", category)
+}
+
+// DeleteCategory elimina una categoría (solo admin)
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID de categoría inválido", err.Error())
+		return
+	}
+
+	if err := h.categoryService.DeleteCategory(c.Request.Context(), id); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Error al eliminar categoría", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Categoría eliminada exitosamente", nil)
+}
+
+// MigrateProductCategories convierte las categorías en texto de los productos en categorías (solo admin)
+func (h *CategoryHandler) MigrateProductCategories(c *gin.Context) {
+	result, err := h.categoryService.MigrateProductCategories(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al migrar categorías", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Categorías migradas exitosamente", result)
+}
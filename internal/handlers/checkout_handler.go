@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/cheoscafe/backend/internal/services"
+	"github.com/cheoscafe/backend/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CheckoutHandler struct {
+	checkoutService *services.CheckoutService
+}
+
+func NewCheckoutHandler(checkoutService *services.CheckoutService) *CheckoutHandler {
+	return &CheckoutHandler{checkoutService: checkoutService}
+}
+
+// Quote cotiza un checkout con el mismo cálculo que la creación de la orden
+func (h *CheckoutHandler) Quote(c *gin.Context) {
+	var req models.CheckoutQuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", err.Error())
+		return
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	var userID *uuid.UUID
+	if id, exists := c.Get("user_id"); exists {
+		if uid, ok := id.(uuid.UUID); ok {
+			userID = &uid
+		}
+	}
+
+	quote, err := h.checkoutService.Quote(c.Request.Context(), &req, userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al cotizar", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Cotización calculada", quote)
+}
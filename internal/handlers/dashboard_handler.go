@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/cheoscafe/backend/internal/services"
 	"github.com/cheoscafe/backend/internal/utils"
@@ -48,6 +49,31 @@ func parseYear(c *gin.Context) (int, error) {
 	return year, nil
 }
 
+// parseDateRange lee from y to (YYYY-MM-DD, ambos incluidos).
+// Por defecto: desde el primer día del mes actual hasta hoy.
+func parseDateRange(c *gin.Context) (time.Time, time.Time, error) {
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var err error
+	if fromStr := c.Query("from"); fromStr != "" {
+		if from, err = time.Parse("2006-01-02", fromStr); err != nil {
+			return time.Time{}, time.Time{}, errors.New("parametro 'from' invalido (YYYY-MM-DD)")
+		}
+	}
+	if toStr := c.Query("to"); toStr != "" {
+		if to, err = time.Parse("2006-01-02", toStr); err != nil {
+			return time.Time{}, time.Time{}, errors.New("parametro 'to' invalido (YYYY-MM-DD)")
+		}
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, errors.New("'to' debe ser igual o posterior a 'from'")
+	}
+
+	return from, to, nil
+}
+
 // --- Endpoints ---
 
 // GetSalesMonthly obtiene ventas mensuales
@@ -186,3 +212,21 @@ func (h *DashboardHandler) RecalculateMonth(c *gin.Context) {
 
 	utils.SuccessResponse(c, http.StatusOK, "Metricas recalculadas exitosamente", nil)
 }
+
+// GetDiscountReport obtiene el rendimiento por código de descuento y por campaña
+// GET /api/v1/dashboard/discounts?from=2026-02-01&to=2026-02-28
+func (h *DashboardHandler) GetDiscountReport(c *gin.Context) {
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Parametros invalidos", err.Error())
+		return
+	}
+
+	data, err := h.dashboardService.GetDiscountReport(c.Request.Context(), from, to)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener reporte de descuentos", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Reporte de descuentos obtenido", data)
+}
@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"bytes"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/cheoscafe/backend/internal/models"
 	"github.com/cheoscafe/backend/internal/services"
@@ -119,6 +123,13 @@ func (h *DiscountHandler) ValidateDiscountCode(c *gin.Context) {
 		return
 	}
 
+	// Signed-in customers are checked against the per-customer limit too
+	if id, exists := c.Get("user_id"); exists {
+		if userID, ok := id.(uuid.UUID); ok {
+			req.UserID = &userID
+		}
+	}
+
 	result, err := h.discountService.ValidateDiscountCode(c.Request.Context(), &req)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error validating discount code", err.Error())
@@ -127,3 +138,93 @@ func (h *DiscountHandler) ValidateDiscountCode(c *gin.Context) {
 
 	utils.SuccessResponse(c, http.StatusOK, result.Message, result)
 }
+
+// GenerateDiscountBatch generates a batch of single-use codes for a campaign (admin only)
+func (h *DiscountHandler) GenerateDiscountBatch(c *gin.Context) {
+	var req models.GenerateDiscountBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid data", err.Error())
+		return
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	discountBatch, err := h.discountService.GenerateDiscountBatch(c.Request.Context(), &req)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Error generating discount codes", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Discount codes generated successfully", discountBatch)
+}
+
+// GetAllDiscountBatches gets all code batches with their stats (admin only)
+func (h *DiscountHandler) GetAllDiscountBatches(c *gin.Context) {
+	batches, err := h.discountService.GetAllDiscountBatches(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error getting discount batches", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Discount batches retrieved", batches)
+}
+
+// GetDiscountBatch gets a code batch with its redemption stats (admin only)
+func (h *DiscountHandler) GetDiscountBatch(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid discount batch ID", err.Error())
+		return
+	}
+
+	discountBatch, err := h.discountService.GetDiscountBatch(c.Request.Context(), id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Discount batch not found", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Discount batch retrieved", discountBatch)
+}
+
+// ExportDiscountBatch downloads the codes of a batch as CSV (admin only)
+func (h *DiscountHandler) ExportDiscountBatch(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid discount batch ID", err.Error())
+		return
+	}
+
+	var buf bytes.Buffer
+	discountBatch, err := h.discountService.ExportDiscountBatchCSV(c.Request.Context(), id, &buf)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error exporting discount batch", err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("codes-%s-%s.csv", strings.ToLower(discountBatch.Prefix), time.Now().Format("2006-01-02"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// DeactivateDiscountBatch deactivates a batch and all its codes (admin only)
+func (h *DiscountHandler) DeactivateDiscountBatch(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid discount batch ID", err.Error())
+		return
+	}
+
+	discountBatch, err := h.discountService.DeactivateDiscountBatch(c.Request.Context(), id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error deactivating discount batch", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Discount batch deactivated", discountBatch)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/cheoscafe/backend/internal/services"
+	"github.com/cheoscafe/backend/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type InventoryHandler struct {
+	inventoryService *services.InventoryService
+}
+
+func NewInventoryHandler(inventoryService *services.InventoryService) *InventoryHandler {
+	return &InventoryHandler{
+		inventoryService: inventoryService,
+	}
+}
+
+// AdjustStock aplica un ajuste de stock con su motivo (solo admin)
+func (h *InventoryHandler) AdjustStock(c *gin.Context) {
+	productID := c.Param("id")
+
+	var req models.UpdateStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", err.Error())
+		return
+	}
+
+	var actorID *uuid.UUID
+	if userIDInterface, exists := c.Get("user_id"); exists {
+		if id, ok := userIDInterface.(uuid.UUID); ok {
+			actorID = &id
+		}
+	}
+
+	movement, err := h.inventoryService.AdjustStock(c.Request.Context(), productID, &req, actorID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Error al actualizar stock", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Stock actualizado exitosamente", movement)
+}
+
+// GetMovements obtiene el ledger de inventario de un producto (solo admin)
+// Query params: from, to (YYYY-MM-DD, to inclusivo), reason
+func (h *InventoryHandler) GetMovements(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID de producto inválido", err.Error())
+		return
+	}
+
+	filter := &models.InventoryMovementFilter{
+		ProductID: productID,
+		Reason:    models.InventoryReason(c.Query("reason")),
+	}
+
+	if v := c.Query("from"); v != "" {
+		from, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Parámetro inválido", "from debe tener formato YYYY-MM-DD")
+			return
+		}
+		filter.From = &from
+	}
+	if v := c.Query("to"); v != "" {
+		to, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Parámetro inválido", "to debe tener formato YYYY-MM-DD")
+			return
+		}
+		to = to.AddDate(0, 0, 1) // Incluir el día completo
+		filter.To = &to
+	}
+
+	response, err := h.inventoryService.GetMovements(c.Request.Context(), filter)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Error al obtener movimientos", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Movimientos obtenidos exitosamente", response)
+}
+
+// GetLowStockReport lista los productos en o bajo su umbral de reorden con su velocidad de venta (solo admin)
+// Query params: days (ventana para la velocidad, por defecto LOW_STOCK_VELOCITY_DAYS)
+func (h *InventoryHandler) GetLowStockReport(c *gin.Context) {
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "0"))
+
+	report, err := h.inventoryService.GetLowStockReport(c.Request.Context(), days)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener reporte de stock bajo", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Reporte de stock bajo obtenido exitosamente", report)
+}
@@ -13,11 +13,13 @@ import (
 
 type OrderHandler struct {
 	orderService *services.OrderService
+	cartService  *services.CartService
 }
 
-func NewOrderHandler(orderService *services.OrderService) *OrderHandler {
+func NewOrderHandler(orderService *services.OrderService, cartService *services.CartService) *OrderHandler {
 	return &OrderHandler{
 		orderService: orderService,
+		cartService:  cartService,
 	}
 }
 
@@ -36,7 +38,15 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		}
 	}
 
-	order, err := h.orderService.CreateOrder(c.Request.Context(), &req, userID)
+	// Carrito a vaciar: el del usuario o, para un invitado, el de su token de carrito
+	var cart *models.CartOwner
+	if userID != nil {
+		cart = &models.CartOwner{UserID: *userID}
+	} else if guestID, err := h.cartService.ParseGuestToken(readCartToken(c)); err == nil {
+		cart = &models.CartOwner{GuestID: guestID}
+	}
+
+	order, err := h.orderService.CreateOrder(c.Request.Context(), &req, userID, cart)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al crear orden", err.Error())
 		return
@@ -136,7 +146,14 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	order, err := h.orderService.UpdateOrderStatus(c.Request.Context(), id, &req)
+	var actorID *uuid.UUID
+	if userIDInterface, exists := c.Get("user_id"); exists {
+		if uid, ok := userIDInterface.(uuid.UUID); ok {
+			actorID = &uid
+		}
+	}
+
+	order, err := h.orderService.UpdateOrderStatus(c.Request.Context(), id, &req, actorID)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al actualizar estado", err.Error())
 		return
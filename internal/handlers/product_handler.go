@@ -3,11 +3,13 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/cheoscafe/backend/internal/models"
 	"github.com/cheoscafe/backend/internal/services"
 	"github.com/cheoscafe/backend/internal/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type ProductHandler struct {
@@ -28,7 +30,14 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 		return
 	}
 
-	product, err := h.productService.CreateProduct(c.Request.Context(), &req)
+	var actorID *uuid.UUID
+	if userIDInterface, exists := c.Get("user_id"); exists {
+		if id, ok := userIDInterface.(uuid.UUID); ok {
+			actorID = &id
+		}
+	}
+
+	product, err := h.productService.CreateProduct(c.Request.Context(), &req, actorID)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al crear producto", err.Error())
 		return
@@ -50,13 +59,118 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, "Producto obtenido exitosamente", product)
 }
 
-// GetAllProducts obtiene todos los productos con paginación
+// GetProductBySlug obtiene un producto por su slug.
+// Los slugs anteriores responden 301 hacia el slug actual.
+func (h *ProductHandler) GetProductBySlug(c *gin.Context) {
+	product, currentSlug, err := h.productService.GetProductBySlug(c.Request.Context(), c.Param("slug"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Producto no encontrado", err.Error())
+		return
+	}
+
+	if currentSlug != "" {
+		c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, c.Param("slug"))+currentSlug)
+		utils.SuccessResponse(c, http.StatusMovedPermanently, "El producto cambió de URL", product)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Producto obtenido exitosamente", product)
+}
+
+// BackfillSlugs genera slugs y meta tags para los productos que no los tienen (solo admin)
+func (h *ProductHandler) BackfillSlugs(c *gin.Context) {
+	updated, err := h.productService.BackfillSlugs(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al generar slugs", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Slugs generados exitosamente", gin.H{"products_updated": updated})
+}
+
+// GetAllProducts obtiene el catálogo con filtros, orden, paginación y facetas.
+// Query params: category, min_price, max_price, in_stock, featured, min_rating,
+// sort (newest, price_asc, price_desc, best_selling, rating), page, page_size
 func (h *ProductHandler) GetAllProducts(c *gin.Context) {
 	// Parámetros de paginación
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
 
-	response, err := h.productService.GetAllProducts(c.Request.Context(), page, pageSize)
+	filter := &models.ProductFilter{
+		Category: c.Query("category"),
+		InStock:  c.Query("in_stock") == "true",
+		Sort:     c.Query("sort"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	if v := c.Query("min_price"); v != "" {
+		minPrice, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Parámetro inválido", "min_price debe ser numérico")
+			return
+		}
+		filter.MinPrice = &minPrice
+	}
+	if v := c.Query("max_price"); v != "" {
+		maxPrice, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Parámetro inválido", "max_price debe ser numérico")
+			return
+		}
+		filter.MaxPrice = &maxPrice
+	}
+	if v := c.Query("featured"); v != "" {
+		featured, err := strconv.ParseBool(v)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Parámetro inválido", "featured debe ser true o false")
+			return
+		}
+		filter.Featured = &featured
+	}
+	if v := c.Query("min_rating"); v != "" {
+		minRating, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Parámetro inválido", "min_rating debe ser numérico")
+			return
+		}
+		filter.MinRating = minRating
+	}
+
+	// Atributos de café
+	filter.Origin = c.Query("origin")
+	filter.Variety = c.Query("variety")
+	if v := c.Query("process"); v != "" {
+		filter.Process = models.CoffeeProcess(strings.ToUpper(v))
+		if _, ok := models.CoffeeProcessLabels[filter.Process]; !ok {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Parámetro inválido", "process debe ser WASHED, NATURAL o HONEY")
+			return
+		}
+	}
+	if v := c.Query("roast_level"); v != "" {
+		filter.RoastLevel = models.RoastLevel(strings.ToUpper(v))
+		if _, ok := models.RoastLevelLabels[filter.RoastLevel]; !ok {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Parámetro inválido", "roast_level debe ser LIGHT, MEDIUM, MEDIUM_DARK o DARK")
+			return
+		}
+	}
+	if v := c.Query("brew_method"); v != "" {
+		filter.BrewMethod = models.BrewMethod(strings.ToUpper(v))
+		if _, ok := models.BrewMethodLabels[filter.BrewMethod]; !ok {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Parámetro inválido", "brew_method no es un método de preparación válido")
+			return
+		}
+	}
+	if v := c.Query("min_altitude"); v != "" {
+		minAltitude, err := strconv.Atoi(v)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Parámetro inválido", "min_altitude debe ser un número entero")
+			return
+		}
+		filter.MinAltitude = minAltitude
+	}
+
+	response, err := h.productService.GetAllProducts(c.Request.Context(), filter)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener productos", err.Error())
 		return
@@ -86,7 +200,14 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 		return
 	}
 
-	product, err := h.productService.UpdateProduct(c.Request.Context(), productID, &req)
+	var actorID *uuid.UUID
+	if userIDInterface, exists := c.Get("user_id"); exists {
+		if id, ok := userIDInterface.(uuid.UUID); ok {
+			actorID = &id
+		}
+	}
+
+	product, err := h.productService.UpdateProduct(c.Request.Context(), productID, &req, actorID)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al actualizar producto", err.Error())
 		return
@@ -107,27 +228,6 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, "Producto eliminado exitosamente", nil)
 }
 
-// UpdateStock actualiza el stock de un producto
-func (h *ProductHandler) UpdateStock(c *gin.Context) {
-	productID := c.Param("id")
-
-	var req struct {
-		Quantity int `json:"quantity" binding:"required"`
-	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", err.Error())
-		return
-	}
-
-	if err := h.productService.UpdateStock(c.Request.Context(), productID, req.Quantity); err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al actualizar stock", err.Error())
-		return
-	}
-
-	utils.SuccessResponse(c, http.StatusOK, "Stock actualizado exitosamente", nil)
-}
-
 // SearchProducts busca productos
 func (h *ProductHandler) SearchProducts(c *gin.Context) {
 	searchTerm := c.Query("q")
@@ -141,3 +241,14 @@ func (h *ProductHandler) SearchProducts(c *gin.Context) {
 
 	utils.SuccessResponse(c, http.StatusOK, "Búsqueda completada", products)
 }
+
+// GetPriceHistory obtiene el historial de cambios de precio y ofertas de un producto (solo admin)
+func (h *ProductHandler) GetPriceHistory(c *gin.Context) {
+	history, err := h.productService.GetPriceHistory(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Error al obtener historial de precios", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Historial de precios obtenido exitosamente", history)
+}
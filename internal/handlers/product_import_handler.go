@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cheoscafe/backend/internal/services"
+	"github.com/cheoscafe/backend/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const maxProductCSVSize = 5 << 20 // 5 MB
+
+type ProductImportHandler struct {
+	importService *services.ProductImportService
+}
+
+func NewProductImportHandler(importService *services.ProductImportService) *ProductImportHandler {
+	return &ProductImportHandler{
+		importService: importService,
+	}
+}
+
+// ImportProducts importa productos desde un CSV (solo admin).
+// Form-data: file (CSV). Query params: dry_run=true solo valida y retorna el reporte.
+func (h *ProductImportHandler) ImportProducts(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Archivo CSV requerido", err.Error())
+		return
+	}
+	if fileHeader.Size > maxProductCSVSize {
+		utils.ErrorResponse(c, http.StatusBadRequest, "El archivo no debe superar 5 MB", nil)
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "No se pudo leer el archivo", err.Error())
+		return
+	}
+	defer file.Close()
+
+	var actorID *uuid.UUID
+	if userIDInterface, exists := c.Get("user_id"); exists {
+		if id, ok := userIDInterface.(uuid.UUID); ok {
+			actorID = &id
+		}
+	}
+
+	dryRun := c.Query("dry_run") == "true"
+	report, err := h.importService.ImportProductsCSV(c.Request.Context(), file, dryRun, actorID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Error al importar productos", err.Error())
+		return
+	}
+
+	switch {
+	case report.Failed > 0 && !report.Applied:
+		utils.ErrorResponse(c, http.StatusUnprocessableEntity, "El archivo tiene errores, no se importó nada", report)
+	case dryRun:
+		utils.SuccessResponse(c, http.StatusOK, "Archivo válido, listo para importar", report)
+	default:
+		utils.SuccessResponse(c, http.StatusOK, "Productos importados exitosamente", report)
+	}
+}
+
+// ExportProducts descarga el catálogo completo en CSV (solo admin)
+func (h *ProductImportHandler) ExportProducts(c *gin.Context) {
+	var buf bytes.Buffer
+	if err := h.importService.ExportProductsCSV(c.Request.Context(), &buf); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al exportar productos", err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("productos-%s.csv", time.Now().Format("2006-01-02"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
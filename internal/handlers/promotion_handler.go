@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/cheoscafe/backend/internal/services"
+	"github.com/cheoscafe/backend/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type PromotionHandler struct {
+	promotionService *services.PromotionService
+}
+
+func NewPromotionHandler(promotionService *services.PromotionService) *PromotionHandler {
+	return &PromotionHandler{
+		promotionService: promotionService,
+	}
+}
+
+// CreatePromotion creates a new automatic promotion (admin only)
+func (h *PromotionHandler) CreatePromotion(c *gin.Context) {
+	var req models.PromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid data", err.Error())
+		return
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	promotion, err := h.promotionService.CreatePromotion(c.Request.Context(), &req)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Error creating promotion", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Promotion created successfully", promotion)
+}
+
+// GetPromotion gets a promotion by ID (admin only)
+func (h *PromotionHandler) GetPromotion(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid promotion ID", err.Error())
+		return
+	}
+
+	promotion, err := h.promotionService.GetPromotionByID(c.Request.Context(), id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Promotion not found", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Promotion retrieved", promotion)
+}
+
+// GetAllPromotions gets all promotions, highest priority first (admin only)
+func (h *PromotionHandler) GetAllPromotions(c *gin.Context) {
+	promotions, err := h.promotionService.GetAllPromotions(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error getting promotions", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Promotions retrieved", promotions)
+}
+
+// UpdatePromotion replaces a promotion (admin only)
+func (h *PromotionHandler) UpdatePromotion(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid promotion ID", err.Error())
+		return
+	}
+
+	var req models.PromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid data", err.Error())
+		return
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	promotion, err := h.promotionService.UpdatePromotion(c.Request.Context(), id, &req)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Error updating promotion", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Promotion updated", promotion)
+}
+
+// DeletePromotion deletes a promotion (admin only)
+func (h *PromotionHandler) DeletePromotion(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid promotion ID", err.Error())
+		return
+	}
+
+	if err := h.promotionService.DeletePromotion(c.Request.Context(), id); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error deleting promotion", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Promotion deleted", nil)
+}
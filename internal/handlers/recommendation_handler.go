@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/cheoscafe/backend/internal/services"
+	"github.com/cheoscafe/backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type RecommendationHandler struct {
+	recommendationService *services.RecommendationService
+	cartService           *services.CartService
+}
+
+func NewRecommendationHandler(recommendationService *services.RecommendationService, cartService *services.CartService) *RecommendationHandler {
+	return &RecommendationHandler{
+		recommendationService: recommendationService,
+		cartService:           cartService,
+	}
+}
+
+// GetRelated obtiene los productos que se suelen comprar junto al producto
+func (h *RecommendationHandler) GetRelated(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "4"))
+
+	recommendations, err := h.recommendationService.GetRelated(c.Request.Context(), c.Param("id"), limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Error al obtener productos relacionados", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Productos relacionados obtenidos exitosamente", recommendations)
+}
+
+// GetCartSuggestions sugiere productos para completar el carrito del usuario o invitado
+func (h *RecommendationHandler) GetCartSuggestions(c *gin.Context) {
+	owner, ok := resolveCartOwner(c, h.cartService)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "4"))
+
+	recommendations, err := h.recommendationService.GetCartSuggestions(c.Request.Context(), owner, limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener sugerencias", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Sugerencias obtenidas exitosamente", recommendations)
+}
@@ -139,3 +139,14 @@ func (h *ReviewHandler) DeleteReview(c *gin.Context) {
 
 	utils.SuccessResponse(c, http.StatusOK, "Review deleted", nil)
 }
+
+// BackfillRatings recomputes every product's rating from its approved reviews (admin only)
+func (h *ReviewHandler) BackfillRatings(c *gin.Context) {
+	updated, err := h.reviewService.BackfillRatings(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error backfilling ratings", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Ratings backfilled", gin.H{"products_updated": updated})
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/cheoscafe/backend/internal/services"
+	"github.com/cheoscafe/backend/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type StockAlertHandler struct {
+	stockAlertService *services.StockAlertService
+}
+
+func NewStockAlertHandler(stockAlertService *services.StockAlertService) *StockAlertHandler {
+	return &StockAlertHandler{
+		stockAlertService: stockAlertService,
+	}
+}
+
+// Subscribe suscribe al cliente al aviso de reposición de un producto agotado.
+// Funciona con o sin sesión: los invitados deben enviar su email.
+func (h *StockAlertHandler) Subscribe(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID de producto inválido", err.Error())
+		return
+	}
+
+	var req models.CreateStockSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", err.Error())
+		return
+	}
+
+	var userID *uuid.UUID
+	if userIDInterface, exists := c.Get("user_id"); exists {
+		if id, ok := userIDInterface.(uuid.UUID); ok {
+			userID = &id
+		}
+	}
+
+	sub, err := h.stockAlertService.Subscribe(c.Request.Context(), productID, &req, userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Error al crear el aviso de reposición", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Te avisaremos cuando vuelva a estar disponible", sub)
+}
+
+// GetMySubscriptions lista los avisos de reposición pendientes del usuario
+func (h *StockAlertHandler) GetMySubscriptions(c *gin.Context) {
+	userID, err := extractUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "No autenticado", err.Error())
+		return
+	}
+
+	subs, err := h.stockAlertService.GetUserSubscriptions(c.Request.Context(), userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener avisos de reposición", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Avisos de reposición obtenidos exitosamente", subs)
+}
+
+// Unsubscribe cancela un aviso de reposición del usuario
+func (h *StockAlertHandler) Unsubscribe(c *gin.Context) {
+	userID, err := extractUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "No autenticado", err.Error())
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID de aviso inválido", err.Error())
+		return
+	}
+
+	if err := h.stockAlertService.Unsubscribe(c.Request.Context(), id, userID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Error al cancelar el aviso de reposición", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Aviso de reposición cancelado exitosamente", nil)
+}
+
+// CancelByToken cancela un aviso de reposición con el enlace firmado (sin sesión)
+func (h *StockAlertHandler) CancelByToken(c *gin.Context) {
+	var req models.CancelStockSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", err.Error())
+		return
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	if err := h.stockAlertService.CancelByToken(c.Request.Context(), req.Token); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Error al cancelar el aviso de reposición", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Aviso de reposición cancelado exitosamente", nil)
+}
+
+// GetProductSubscriptions lista la cola de avisos pendientes de un producto (solo admin)
+func (h *StockAlertHandler) GetProductSubscriptions(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID de producto inválido", err.Error())
+		return
+	}
+
+	subs, err := h.stockAlertService.GetProductSubscriptions(c.Request.Context(), productID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener avisos de reposición", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Avisos de reposición obtenidos exitosamente", subs)
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/cheoscafe/backend/internal/services"
+	"github.com/cheoscafe/backend/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WishlistHandler struct {
+	wishlistService *services.WishlistService
+}
+
+func NewWishlistHandler(wishlistService *services.WishlistService) *WishlistHandler {
+	return &WishlistHandler{
+		wishlistService: wishlistService,
+	}
+}
+
+// GetMyWishlist obtiene la lista de deseos del usuario autenticado
+func (h *WishlistHandler) GetMyWishlist(c *gin.Context) {
+	userID, err := extractUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "No autenticado", err.Error())
+		return
+	}
+
+	wishlist, err := h.wishlistService.GetWishlist(c.Request.Context(), userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener la lista de deseos", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Lista de deseos obtenida exitosamente", wishlist)
+}
+
+// AddItem agrega un producto a la lista de deseos
+func (h *WishlistHandler) AddItem(c *gin.Context) {
+	userID, err := extractUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "No autenticado", err.Error())
+		return
+	}
+
+	var req models.AddWishlistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", err.Error())
+		return
+	}
+
+	wishlist, err := h.wishlistService.AddItem(c.Request.Context(), userID, &req)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Error al agregar a la lista de deseos", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Producto agregado a la lista de deseos", wishlist)
+}
+
+// RemoveItem quita un producto de la lista de deseos (?variant_id= para una variante)
+func (h *WishlistHandler) RemoveItem(c *gin.Context) {
+	userID, err := extractUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "No autenticado", err.Error())
+		return
+	}
+
+	productID, err := uuid.Parse(c.Param("productId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID de producto inválido", err.Error())
+		return
+	}
+
+	var variantID *uuid.UUID
+	if raw := c.Query("variant_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "ID de variante inválido", err.Error())
+			return
+		}
+		variantID = &id
+	}
+
+	wishlist, err := h.wishlistService.RemoveItem(c.Request.Context(), userID, productID, variantID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Error al quitar de la lista de deseos", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Producto quitado de la lista de deseos", wishlist)
+}
+
+// EnableSharing genera el enlace público de la lista de deseos
+func (h *WishlistHandler) EnableSharing(c *gin.Context) {
+	userID, err := extractUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "No autenticado", err.Error())
+		return
+	}
+
+	wishlist, err := h.wishlistService.EnableSharing(c.Request.Context(), userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al compartir la lista de deseos", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Enlace de la lista de deseos generado", wishlist)
+}
+
+// DisableSharing revoca el enlace público de la lista de deseos
+func (h *WishlistHandler) DisableSharing(c *gin.Context) {
+	userID, err := extractUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "No autenticado", err.Error())
+		return
+	}
+
+	if err := h.wishlistService.DisableSharing(c.Request.Context(), userID); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al dejar de compartir la lista de deseos", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "La lista de deseos ya no es pública", nil)
+}
+
+// GetSharedWishlist obtiene una lista de deseos compartida (público)
+func (h *WishlistHandler) GetSharedWishlist(c *gin.Context) {
+	wishlist, err := h.wishlistService.GetSharedWishlist(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Lista de deseos no encontrada", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Lista de deseos obtenida exitosamente", wishlist)
+}
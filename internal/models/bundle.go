@@ -0,0 +1,58 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// BundleComponent es un producto (o una variante) incluido en un kit, con la cantidad
+// que lleva cada unidad del kit. Los nombres se denormalizan al guardar el kit.
+type BundleComponent struct {
+	ProductID   uuid.UUID  `json:"product_id" firestore:"product_id"`
+	VariantID   *uuid.UUID `json:"variant_id" firestore:"variant_id"`
+	ProductName string     `json:"product_name" firestore:"product_name"`
+	VariantName string     `json:"variant_name" firestore:"variant_name"`
+	Quantity    int        `json:"quantity" firestore:"quantity"`
+}
+
+// IsBundle indica si el producto es un kit armado con otros productos.
+// El stock de un kit no se maneja a mano: se deriva del de sus componentes.
+func (p *Product) IsBundle() bool {
+	return len(p.BundleItems) > 0
+}
+
+// BundleStock calcula cuántos kits se pueden armar con el stock de los componentes.
+// Un componente inexistente, inactivo o con la variante inactiva deja el kit sin stock.
+func (p *Product) BundleStock(components map[uuid.UUID]*Product) int {
+	if !p.IsBundle() {
+		return p.Stock
+	}
+
+	stock := -1
+	for _, item := range p.BundleItems {
+		available := 0
+		if component, ok := components[item.ProductID]; ok && component.IsActive {
+			if item.VariantID == nil {
+				available = component.Stock
+			} else if variant := component.FindVariant(*item.VariantID); variant != nil && variant.IsActive {
+				available = variant.Stock
+			}
+		}
+
+		units := 0
+		if item.Quantity > 0 {
+			units = available / item.Quantity
+		}
+		if stock < 0 || units < stock {
+			stock = units
+		}
+	}
+	return max(stock, 0)
+}
+
+// DTOs
+
+type BundleItemRequest struct {
+	ProductID uuid.UUID  `json:"product_id" validate:"required"`
+	VariantID *uuid.UUID `json:"variant_id"`
+	Quantity  int        `json:"quantity" validate:"required,gt=0"`
+}
@@ -8,29 +8,91 @@ import (
 
 type Cart struct {
 	UserID    uuid.UUID  `json:"user_id" firestore:"user_id"`
+	GuestID   *uuid.UUID `json:"guest_id,omitempty" firestore:"guest_id,omitempty"` // Solo en carritos de invitado
 	Items     []CartItem `json:"items" firestore:"items"`
 	UpdatedAt time.Time  `json:"updated_at" firestore:"updated_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" firestore:"expires_at,omitempty"` // Solo en carritos de invitado
+
+	ReminderSentAt *time.Time `json:"-" firestore:"reminder_sent_at,omitempty"` // Recordatorio de carrito abandonado ya enviado
+}
+
+// CartOwner identifica de quién es un carrito: un usuario autenticado o un
+// invitado, identificado por el ID firmado en su token de carrito
+type CartOwner struct {
+	UserID  uuid.UUID
+	GuestID uuid.UUID
+}
+
+// IsGuest indica si el carrito es de un invitado
+func (o CartOwner) IsGuest() bool {
+	return o.UserID == uuid.Nil
 }
 
 type CartItem struct {
-	ProductID    uuid.UUID `json:"product_id" firestore:"product_id"`
-	ProductName  string    `json:"product_name" firestore:"product_name"`
-	ProductPrice float64   `json:"product_price" firestore:"product_price"`
-	ProductImage string    `json:"product_image" firestore:"product_image"`
-	Quantity     int       `json:"quantity" firestore:"quantity"`
+	ProductID    uuid.UUID  `json:"product_id" firestore:"product_id"`
+	VariantID    *uuid.UUID `json:"variant_id" firestore:"variant_id"`
+	VariantName  string     `json:"variant_name" firestore:"variant_name"`
+	SKU          string     `json:"sku" firestore:"sku"`
+	ProductName  string     `json:"product_name" firestore:"product_name"`
+	ProductPrice float64    `json:"product_price" firestore:"product_price"`
+	ProductImage string     `json:"product_image" firestore:"product_image"`
+	Quantity     int        `json:"quantity" firestore:"quantity"`
+}
+
+// Matches indica si el item corresponde al producto/variante dados
+func (i *CartItem) Matches(productID uuid.UUID, variantID *uuid.UUID) bool {
+	return i.ProductID == productID && SameVariant(i.VariantID, variantID)
+}
+
+// SameVariant compara dos IDs de variante opcionales (nil == nil)
+func SameVariant(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// CartWarningType indica qué cambió en un item al revalidar el carrito
+type CartWarningType string
+
+const (
+	CartWarningPriceChanged    CartWarningType = "PRICE_CHANGED"    // El precio vigente es otro (p. ej. empezó o terminó una oferta)
+	CartWarningQuantityReduced CartWarningType = "QUANTITY_REDUCED" // Se bajó la cantidad al stock disponible
+	CartWarningItemUnavailable CartWarningType = "ITEM_UNAVAILABLE" // Producto eliminado o inactivo, variante inactiva o sin stock; se quitó del carrito
+)
+
+// CartWarning describe un ajuste hecho a un item al revalidar el carrito
+type CartWarning struct {
+	Type             CartWarningType `json:"type"`
+	ProductID        uuid.UUID       `json:"product_id"`
+	VariantID        *uuid.UUID      `json:"variant_id"`
+	ProductName      string          `json:"product_name"`
+	Message          string          `json:"message"`
+	PreviousPrice    float64         `json:"previous_price,omitempty"`
+	CurrentPrice     float64         `json:"current_price,omitempty"`
+	PreviousQuantity int             `json:"previous_quantity,omitempty"`
+	Quantity         int             `json:"quantity,omitempty"`
 }
 
 // DTOs
 
+// CartResponse es el carrito revalidado contra el catálogo con sus totales
+type CartResponse struct {
+	*Cart
+	Warnings  []CartWarning `json:"warnings"`
+	ItemCount int           `json:"item_count"`
+	Subtotal  float64       `json:"subtotal"`
+
+	Promotions []AppliedPromotion `json:"promotions"` // Promociones automáticas que aplicarían al comprar
+	Discount   float64            `json:"discount"`
+}
+
 type AddToCartRequest struct {
-	ProductID uuid.UUID `json:"product_id" validate:"required"`
-	Quantity  int       `json:"quantity" validate:"required,gt=0"`
+	ProductID uuid.UUID  `json:"product_id" validate:"required"`
+	VariantID *uuid.UUID `json:"variant_id"`
+	Quantity  int        `json:"quantity" validate:"required,gt=0"`
 }
 
 type UpdateCartItemRequest struct {
 	Quantity int `json:"quantity" validate:"required,gt=0"`
 }
-
-type SyncCartRequest struct {
-	Items []AddToCartRequest `json:"items" validate:"required"`
-}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CartReminder es un recordatorio de carrito abandonado enviado a un usuario.
+// Si el usuario compra dentro de la ventana de atribución, la orden se marca
+// como recuperada y se enlaza aquí.
+type CartReminder struct {
+	ID           uuid.UUID  `json:"id" firestore:"id"`
+	UserID       uuid.UUID  `json:"user_id" firestore:"user_id"`
+	Email        string     `json:"email" firestore:"email"`
+	ItemCount    int        `json:"item_count" firestore:"item_count"`
+	CartSubtotal float64    `json:"cart_subtotal" firestore:"cart_subtotal"`
+	DiscountCode string     `json:"discount_code,omitempty" firestore:"discount_code,omitempty"`
+	SentAt       time.Time  `json:"sent_at" firestore:"sent_at"`
+	OrderID      *uuid.UUID `json:"order_id,omitempty" firestore:"order_id,omitempty"` // Orden recuperada
+	RecoveredAt  *time.Time `json:"recovered_at,omitempty" firestore:"recovered_at,omitempty"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Category struct {
+	ID          uuid.UUID  `json:"id" firestore:"id"`
+	Name        string     `json:"name" firestore:"name"`
+	Slug        string     `json:"slug" firestore:"slug"`
+	ParentID    *uuid.UUID `json:"parent_id" firestore:"parent_id"` // nil = categoría raíz
+	Description string     `json:"description" firestore:"description"`
+	Image       string     `json:"image" firestore:"image"`
+	SortOrder   int        `json:"sort_order" firestore:"sort_order"`
+	IsActive    bool       `json:"is_active" firestore:"is_active"`
+	CreatedAt   time.Time  `json:"created_at" firestore:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" firestore:"updated_at"`
+}
+
+// CategoryNode es una categoría con sus subcategorías, para el árbol público
+type CategoryNode struct {
+	Category
+	Children []*CategoryNode `json:"children"`
+}
+
+// DTOs
+
+type CreateCategoryRequest struct {
+	Name        string     `json:"name" validate:"required,min=2"`
+	Slug        string     `json:"slug" validate:"omitempty"` // Se genera desde el nombre si viene vacío
+	ParentID    *uuid.UUID `json:"parent_id"`
+	Description string     `json:"description" validate:"omitempty"`
+	Image       string     `json:"image" validate:"omitempty,url"`
+	SortOrder   int        `json:"sort_order"`
+	IsActive    *bool      `json:"is_active"`
+}
+
+type UpdateCategoryRequest struct {
+	Name         *string    `json:"name" validate:"omitempty,min=2"`
+	Slug         *string    `json:"slug" validate:"omitempty"`
+	ParentID     *uuid.UUID `json:"parent_id"`
+	RemoveParent bool       `json:"remove_parent"` // true = convertir en categoría raíz
+	Description  *string    `json:"description" validate:"omitempty"`
+	Image        *string    `json:"image" validate:"omitempty,url"`
+	SortOrder    *int       `json:"sort_order"`
+	IsActive     *bool      `json:"is_active"`
+}
+
+// CategoryMigrationResult resume la conversión de categorías en texto libre a la colección categories
+type CategoryMigrationResult struct {
+	CategoriesCreated []Category `json:"categories_created"`
+	ProductsUpdated   int        `json:"products_updated"`
+	ProductsSkipped   int        `json:"products_skipped"` // Sin categoría o ya migrados
+}
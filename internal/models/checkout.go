@@ -0,0 +1,43 @@
+package models
+
+import "github.com/google/uuid"
+
+// CheckoutQuoteLine es el precio de un item tal como se cobraría al crear la orden
+type CheckoutQuoteLine struct {
+	ProductID   uuid.UUID  `json:"product_id"`
+	VariantID   *uuid.UUID `json:"variant_id"`
+	ProductName string     `json:"product_name"`
+	VariantName string     `json:"variant_name"`
+	SKU         string     `json:"sku"`
+	Quantity    int        `json:"quantity"`
+	UnitPrice   float64    `json:"unit_price"`
+	Subtotal    float64    `json:"subtotal"`
+	Discount    float64    `json:"discount"`        // Parte del descuento que corresponde a la línea
+	Error       string     `json:"error,omitempty"` // La línea no se puede comprar; no suma al subtotal
+}
+
+// CheckoutQuote es el desglose completo de un checkout. Lo arma el mismo código
+// que crea la orden, así que la cotización y el cobro siempre coinciden.
+type CheckoutQuote struct {
+	Lines             []CheckoutQuoteLine `json:"lines"`
+	ItemCount         int                 `json:"item_count"`
+	Subtotal          float64             `json:"subtotal"`
+	Promotions        []AppliedPromotion  `json:"promotions"` // Promociones automáticas aplicadas
+	DiscountCode      string              `json:"discount_code,omitempty"`
+	Discount          float64             `json:"discount"`           // Promociones más código de descuento
+	PromotionDiscount float64             `json:"promotion_discount"` // Parte de Discount que viene de promociones
+	Shipping          float64             `json:"shipping"`
+	Tax               float64             `json:"tax"` // IVA incluido en el total (informativo, no se suma)
+	Total             float64             `json:"total"`
+	Valid             bool                `json:"valid"`
+	Errors            []string            `json:"errors"` // Impiden crear la orden
+}
+
+// DTOs
+
+type CheckoutQuoteRequest struct {
+	Items           []CreateOrderItemRequest `json:"items" validate:"required,min=1,dive"`
+	DiscountCode    string                   `json:"discount_code"`
+	ShippingAddress *ShippingAddress         `json:"shipping_address"`
+	CustomerEmail   string                   `json:"customer_email" validate:"omitempty,email"` // Para los límites de compra por cliente
+}
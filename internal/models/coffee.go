@@ -0,0 +1,91 @@
+package models
+
+import "time"
+
+type CoffeeProcess string
+
+const (
+	ProcessWashed  CoffeeProcess = "WASHED"
+	ProcessNatural CoffeeProcess = "NATURAL"
+	ProcessHoney   CoffeeProcess = "HONEY"
+)
+
+type RoastLevel string
+
+const (
+	RoastLight      RoastLevel = "LIGHT"
+	RoastMedium     RoastLevel = "MEDIUM"
+	RoastMediumDark RoastLevel = "MEDIUM_DARK"
+	RoastDark       RoastLevel = "DARK"
+)
+
+type BrewMethod string
+
+const (
+	BrewEspresso    BrewMethod = "ESPRESSO"
+	BrewPourOver    BrewMethod = "POUR_OVER" // V60, Kalita, Chemex
+	BrewFrenchPress BrewMethod = "FRENCH_PRESS"
+	BrewMoka        BrewMethod = "MOKA"
+	BrewAeroPress   BrewMethod = "AEROPRESS"
+	BrewColdBrew    BrewMethod = "COLD_BREW"
+	BrewDrip        BrewMethod = "DRIP" // Cafetera de goteo
+)
+
+// Etiquetas en español, para mostrar y para el índice de búsqueda
+var (
+	CoffeeProcessLabels = map[CoffeeProcess]string{
+		ProcessWashed:  "Lavado",
+		ProcessNatural: "Natural",
+		ProcessHoney:   "Honey",
+	}
+	RoastLevelLabels = map[RoastLevel]string{
+		RoastLight:      "Tueste claro",
+		RoastMedium:     "Tueste medio",
+		RoastMediumDark: "Tueste medio oscuro",
+		RoastDark:       "Tueste oscuro",
+	}
+	BrewMethodLabels = map[BrewMethod]string{
+		BrewEspresso:    "Espresso",
+		BrewPourOver:    "Filtrado (V60, Chemex)",
+		BrewFrenchPress: "Prensa francesa",
+		BrewMoka:        "Moka italiana",
+		BrewAeroPress:   "AeroPress",
+		BrewColdBrew:    "Cold brew",
+		BrewDrip:        "Cafetera de goteo",
+	}
+)
+
+// CoffeeAttributes son los datos de origen y tueste de un café.
+// Los productos que no son café (accesorios, merch) no los tienen.
+type CoffeeAttributes struct {
+	OriginRegion string        `json:"origin_region" firestore:"origin_region"` // Ej: "Huila", "Sierra Nevada"
+	Farm         string        `json:"farm" firestore:"farm"`
+	AltitudeMin  int           `json:"altitude_min" firestore:"altitude_min"` // msnm
+	AltitudeMax  int           `json:"altitude_max" firestore:"altitude_max"` // msnm; igual a AltitudeMin si es un valor único
+	Varieties    []string      `json:"varieties" firestore:"varieties"`       // Caturra, Castillo, Geisha...
+	Process      CoffeeProcess `json:"process" firestore:"process"`
+	RoastLevel   RoastLevel    `json:"roast_level" firestore:"roast_level"`
+	BrewMethods  []BrewMethod  `json:"brew_methods" firestore:"brew_methods"` // Métodos recomendados
+	RoastDate    *time.Time    `json:"roast_date" firestore:"roast_date"`
+}
+
+// DTOs
+
+type CoffeeAttributesRequest struct {
+	OriginRegion string        `json:"origin_region" validate:"omitempty,max=100"`
+	Farm         string        `json:"farm" validate:"omitempty,max=100"`
+	AltitudeMin  int           `json:"altitude_min" validate:"omitempty,gte=0,lte=5000"`
+	AltitudeMax  int           `json:"altitude_max" validate:"omitempty,gte=0,lte=5000"`
+	Varieties    []string      `json:"varieties" validate:"omitempty,dive,required"`
+	Process      CoffeeProcess `json:"process" validate:"omitempty,oneof=WASHED NATURAL HONEY"`
+	RoastLevel   RoastLevel    `json:"roast_level" validate:"omitempty,oneof=LIGHT MEDIUM MEDIUM_DARK DARK"`
+	BrewMethods  []BrewMethod  `json:"brew_methods" validate:"omitempty,dive,oneof=ESPRESSO POUR_OVER FRENCH_PRESS MOKA AEROPRESS COLD_BREW DRIP"`
+	RoastDate    *time.Time    `json:"roast_date"`
+}
+
+// ValueFacet es un valor de atributo con su conteo (origen, proceso, tueste)
+type ValueFacet struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
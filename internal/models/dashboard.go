@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // --- Ventas ---
@@ -38,6 +40,9 @@ type SalesMonthlyMetrics struct {
 	OrdersWithDiscount int                           `json:"orders_with_discount" firestore:"orders_with_discount"`
 	PaymentMethods     map[string]PaymentMethodStats `json:"payment_methods" firestore:"payment_methods"`
 	DailyBreakdown     map[string]DailyBreakdown     `json:"daily_breakdown" firestore:"daily_breakdown"`
+	CartRemindersSent  int                           `json:"cart_reminders_sent" firestore:"cart_reminders_sent"` // Recordatorios de carrito abandonado enviados
+	RecoveredOrders    int                           `json:"recovered_orders" firestore:"recovered_orders"`       // Órdenes creadas tras un recordatorio
+	RecoveredRevenue   float64                       `json:"recovered_revenue" firestore:"recovered_revenue"`     // Revenue (entregadas) de esas órdenes
 	UpdatedAt          time.Time                     `json:"updated_at" firestore:"updated_at"`
 }
 
@@ -87,16 +92,27 @@ type BuyersYearlyMetrics struct {
 
 // ProductStats estadisticas de un producto individual
 type ProductStats struct {
-	ProductID     string  `json:"product_id" firestore:"product_id"`
-	ProductName   string  `json:"product_name" firestore:"product_name"`
-	TotalQuantity int     `json:"total_quantity" firestore:"total_quantity"`
-	TotalRevenue  float64 `json:"total_revenue" firestore:"total_revenue"`
-	OrderCount    int     `json:"order_count" firestore:"order_count"`
+	ProductID     string                     `json:"product_id" firestore:"product_id"`
+	ProductName   string                     `json:"product_name" firestore:"product_name"`
+	TotalQuantity int                        `json:"total_quantity" firestore:"total_quantity"`
+	TotalRevenue  float64                    `json:"total_revenue" firestore:"total_revenue"`
+	OrderCount    int                        `json:"order_count" firestore:"order_count"`
+	Variants      map[string]VariantStatsMap `json:"variants,omitempty" firestore:"variants,omitempty"`
 }
 
 // ProductStatsMap estadisticas de un producto en el mapa all_products
 type ProductStatsMap struct {
+	Name     string                     `json:"name" firestore:"name"`
+	Quantity int                        `json:"quantity" firestore:"quantity"`
+	Revenue  float64                    `json:"revenue" firestore:"revenue"`
+	Orders   int                        `json:"orders" firestore:"orders"`
+	Variants map[string]VariantStatsMap `json:"variants,omitempty" firestore:"variants,omitempty"` // key = variant_id
+}
+
+// VariantStatsMap estadisticas de una variante dentro de ProductStatsMap
+type VariantStatsMap struct {
 	Name     string  `json:"name" firestore:"name"`
+	SKU      string  `json:"sku" firestore:"sku"`
 	Quantity int     `json:"quantity" firestore:"quantity"`
 	Revenue  float64 `json:"revenue" firestore:"revenue"`
 	Orders   int     `json:"orders" firestore:"orders"`
@@ -120,6 +136,9 @@ type DashboardSummaryCurrentMonth struct {
 	Orders        int     `json:"orders"`
 	AverageTicket float64 `json:"average_ticket"`
 	NewBuyers     int     `json:"new_buyers"`
+
+	CartRemindersSent int `json:"cart_reminders_sent"`
+	RecoveredOrders   int `json:"recovered_orders"`
 }
 
 // DashboardSummaryCurrentYear resumen del ano actual
@@ -143,3 +162,46 @@ type DashboardSummary struct {
 	GenderBreakdown map[string]int               `json:"gender_breakdown"`
 	AgeBreakdown    map[string]int               `json:"age_breakdown"`
 }
+
+// --- Descuentos y campañas ---
+
+// DiscountPerformance rendimiento de las órdenes de un código o campaña.
+// Las órdenes canceladas solo cuentan en CancelledOrders.
+type DiscountPerformance struct {
+	Orders             int     `json:"orders"` // En un código: sus canjes
+	CompletedOrders    int     `json:"completed_orders"`
+	CancelledOrders    int     `json:"cancelled_orders"`
+	GrossRevenue       float64 `json:"gross_revenue"` // Subtotal antes de descuentos
+	DiscountCost       float64 `json:"discount_cost"` // Solo el código de descuento; las promociones automáticas no cuentan
+	NetRevenue         float64 `json:"net_revenue"`   // Total cobrado
+	AverageTicket      float64 `json:"average_ticket"`
+	NewCustomers       int     `json:"new_customers"` // Clientes cuya primera compra fue una de estas órdenes
+	ReturningCustomers int     `json:"returning_customers"`
+}
+
+// DiscountCodeReport rendimiento de un código de descuento
+type DiscountCodeReport struct {
+	DiscountCodeID uuid.UUID      `json:"discount_code_id"`
+	Code           string         `json:"code"` // Vacío si el código fue eliminado
+	Type           DiscountType   `json:"type"`
+	CampaignID     string         `json:"campaign_id"`
+	UTMCampaigns   map[string]int `json:"utm_campaigns"` // Órdenes por utm_campaign
+	DiscountPerformance
+}
+
+// CampaignReport rendimiento de una campaña: órdenes con su utm_campaign o con un código de la campaña
+type CampaignReport struct {
+	CampaignID     string   `json:"campaign_id"`
+	OrdersWithCode int      `json:"orders_with_code"`
+	Codes          []string `json:"codes"`
+	DiscountPerformance
+}
+
+// DiscountReport reporte de códigos y campañas en un rango de fechas (por fecha de la orden)
+type DiscountReport struct {
+	From      time.Time            `json:"from"`
+	To        time.Time            `json:"to"`
+	Totals    DiscountPerformance  `json:"totals"` // Todas las órdenes con código
+	Codes     []DiscountCodeReport `json:"codes"`
+	Campaigns []CampaignReport     `json:"campaigns"`
+}
@@ -9,62 +9,179 @@ import (
 type DiscountType string
 
 const (
-	DiscountPercentage  DiscountType = "PERCENTAGE"
-	DiscountFixedAmount DiscountType = "FIXED_AMOUNT"
+	DiscountPercentage   DiscountType = "PERCENTAGE"
+	DiscountFixedAmount  DiscountType = "FIXED_AMOUNT"
+	DiscountFreeShipping DiscountType = "FREE_SHIPPING" // Waives the shipping cost; Value is ignored
 )
 
 type DiscountCode struct {
-	ID          uuid.UUID    `json:"id" firestore:"id"`
-	Code        string       `json:"code" firestore:"code"`
-	Description string       `json:"description" firestore:"description"`
-	Type        DiscountType `json:"type" firestore:"type"`
-	Value       float64      `json:"value" firestore:"value"`
-	MinPurchase *float64     `json:"min_purchase" firestore:"min_purchase"`
-	MaxUses     *int         `json:"max_uses" firestore:"max_uses"`
-	UsedCount   int          `json:"used_count" firestore:"used_count"`
-	StartDate   time.Time    `json:"start_date" firestore:"start_date"`
-	EndDate     time.Time    `json:"end_date" firestore:"end_date"`
-	IsActive    bool         `json:"is_active" firestore:"is_active"`
-	CreatedAt   time.Time    `json:"created_at" firestore:"created_at"`
-	UpdatedAt   time.Time    `json:"updated_at" firestore:"updated_at"`
+	ID                 uuid.UUID    `json:"id" firestore:"id"`
+	Code               string       `json:"code" firestore:"code"`
+	Description        string       `json:"description" firestore:"description"`
+	Type               DiscountType `json:"type" firestore:"type"`
+	Value              float64      `json:"value" firestore:"value"`
+	MinPurchase        *float64     `json:"min_purchase" firestore:"min_purchase"`
+	MaxUses            *int         `json:"max_uses" firestore:"max_uses"`
+	MaxUsesPerCustomer *int         `json:"max_uses_per_customer" firestore:"max_uses_per_customer"` // Redemptions per user/email; nil = unlimited
+	MaxDiscount        *float64     `json:"max_discount" firestore:"max_discount"`                   // Cap for percentage codes; nil = no cap
+	ProductIDs         []uuid.UUID  `json:"product_ids" firestore:"product_ids"`                     // Scope: eligible products (with categories, either matches); empty = whole purchase
+	CategoryIDs        []uuid.UUID  `json:"category_ids" firestore:"category_ids"`                   // Scope: eligible categories, subcategories included
+	FirstOrderOnly     bool         `json:"first_order_only" firestore:"first_order_only"`           // Only for customers without previous orders
+	BatchID            *uuid.UUID   `json:"batch_id" firestore:"batch_id"`                           // Set on codes generated in bulk
+	CampaignID         string       `json:"campaign_id" firestore:"campaign_id"`
+	UsedCount          int          `json:"used_count" firestore:"used_count"`
+	StartDate          time.Time    `json:"start_date" firestore:"start_date"`
+	EndDate            time.Time    `json:"end_date" firestore:"end_date"`
+	IsActive           bool         `json:"is_active" firestore:"is_active"`
+	CreatedAt          time.Time    `json:"created_at" firestore:"created_at"`
+	UpdatedAt          time.Time    `json:"updated_at" firestore:"updated_at"`
+}
+
+// IsScoped reports whether the code only applies to some products or categories
+func (d *DiscountCode) IsScoped() bool {
+	return len(d.ProductIDs) > 0 || len(d.CategoryIDs) > 0
+}
+
+// DiscountRedemption records one use of a discount code by an order. Released
+// redemptions (cancelled orders) stay for reporting but no longer count toward limits.
+type DiscountRedemption struct {
+	ID             uuid.UUID  `json:"id" firestore:"id"`
+	DiscountCodeID uuid.UUID  `json:"discount_code_id" firestore:"discount_code_id"`
+	Code           string     `json:"code" firestore:"code"`
+	OrderID        uuid.UUID  `json:"order_id" firestore:"order_id"`
+	UserID         *uuid.UUID `json:"user_id" firestore:"user_id"`
+	Email          string     `json:"email" firestore:"email"` // Lowercased, so guest and user orders match
+	Amount         float64    `json:"amount" firestore:"amount"`
+	BatchID        *uuid.UUID `json:"batch_id" firestore:"batch_id"` // Copied from the code, for per-batch stats
+	RedeemedAt     time.Time  `json:"redeemed_at" firestore:"redeemed_at"`
+	ReleasedAt     *time.Time `json:"released_at" firestore:"released_at"`
+}
+
+// DiscountBatch is a set of single-use codes generated together for a campaign.
+// Every code shares the batch rules; deactivating the batch deactivates all of them.
+type DiscountBatch struct {
+	ID             uuid.UUID    `json:"id" firestore:"id"`
+	CampaignID     string       `json:"campaign_id" firestore:"campaign_id"`
+	Prefix         string       `json:"prefix" firestore:"prefix"`
+	Length         int          `json:"length" firestore:"length"` // Random characters after the prefix
+	Quantity       int          `json:"quantity" firestore:"quantity"`
+	Description    string       `json:"description" firestore:"description"`
+	Type           DiscountType `json:"type" firestore:"type"`
+	Value          float64      `json:"value" firestore:"value"`
+	MinPurchase    *float64     `json:"min_purchase" firestore:"min_purchase"`
+	MaxDiscount    *float64     `json:"max_discount" firestore:"max_discount"`
+	ProductIDs     []uuid.UUID  `json:"product_ids" firestore:"product_ids"`
+	CategoryIDs    []uuid.UUID  `json:"category_ids" firestore:"category_ids"`
+	FirstOrderOnly bool         `json:"first_order_only" firestore:"first_order_only"`
+	StartDate      time.Time    `json:"start_date" firestore:"start_date"`
+	EndDate        time.Time    `json:"end_date" firestore:"end_date"`
+	IsActive       bool         `json:"is_active" firestore:"is_active"`
+	CreatedAt      time.Time    `json:"created_at" firestore:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at" firestore:"updated_at"`
+}
+
+// DiscountBatchStats summarizes the redemptions of a batch. Released redemptions
+// (cancelled orders) are counted apart and left out of the other figures.
+type DiscountBatchStats struct {
+	Codes               int     `json:"codes"`
+	ActiveCodes         int     `json:"active_codes"`
+	RedeemedCodes       int     `json:"redeemed_codes"`
+	Redemptions         int     `json:"redemptions"`
+	ReleasedRedemptions int     `json:"released_redemptions"`
+	RedemptionRate      float64 `json:"redemption_rate"` // Percent of codes redeemed
+	DiscountGiven       float64 `json:"discount_given"`
 }
 
 // DTOs
 
+// GenerateDiscountBatchRequest generates Quantity single-use codes PREFIX-XXXXXXXX
+type GenerateDiscountBatchRequest struct {
+	CampaignID     string       `json:"campaign_id" validate:"required,max=50"`
+	Prefix         string       `json:"prefix" validate:"required,alphanum,min=2,max=8"`
+	Length         int          `json:"length" validate:"omitempty,gte=6,lte=11"` // Default 8
+	Quantity       int          `json:"quantity" validate:"required,gte=1,lte=1000"`
+	Description    string       `json:"description" validate:"required"`
+	Type           DiscountType `json:"type" validate:"required,oneof=PERCENTAGE FIXED_AMOUNT FREE_SHIPPING"`
+	Value          float64      `json:"value" validate:"required_unless=Type FREE_SHIPPING,omitempty,gt=0"`
+	MinPurchase    *float64     `json:"min_purchase" validate:"omitempty,gt=0"`
+	MaxDiscount    *float64     `json:"max_discount" validate:"omitempty,gt=0"`
+	ProductIDs     []uuid.UUID  `json:"product_ids"`
+	CategoryIDs    []uuid.UUID  `json:"category_ids"`
+	FirstOrderOnly bool         `json:"first_order_only"`
+	StartDate      time.Time    `json:"start_date" validate:"required"`
+	EndDate        time.Time    `json:"end_date" validate:"required"`
+	IsActive       bool         `json:"is_active"`
+}
+
+type DiscountBatchResponse struct {
+	Batch *DiscountBatch     `json:"batch"`
+	Stats DiscountBatchStats `json:"stats"`
+}
+
+type DiscountBatchListResponse struct {
+	Batches []DiscountBatchResponse `json:"batches"`
+	Total   int                     `json:"total"`
+}
+
 type CreateDiscountCodeRequest struct {
-	Code        string       `json:"code" validate:"required,min=3,max=20"`
-	Description string       `json:"description" validate:"required"`
-	Type        DiscountType `json:"type" validate:"required"`
-	Value       float64      `json:"value" validate:"required,gt=0"`
-	MinPurchase *float64     `json:"min_purchase" validate:"omitempty,gt=0"`
-	MaxUses     *int         `json:"max_uses" validate:"omitempty,gt=0"`
-	StartDate   time.Time    `json:"start_date" validate:"required"`
-	EndDate     time.Time    `json:"end_date" validate:"required"`
-	IsActive    bool         `json:"is_active"`
+	Code               string       `json:"code" validate:"required,min=3,max=20"`
+	Description        string       `json:"description" validate:"required"`
+	Type               DiscountType `json:"type" validate:"required,oneof=PERCENTAGE FIXED_AMOUNT FREE_SHIPPING"`
+	Value              float64      `json:"value" validate:"required_unless=Type FREE_SHIPPING,omitempty,gt=0"`
+	MinPurchase        *float64     `json:"min_purchase" validate:"omitempty,gt=0"`
+	MaxUses            *int         `json:"max_uses" validate:"omitempty,gt=0"`
+	MaxUsesPerCustomer *int         `json:"max_uses_per_customer" validate:"omitempty,gt=0"`
+	MaxDiscount        *float64     `json:"max_discount" validate:"omitempty,gt=0"`
+	ProductIDs         []uuid.UUID  `json:"product_ids"`
+	CategoryIDs        []uuid.UUID  `json:"category_ids"`
+	FirstOrderOnly     bool         `json:"first_order_only"`
+	CampaignID         string       `json:"campaign_id" validate:"omitempty,max=50"` // Matches the orders' utm_campaign in reports
+	StartDate          time.Time    `json:"start_date" validate:"required"`
+	EndDate            time.Time    `json:"end_date" validate:"required"`
+	IsActive           bool         `json:"is_active"`
 }
 
 type UpdateDiscountCodeRequest struct {
-	Code        string       `json:"code" validate:"omitempty,min=3,max=20"`
-	Description string       `json:"description"`
-	Type        DiscountType `json:"type" validate:"omitempty"`
-	Value       float64      `json:"value" validate:"omitempty,gt=0"`
-	MinPurchase *float64     `json:"min_purchase" validate:"omitempty,gt=0"`
-	MaxUses     *int         `json:"max_uses" validate:"omitempty,gt=0"`
-	StartDate   time.Time    `json:"start_date" validate:"omitempty"`
-	EndDate     time.Time    `json:"end_date" validate:"omitempty"`
-	IsActive    *bool        `json:"is_active" validate:"omitempty"`
+	Code               string       `json:"code" validate:"omitempty,min=3,max=20"`
+	Description        string       `json:"description"`
+	Type               DiscountType `json:"type" validate:"omitempty,oneof=PERCENTAGE FIXED_AMOUNT FREE_SHIPPING"`
+	Value              float64      `json:"value" validate:"omitempty,gt=0"`
+	MinPurchase        *float64     `json:"min_purchase" validate:"omitempty,gt=0"`
+	MaxUses            *int         `json:"max_uses" validate:"omitempty,gt=0"`
+	MaxUsesPerCustomer *int         `json:"max_uses_per_customer" validate:"omitempty,gt=0"`
+	MaxDiscount        *float64     `json:"max_discount" validate:"omitempty,gte=0"` // 0 removes the cap
+	ProductIDs         []uuid.UUID  `json:"product_ids"`                             // Replaces the scope; [] clears it
+	CategoryIDs        []uuid.UUID  `json:"category_ids"`
+	FirstOrderOnly     *bool        `json:"first_order_only"`
+	CampaignID         *string      `json:"campaign_id" validate:"omitempty,max=50"` // "" removes it
+	StartDate          time.Time    `json:"start_date" validate:"omitempty"`
+	EndDate            time.Time    `json:"end_date" validate:"omitempty"`
+	IsActive           *bool        `json:"is_active" validate:"omitempty"`
 }
 
 type ValidateDiscountRequest struct {
-	Code          string  `json:"code" validate:"required"`
-	PurchaseTotal float64 `json:"purchase_total" validate:"required,gt=0"`
+	Code          string     `json:"code" validate:"required"`
+	PurchaseTotal float64    `json:"purchase_total" validate:"required,gt=0"`
+	Email         string     `json:"email" validate:"omitempty,email"` // Checks the per-customer and first-order limits when present
+	UserID        *uuid.UUID `json:"-"`
+
+	Lines []DiscountLine `json:"-"` // Set by checkout: scoped codes are computed per eligible line
+}
+
+// DiscountLine is a priced checkout line a discount may apply to
+type DiscountLine struct {
+	ProductID  uuid.UUID
+	CategoryID *uuid.UUID
+	Subtotal   float64
 }
 
 type ValidateDiscountResponse struct {
-	Valid          bool         `json:"valid"`
+	Valid          bool          `json:"valid"`
 	DiscountCode   *DiscountCode `json:"discount_code"`
-	DiscountAmount float64      `json:"discount_amount"`
-	Message        string       `json:"message"`
+	DiscountAmount float64       `json:"discount_amount"`
+	FreeShipping   bool          `json:"free_shipping"`
+	LineDiscounts  []float64     `json:"-"` // Share of DiscountAmount per request line
+	Message        string        `json:"message"`
 }
 
 type DiscountCodeListResponse struct {
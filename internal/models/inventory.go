@@ -0,0 +1,100 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type InventoryReason string
+
+const (
+	InventorySale               InventoryReason = "SALE"
+	InventoryCancellationReturn InventoryReason = "CANCELLATION_RETURN"
+	InventoryManualAdjustment   InventoryReason = "MANUAL_ADJUSTMENT"
+	InventoryReturnRestock      InventoryReason = "RETURN_RESTOCK"
+	InventoryStocktake          InventoryReason = "STOCKTAKE"
+)
+
+// InventoryMovement es un registro inmutable de un cambio de stock.
+// Balance es el stock resultante del producto (o de la variante, si aplica).
+type InventoryMovement struct {
+	ID          uuid.UUID       `json:"id" firestore:"id"`
+	ProductID   uuid.UUID       `json:"product_id" firestore:"product_id"`
+	VariantID   *uuid.UUID      `json:"variant_id,omitempty" firestore:"variant_id,omitempty"`
+	ProductName string          `json:"product_name" firestore:"product_name"`
+	VariantName string          `json:"variant_name,omitempty" firestore:"variant_name,omitempty"`
+	SKU         string          `json:"sku,omitempty" firestore:"sku,omitempty"`
+	Delta       int             `json:"delta" firestore:"delta"`
+	Balance     int             `json:"balance" firestore:"balance"`
+	Reason      InventoryReason `json:"reason" firestore:"reason"`
+	Reference   string          `json:"reference" firestore:"reference"` // Número de orden, conteo, etc.
+	Note        string          `json:"note,omitempty" firestore:"note,omitempty"`
+	ActorID     *uuid.UUID      `json:"actor_id" firestore:"actor_id"` // nil = sistema o cliente invitado
+	CreatedAt   time.Time       `json:"created_at" firestore:"created_at"`
+}
+
+// StockChange describe un cambio de stock a aplicar.
+// Delta suma o resta unidades; SetTo (conteo físico) fija el stock y el delta se calcula.
+type StockChange struct {
+	ProductID uuid.UUID
+	VariantID *uuid.UUID
+	Delta     int
+	SetTo     *int
+	Reason    InventoryReason
+	Reference string
+	Note      string
+	ActorID   *uuid.UUID
+
+	// BundleItems es la composición del kit al momento de la venta. Si viene, una devolución
+	// repone exactamente lo que se descontó aunque el kit se haya editado después.
+	BundleItems []BundleComponent
+}
+
+// DTOs
+
+// UpdateStockRequest es el ajuste manual de stock desde el panel.
+// STOCKTAKE usa counted (stock contado); los demás motivos usan quantity (delta).
+type UpdateStockRequest struct {
+	Quantity  int             `json:"quantity"`
+	Counted   *int            `json:"counted" validate:"omitempty,gte=0"`
+	VariantID *uuid.UUID      `json:"variant_id"`
+	Reason    InventoryReason `json:"reason" validate:"omitempty,oneof=MANUAL_ADJUSTMENT RETURN_RESTOCK STOCKTAKE"`
+	Reference string          `json:"reference" validate:"omitempty,max=100"`
+	Note      string          `json:"note" validate:"omitempty,max=500"`
+}
+
+type InventoryMovementFilter struct {
+	ProductID uuid.UUID
+	From      *time.Time
+	To        *time.Time
+	Reason    InventoryReason
+}
+
+// InventoryMovementsResponse incluye los totales del período para cuadrar contra el stock
+type InventoryMovementsResponse struct {
+	Movements []*InventoryMovement `json:"movements"`
+	TotalIn   int                  `json:"total_in"`
+	TotalOut  int                  `json:"total_out"`
+	Net       int                  `json:"net"`
+}
+
+// LowStockItem es un producto (o variante) en o por debajo de su umbral de reorden
+type LowStockItem struct {
+	ProductID        uuid.UUID  `json:"product_id"`
+	VariantID        *uuid.UUID `json:"variant_id,omitempty"`
+	ProductName      string     `json:"product_name"`
+	VariantName      string     `json:"variant_name,omitempty"`
+	SKU              string     `json:"sku,omitempty"`
+	Stock            int        `json:"stock"`
+	ReorderThreshold int        `json:"reorder_threshold"`
+	UnitsSold        int        `json:"units_sold"`     // Ventas netas de cancelaciones en el período
+	DailyVelocity    float64    `json:"daily_velocity"` // Unidades vendidas por día
+	DaysOfCover      *float64   `json:"days_of_cover"`  // Días que alcanza el stock al ritmo actual; nil sin ventas
+}
+
+type LowStockReport struct {
+	Days        int            `json:"days"`
+	Items       []LowStockItem `json:"items"`
+	GeneratedAt time.Time      `json:"generated_at"`
+}
@@ -13,7 +13,10 @@ const (
 	NotificationUnread NotificationStatus = "UNREAD"
 	NotificationRead   NotificationStatus = "READ"
 
-	NotificationOrderStatus NotificationType = "ORDER_STATUS"
+	NotificationOrderStatus  NotificationType = "ORDER_STATUS"
+	NotificationLowStock     NotificationType = "LOW_STOCK"
+	NotificationBackInStock  NotificationType = "BACK_IN_STOCK"
+	NotificationWishlistSale NotificationType = "WISHLIST_SALE"
 )
 
 type Notification struct {
@@ -27,6 +30,7 @@ type Notification struct {
 	Body        string             `json:"body" firestore:"body"`
 	Icon        string             `json:"icon" firestore:"icon"`
 	OrderStatus string             `json:"order_status" firestore:"order_status"`
+	ProductID   *uuid.UUID         `json:"product_id,omitempty" firestore:"product_id,omitempty"`
 	CreatedAt   time.Time          `json:"created_at" firestore:"created_at"`
 	ReadAt      *time.Time         `json:"read_at,omitempty" firestore:"read_at,omitempty"`
 }
@@ -46,6 +46,8 @@ type Order struct {
 	CustomerPhone      string           `json:"customer_phone" firestore:"customer_phone"`
 	Subtotal           float64          `json:"subtotal" firestore:"subtotal"`
 	Discount           float64          `json:"discount" firestore:"discount"`
+	Shipping           float64          `json:"shipping" firestore:"shipping"`
+	Tax                float64          `json:"tax" firestore:"tax"` // IVA incluido en el total (informativo)
 	Total              float64          `json:"total" firestore:"total"`
 	PaymentMethod      PaymentMethod    `json:"payment_method" firestore:"payment_method"`
 	PaymentStatus      PaymentStatus    `json:"payment_status" firestore:"payment_status"`
@@ -57,25 +59,37 @@ type Order struct {
 	UTMSource          string           `json:"utm_source" firestore:"utm_source"`
 	UTMMedium          string           `json:"utm_medium" firestore:"utm_medium"`
 	UTMCampaign        string           `json:"utm_campaign" firestore:"utm_campaign"`
+	RecoveredCart      bool             `json:"recovered_cart" firestore:"recovered_cart"` // Compra hecha tras un recordatorio de carrito abandonado
 	CreatedAt          time.Time        `json:"created_at" firestore:"created_at"`
 	UpdatedAt          time.Time        `json:"updated_at" firestore:"updated_at"`
+
+	// Promociones automáticas aplicadas; su monto ya está incluido en Discount
+	Promotions        []AppliedPromotion `json:"promotions" firestore:"promotions"`
+	PromotionDiscount float64            `json:"promotion_discount" firestore:"promotion_discount"`
 }
 
 type OrderItem struct {
-	ID          uuid.UUID `json:"id" firestore:"id"`
-	OrderID     uuid.UUID `json:"order_id" firestore:"order_id"`
-	ProductID   uuid.UUID `json:"product_id" firestore:"product_id"`
-	ProductName string    `json:"product_name" firestore:"product_name"`
-	Quantity    int       `json:"quantity" firestore:"quantity"`
-	Price       float64   `json:"price" firestore:"price"`
-	Subtotal    float64   `json:"subtotal" firestore:"subtotal"`
+	ID          uuid.UUID  `json:"id" firestore:"id"`
+	OrderID     uuid.UUID  `json:"order_id" firestore:"order_id"`
+	ProductID   uuid.UUID  `json:"product_id" firestore:"product_id"`
+	VariantID   *uuid.UUID `json:"variant_id" firestore:"variant_id"`
+	VariantName string     `json:"variant_name" firestore:"variant_name"`
+	SKU         string     `json:"sku" firestore:"sku"`
+	ProductName string     `json:"product_name" firestore:"product_name"`
+	Quantity    int        `json:"quantity" firestore:"quantity"`
+	Price       float64    `json:"price" firestore:"price"`
+	Subtotal    float64    `json:"subtotal" firestore:"subtotal"`
+	Discount    float64    `json:"discount" firestore:"discount"` // Parte del descuento de la orden aplicada a la línea
+
+	BundleItems []BundleComponent `json:"bundle_items,omitempty" firestore:"bundle_items,omitempty"` // Composición del kit al comprarlo
 }
 
 // DTOs
 
 type CreateOrderItemRequest struct {
-	ProductID uuid.UUID `json:"product_id" validate:"required"`
-	Quantity  int       `json:"quantity" validate:"required,gt=0"`
+	ProductID uuid.UUID  `json:"product_id" validate:"required"`
+	VariantID *uuid.UUID `json:"variant_id"`
+	Quantity  int        `json:"quantity" validate:"required,gt=0"`
 }
 
 type CreateOrderRequest struct {
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PriceChange registra un cambio de precio u oferta de un producto o variante.
+// Guarda el estado resultante y los precios anteriores (nil en el alta).
+type PriceChange struct {
+	ID                uuid.UUID  `json:"id" firestore:"id"`
+	ProductID         uuid.UUID  `json:"product_id" firestore:"product_id"`
+	VariantID         *uuid.UUID `json:"variant_id,omitempty" firestore:"variant_id,omitempty"`
+	ProductName       string     `json:"product_name" firestore:"product_name"`
+	VariantName       string     `json:"variant_name,omitempty" firestore:"variant_name,omitempty"`
+	SKU               string     `json:"sku,omitempty" firestore:"sku,omitempty"`
+	Price             float64    `json:"price" firestore:"price"`
+	PreviousPrice     *float64   `json:"previous_price" firestore:"previous_price"`
+	SalePrice         *float64   `json:"sale_price" firestore:"sale_price"`
+	PreviousSalePrice *float64   `json:"previous_sale_price" firestore:"previous_sale_price"`
+	SaleStartsAt      *time.Time `json:"sale_starts_at" firestore:"sale_starts_at"`
+	SaleEndsAt        *time.Time `json:"sale_ends_at" firestore:"sale_ends_at"`
+	ActorID           *uuid.UUID `json:"actor_id" firestore:"actor_id"`
+	CreatedAt         time.Time  `json:"created_at" firestore:"created_at"`
+}
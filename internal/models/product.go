@@ -1,61 +1,301 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type Product struct {
-	ID          uuid.UUID `json:"id" firestore:"id"`
-	Name        string    `json:"name" firestore:"name"`
-	Description string    `json:"description" firestore:"description"`
-	Price       float64   `json:"price" firestore:"price"`
-	Weight      int       `json:"weight" firestore:"weight"` // en gramos
-	Stock       int       `json:"stock" firestore:"stock"`
-	Category    string    `json:"category" firestore:"category"`
-	Images      []string  `json:"images" firestore:"images"`
-	IsActive    bool      `json:"is_active" firestore:"is_active"`
-	IsFeatured  bool      `json:"is_featured" firestore:"is_featured"`
-	CreatedAt   time.Time `json:"created_at" firestore:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" firestore:"updated_at"`
+	ID               uuid.UUID         `json:"id" firestore:"id"`
+	Name             string            `json:"name" firestore:"name"`
+	Slug             string            `json:"slug" firestore:"slug"`
+	SKU              string            `json:"sku,omitempty" firestore:"sku,omitempty"`   // SKU del producto simple; las variantes tienen el suyo
+	PreviousSlugs    []string          `json:"previous_slugs" firestore:"previous_slugs"` // Slugs anteriores, redirigen al actual
+	Description      string            `json:"description" firestore:"description"`
+	Price            float64           `json:"price" firestore:"price"`
+	SalePrice        *float64          `json:"sale_price" firestore:"sale_price"` // Oferta programada (solo productos simples)
+	SaleStartsAt     *time.Time        `json:"sale_starts_at" firestore:"sale_starts_at"`
+	SaleEndsAt       *time.Time        `json:"sale_ends_at" firestore:"sale_ends_at"`
+	EffectivePrice   float64           `json:"effective_price" firestore:"-"` // Precio vigente; con variantes, el menor de ellas
+	OnSale           bool              `json:"on_sale" firestore:"-"`
+	Weight           int               `json:"weight" firestore:"weight"` // en gramos
+	Stock            int               `json:"stock" firestore:"stock"`
+	ReorderThreshold int               `json:"reorder_threshold" firestore:"reorder_threshold"` // Alerta cuando el stock llega a este nivel
+	CategoryID       *uuid.UUID        `json:"category_id" firestore:"category_id"`
+	Category         string            `json:"category" firestore:"category"` // Nombre denormalizado de la categoría
+	Images           []string          `json:"images" firestore:"images"`
+	TastingNotes     []string          `json:"tasting_notes" firestore:"tasting_notes"` // Notas de cata: "chocolate", "frutos rojos"...
+	Coffee           *CoffeeAttributes `json:"coffee" firestore:"coffee"`               // Origen, proceso y tueste; nil si no es café
+	Options          []ProductOption   `json:"options" firestore:"options"`             // Ejes de variante (tamaño, molienda...)
+	Variants         []ProductVariant  `json:"variants" firestore:"variants"`           // Vacío = producto simple
+	BundleItems      []BundleComponent `json:"bundle_items" firestore:"bundle_items"`   // Vacío = no es un kit
+	BundleProductIDs []string          `json:"-" firestore:"bundle_product_ids"`        // Índice de componentes (array-contains)
+	SKUs             []string          `json:"-" firestore:"skus"`                      // Índice para buscar por SKU (array-contains)
+	Rating           float64           `json:"rating" firestore:"rating"`               // Promedio de reseñas aprobadas
+	ReviewCount      int               `json:"review_count" firestore:"review_count"`   // Cantidad de reseñas aprobadas
+	MetaTitle        string            `json:"meta_title" firestore:"meta_title"`
+	MetaDescription  string            `json:"meta_description" firestore:"meta_description"`
+	CanonicalURL     string            `json:"canonical_url" firestore:"canonical_url"`
+	PurchaseRules    *PurchaseRules    `json:"purchase_rules" firestore:"purchase_rules"` // Límites de compra; nil = sin reglas
+	IsActive         bool              `json:"is_active" firestore:"is_active"`
+	IsFeatured       bool              `json:"is_featured" firestore:"is_featured"`
+	CreatedAt        time.Time         `json:"created_at" firestore:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at" firestore:"updated_at"`
+}
+
+// ProductOption define un eje de variante y sus valores permitidos.
+// Ej: {Name: "Tamaño", Values: ["250 g", "500 g", "1 kg"]}
+type ProductOption struct {
+	Name   string   `json:"name" firestore:"name"`
+	Values []string `json:"values" firestore:"values"`
+}
+
+// ProductVariant es una combinación concreta de opciones con su propio SKU, precio, peso y stock
+type ProductVariant struct {
+	ID       uuid.UUID         `json:"id" firestore:"id"`
+	SKU      string            `json:"sku" firestore:"sku"`
+	Options  map[string]string `json:"options" firestore:"options"` // nombre de opción → valor
+	Price    float64           `json:"price" firestore:"price"`
+	Weight   int               `json:"weight" firestore:"weight"` // en gramos
+	Stock    int               `json:"stock" firestore:"stock"`
+	IsActive bool              `json:"is_active" firestore:"is_active"`
+
+	SalePrice      *float64   `json:"sale_price" firestore:"sale_price"`
+	SaleStartsAt   *time.Time `json:"sale_starts_at" firestore:"sale_starts_at"`
+	SaleEndsAt     *time.Time `json:"sale_ends_at" firestore:"sale_ends_at"`
+	EffectivePrice float64    `json:"effective_price" firestore:"-"`
+	OnSale         bool       `json:"on_sale" firestore:"-"`
+}
+
+// HasVariants indica si el producto se vende por variantes
+func (p *Product) HasVariants() bool {
+	return len(p.Variants) > 0
+}
+
+// FindVariant busca una variante por ID. Retorna nil si no existe.
+func (p *Product) FindVariant(id uuid.UUID) *ProductVariant {
+	for i := range p.Variants {
+		if p.Variants[i].ID == id {
+			return &p.Variants[i]
+		}
+	}
+	return nil
+}
+
+// VariantLabel arma el nombre legible de una variante respetando el orden de las opciones del producto.
+// Ej: "500 g / Molido"
+func (p *Product) VariantLabel(v *ProductVariant) string {
+	if v == nil {
+		return ""
+	}
+	var parts []string
+	for _, opt := range p.Options {
+		if value, ok := v.Options[opt.Name]; ok && value != "" {
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, " / ")
+}
+
+// saleActive indica si la oferta está vigente en t. Sin fechas, la oferta no vence.
+// Una oferta igual o mayor al precio regular se ignora.
+func saleActive(salePrice *float64, startsAt, endsAt *time.Time, regular float64, t time.Time) bool {
+	if salePrice == nil || *salePrice <= 0 || *salePrice >= regular {
+		return false
+	}
+	if startsAt != nil && t.Before(*startsAt) {
+		return false
+	}
+	if endsAt != nil && !t.Before(*endsAt) {
+		return false
+	}
+	return true
+}
+
+// PriceAt retorna el precio de la variante vigente en t
+func (v *ProductVariant) PriceAt(t time.Time) float64 {
+	if saleActive(v.SalePrice, v.SaleStartsAt, v.SaleEndsAt, v.Price, t) {
+		return *v.SalePrice
+	}
+	return v.Price
+}
+
+// PriceAt retorna el precio vigente en t de un producto simple
+func (p *Product) PriceAt(t time.Time) float64 {
+	if saleActive(p.SalePrice, p.SaleStartsAt, p.SaleEndsAt, p.Price, t) {
+		return *p.SalePrice
+	}
+	return p.Price
+}
+
+// ApplyPricing calcula EffectivePrice y OnSale del producto y sus variantes en t.
+// Con variantes, el producto muestra el menor precio vigente entre las activas ("desde $X").
+func (p *Product) ApplyPricing(t time.Time) {
+	for i := range p.Variants {
+		v := &p.Variants[i]
+		v.EffectivePrice = v.PriceAt(t)
+		v.OnSale = v.EffectivePrice < v.Price
+	}
+
+	if !p.HasVariants() {
+		p.EffectivePrice = p.PriceAt(t)
+		p.OnSale = p.EffectivePrice < p.Price
+		return
+	}
+
+	p.EffectivePrice = p.Price
+	p.OnSale = false
+	first := true
+	for _, v := range p.Variants {
+		if !v.IsActive {
+			continue
+		}
+		if first || v.EffectivePrice < p.EffectivePrice {
+			p.EffectivePrice = v.EffectivePrice
+			first = false
+		}
+		if v.OnSale {
+			p.OnSale = true
+		}
+	}
 }
 
 // DTOs
 
+type ProductOptionRequest struct {
+	Name   string   `json:"name" validate:"required"`
+	Values []string `json:"values" validate:"required,min=1,dive,required"`
+}
+
+type ProductVariantRequest struct {
+	ID       *uuid.UUID        `json:"id"` // Presente al editar una variante existente
+	SKU      string            `json:"sku" validate:"required"`
+	Options  map[string]string `json:"options" validate:"required,min=1"`
+	Price    float64           `json:"price" validate:"required,gt=0"`
+	Weight   int               `json:"weight" validate:"required,gt=0"`
+	Stock    int               `json:"stock" validate:"gte=0"`
+	IsActive *bool             `json:"is_active"`
+
+	SalePrice    *float64   `json:"sale_price" validate:"omitempty,gt=0"`
+	SaleStartsAt *time.Time `json:"sale_starts_at"`
+	SaleEndsAt   *time.Time `json:"sale_ends_at"`
+}
+
 type CreateProductRequest struct {
-	Name        string   `json:"name" validate:"required,min=3"`
-	Description string   `json:"description" validate:"required"`
-	Price       float64  `json:"price" validate:"required,gt=0"`
-	Weight      int      `json:"weight" validate:"required,gt=0"` // en gramos
-	Stock       int      `json:"stock" validate:"required,gte=0"`
-	Category    string   `json:"category" validate:"required"`
-	Images      []string `json:"images" validate:"required,min=1"`
-	IsFeatured  bool     `json:"is_featured"`
+	Name             string                   `json:"name" validate:"required,min=3"`
+	Slug             string                   `json:"slug" validate:"omitempty"` // Se genera desde el nombre si viene vacío
+	SKU              string                   `json:"sku" validate:"omitempty,max=64"`
+	Description      string                   `json:"description" validate:"required"`
+	Price            float64                  `json:"price" validate:"required_without=Variants,omitempty,gt=0"`
+	Weight           int                      `json:"weight" validate:"required_without=Variants,omitempty,gt=0"` // en gramos
+	Stock            int                      `json:"stock" validate:"required_without_all=Variants BundleItems,omitempty,gte=0"`
+	ReorderThreshold int                      `json:"reorder_threshold" validate:"gte=0"`
+	CategoryID       uuid.UUID                `json:"category_id" validate:"required"`
+	Images           []string                 `json:"images" validate:"required,min=1"`
+	TastingNotes     []string                 `json:"tasting_notes" validate:"omitempty,dive,required"`
+	Coffee           *CoffeeAttributesRequest `json:"coffee"`
+	Options          []ProductOptionRequest   `json:"options" validate:"required_with=Variants,omitempty,dive"`
+	Variants         []ProductVariantRequest  `json:"variants" validate:"omitempty,dive"`
+	BundleItems      []BundleItemRequest      `json:"bundle_items" validate:"omitempty,dive"` // Componentes si el producto es un kit
+	PurchaseRules    *PurchaseRulesRequest    `json:"purchase_rules"`
+	MetaTitle        string                   `json:"meta_title" validate:"omitempty,max=70"`
+	MetaDescription  string                   `json:"meta_description" validate:"omitempty,max=160"`
+	IsFeatured       bool                     `json:"is_featured"`
+	SalePrice        *float64                 `json:"sale_price" validate:"omitempty,gt=0"`
+	SaleStartsAt     *time.Time               `json:"sale_starts_at"`
+	SaleEndsAt       *time.Time               `json:"sale_ends_at"`
 }
 
 type UpdateProductRequest struct {
-	Name        *string   `json:"name" validate:"omitempty,min=3"`
-	Description *string   `json:"description" validate:"omitempty"`
-	Price       *float64  `json:"price" validate:"omitempty,gt=0"`
-	Weight      *int      `json:"weight" validate:"omitempty,gt=0"`
-	Stock       *int      `json:"stock" validate:"omitempty,gte=0"`
-	Category    *string   `json:"category" validate:"omitempty"`
-	Images      []string  `json:"images" validate:"omitempty"`
-	IsActive    *bool     `json:"is_active" validate:"omitempty"`
-	IsFeatured  *bool     `json:"is_featured" validate:"omitempty"`
+	Name             *string                  `json:"name" validate:"omitempty,min=3"`
+	Slug             *string                  `json:"slug" validate:"omitempty"`
+	SKU              *string                  `json:"sku" validate:"omitempty,max=64"`
+	Description      *string                  `json:"description" validate:"omitempty"`
+	Price            *float64                 `json:"price" validate:"omitempty,gt=0"`
+	Weight           *int                     `json:"weight" validate:"omitempty,gt=0"`
+	Stock            *int                     `json:"stock" validate:"omitempty,gte=0"`
+	ReorderThreshold *int                     `json:"reorder_threshold" validate:"omitempty,gte=0"`
+	CategoryID       *uuid.UUID               `json:"category_id" validate:"omitempty"`
+	Images           []string                 `json:"images" validate:"omitempty"`
+	TastingNotes     []string                 `json:"tasting_notes" validate:"omitempty,dive,required"`
+	Coffee           *CoffeeAttributesRequest `json:"coffee"` // Reemplaza los atributos de café completos
+	RemoveCoffee     bool                     `json:"remove_coffee"`
+	Options          []ProductOptionRequest   `json:"options" validate:"omitempty,dive"`
+	Variants         []ProductVariantRequest  `json:"variants" validate:"omitempty,dive"`     // Reemplaza el set completo de variantes
+	BundleItems      []BundleItemRequest      `json:"bundle_items" validate:"omitempty,dive"` // Reemplaza los componentes del kit; [] deja de ser kit
+	PurchaseRules    *PurchaseRulesRequest    `json:"purchase_rules"`                         // Reemplaza las reglas de compra; todo en 0 las quita
+	MetaTitle        *string                  `json:"meta_title" validate:"omitempty,max=70"`
+	MetaDescription  *string                  `json:"meta_description" validate:"omitempty,max=160"`
+	IsActive         *bool                    `json:"is_active" validate:"omitempty"`
+	IsFeatured       *bool                    `json:"is_featured" validate:"omitempty"`
+	SalePrice        *float64                 `json:"sale_price" validate:"omitempty,gt=0"`
+	SaleStartsAt     *time.Time               `json:"sale_starts_at"`
+	SaleEndsAt       *time.Time               `json:"sale_ends_at"`
+	RemoveSale       bool                     `json:"remove_sale"` // Quita la oferta del producto
+}
+
+// Ordenamientos soportados por el catálogo
+const (
+	ProductSortNewest      = "newest"
+	ProductSortPriceAsc    = "price_asc"
+	ProductSortPriceDesc   = "price_desc"
+	ProductSortBestSelling = "best_selling"
+	ProductSortRating      = "rating"
+)
+
+// ProductFilter agrupa los filtros, el orden y la paginación del catálogo
+type ProductFilter struct {
+	Category    string             // ID o slug de categoría (incluye subcategorías)
+	CategoryIDs map[uuid.UUID]bool // Resuelto por el servicio a partir de Category
+	MinPrice    *float64
+	MaxPrice    *float64
+	InStock     bool
+	Featured    *bool
+	MinRating   float64
+	Origin      string // Región de origen (coincidencia parcial, sin tildes)
+	Process     CoffeeProcess
+	RoastLevel  RoastLevel
+	Variety     string     // Variedad (sin tildes ni mayúsculas)
+	BrewMethod  BrewMethod // Método de preparación recomendado
+	MinAltitude int        // msnm; compara contra la altitud máxima del café
+	Sort        string
+	Page        int
+	PageSize    int
+}
+
+type CategoryFacet struct {
+	CategoryID *uuid.UUID `json:"category_id"`
+	Category   string     `json:"category"`
+	Count      int        `json:"count"`
+}
+
+// PriceBucketFacet es un rango de precio [Min, Max). Max nil = sin tope.
+type PriceBucketFacet struct {
+	Label string   `json:"label"`
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max"`
+	Count int      `json:"count"`
 }
 
-type UpdateStockRequest struct {
-	Quantity int `json:"quantity" validate:"required"`
+// ProductFacets son los conteos para armar la barra de filtros.
+// Cada faceta se calcula con todos los filtros activos excepto el suyo.
+type ProductFacets struct {
+	Categories   []CategoryFacet    `json:"categories"`
+	PriceBuckets []PriceBucketFacet `json:"price_buckets"`
+	Origins      []ValueFacet       `json:"origins"`
+	Processes    []ValueFacet       `json:"processes"`
+	RoastLevels  []ValueFacet       `json:"roast_levels"`
 }
 
 type PaginatedProductsResponse struct {
-	Products    []*Product `json:"products"`
-	Total       int        `json:"total"`
-	Page        int        `json:"page"`
-	PageSize    int        `json:"page_size"`
-	TotalPages  int        `json:"total_pages"`
-	HasNext     bool       `json:"has_next"`
-	HasPrevious bool       `json:"has_previous"`
+	Products    []*Product     `json:"products"`
+	Total       int            `json:"total"`
+	Page        int            `json:"page"`
+	PageSize    int            `json:"page_size"`
+	TotalPages  int            `json:"total_pages"`
+	HasNext     bool           `json:"has_next"`
+	HasPrevious bool           `json:"has_previous"`
+	Facets      *ProductFacets `json:"facets,omitempty"`
 }
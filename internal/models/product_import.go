@@ -0,0 +1,30 @@
+package models
+
+import "github.com/google/uuid"
+
+const (
+	ProductImportCreate = "create"
+	ProductImportUpdate = "update"
+)
+
+// ProductImportRow es el resultado de validar (y aplicar) una fila del CSV
+type ProductImportRow struct {
+	Row       int        `json:"row"`    // Número de línea en el archivo (la cabecera es la 1)
+	Action    string     `json:"action"` // create | update; vacío si la fila tiene errores
+	ProductID *uuid.UUID `json:"product_id,omitempty"`
+	SKU       string     `json:"sku,omitempty"`
+	Name      string     `json:"name,omitempty"`
+	Errors    []string   `json:"errors,omitempty"`
+	Warnings  []string   `json:"warnings,omitempty"`
+}
+
+// ProductImportReport resume la importación. Si alguna fila tiene errores no se aplica nada.
+type ProductImportReport struct {
+	DryRun    bool               `json:"dry_run"`
+	Applied   bool               `json:"applied"`
+	TotalRows int                `json:"total_rows"`
+	Created   int                `json:"created"`
+	Updated   int                `json:"updated"`
+	Failed    int                `json:"failed"`
+	Rows      []ProductImportRow `json:"rows"`
+}
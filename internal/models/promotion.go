@@ -0,0 +1,108 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PromotionType string
+
+const (
+	PromotionBuyXGetY    PromotionType = "BUY_X_GET_Y"  // Buy BuyQuantity, get GetQuantity more with GetPercent off (100 = free)
+	PromotionTieredSpend PromotionType = "TIERED_SPEND" // Percent off the eligible subtotal, by the highest tier reached
+	PromotionBundlePrice PromotionType = "BUNDLE_PRICE" // Every BundleQuantity eligible units cost BundlePrice
+	PromotionHappyHour   PromotionType = "HAPPY_HOUR"   // Percent off the eligible lines on some weekdays and hours
+)
+
+// Promotion is a discount applied automatically to every cart and order, without a code.
+// Promotions are evaluated by priority (highest first). A non-stackable promotion is only
+// applied alone, and only promotions that combine with codes apply when a code is used.
+type Promotion struct {
+	ID                uuid.UUID     `json:"id" firestore:"id"`
+	Name              string        `json:"name" firestore:"name"` // Shown to customers on the cart and quote
+	Description       string        `json:"description" firestore:"description"`
+	Type              PromotionType `json:"type" firestore:"type"`
+	Priority          int           `json:"priority" firestore:"priority"`
+	Stackable         bool          `json:"stackable" firestore:"stackable"`
+	CombinesWithCodes bool          `json:"combines_with_codes" firestore:"combines_with_codes"`
+	ProductIDs        []uuid.UUID   `json:"product_ids" firestore:"product_ids"`   // Scope; empty (with no categories) = every product
+	CategoryIDs       []uuid.UUID   `json:"category_ids" firestore:"category_ids"` // Subcategories included
+
+	// BUY_X_GET_Y: the cheapest eligible units get the discount
+	BuyQuantity int     `json:"buy_quantity" firestore:"buy_quantity"`
+	GetQuantity int     `json:"get_quantity" firestore:"get_quantity"`
+	GetPercent  float64 `json:"get_percent" firestore:"get_percent"`
+
+	// TIERED_SPEND
+	Tiers []PromotionTier `json:"tiers" firestore:"tiers"`
+
+	// BUNDLE_PRICE: bundles are made with the most expensive eligible units
+	BundleQuantity int     `json:"bundle_quantity" firestore:"bundle_quantity"`
+	BundlePrice    float64 `json:"bundle_price" firestore:"bundle_price"`
+
+	// HAPPY_HOUR: store local time (STORE_TIMEZONE), from StartHour until EndHour
+	Percent   float64 `json:"percent" firestore:"percent"`
+	Weekdays  []int   `json:"weekdays" firestore:"weekdays"` // 0 = Sunday ... 6 = Saturday; empty = every day
+	StartHour int     `json:"start_hour" firestore:"start_hour"`
+	EndHour   int     `json:"end_hour" firestore:"end_hour"`
+
+	StartDate time.Time `json:"start_date" firestore:"start_date"`
+	EndDate   time.Time `json:"end_date" firestore:"end_date"`
+	IsActive  bool      `json:"is_active" firestore:"is_active"`
+	CreatedAt time.Time `json:"created_at" firestore:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" firestore:"updated_at"`
+}
+
+type PromotionTier struct {
+	MinSubtotal float64 `json:"min_subtotal" firestore:"min_subtotal"`
+	Percent     float64 `json:"percent" firestore:"percent"`
+}
+
+// AppliedPromotion is a promotion applied to a cart, quote or order
+type AppliedPromotion struct {
+	PromotionID uuid.UUID     `json:"promotion_id" firestore:"promotion_id"`
+	Name        string        `json:"name" firestore:"name"`
+	Type        PromotionType `json:"type" firestore:"type"`
+	Amount      float64       `json:"amount" firestore:"amount"`
+}
+
+// PromotionLine is a priced cart or order line promotions are evaluated on
+type PromotionLine struct {
+	ProductID  uuid.UUID
+	CategoryID *uuid.UUID
+	UnitPrice  float64
+	Quantity   int
+}
+
+// DTOs
+
+// PromotionRequest creates a promotion; on update it replaces the whole promotion
+type PromotionRequest struct {
+	Name              string          `json:"name" validate:"required,min=3"`
+	Description       string          `json:"description"`
+	Type              PromotionType   `json:"type" validate:"required,oneof=BUY_X_GET_Y TIERED_SPEND BUNDLE_PRICE HAPPY_HOUR"`
+	Priority          int             `json:"priority"`
+	Stackable         bool            `json:"stackable"`
+	CombinesWithCodes bool            `json:"combines_with_codes"`
+	ProductIDs        []uuid.UUID     `json:"product_ids"`
+	CategoryIDs       []uuid.UUID     `json:"category_ids"`
+	BuyQuantity       int             `json:"buy_quantity" validate:"gte=0"`
+	GetQuantity       int             `json:"get_quantity" validate:"gte=0"`
+	GetPercent        float64         `json:"get_percent" validate:"gte=0,lte=100"`
+	Tiers             []PromotionTier `json:"tiers" validate:"omitempty,dive"`
+	BundleQuantity    int             `json:"bundle_quantity" validate:"gte=0"`
+	BundlePrice       float64         `json:"bundle_price" validate:"gte=0"`
+	Percent           float64         `json:"percent" validate:"gte=0,lte=100"`
+	Weekdays          []int           `json:"weekdays" validate:"omitempty,dive,gte=0,lte=6"`
+	StartHour         int             `json:"start_hour" validate:"gte=0,lte=23"`
+	EndHour           int             `json:"end_hour" validate:"gte=0,lte=24"`
+	StartDate         time.Time       `json:"start_date" validate:"required"`
+	EndDate           time.Time       `json:"end_date" validate:"required"`
+	IsActive          bool            `json:"is_active"`
+}
+
+type PromotionListResponse struct {
+	Promotions []Promotion `json:"promotions"`
+	Total      int         `json:"total"`
+}
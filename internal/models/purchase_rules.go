@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PurchaseRules limita cuánto se puede comprar de un producto (lotes limitados,
+// venta mayorista). Un valor 0 desactiva la regla. Las cantidades cuentan todas
+// las variantes del producto juntas.
+type PurchaseRules struct {
+	MinQuantity        int `json:"min_quantity" firestore:"min_quantity"`
+	QuantityStep       int `json:"quantity_step" firestore:"quantity_step"` // La cantidad debe ser múltiplo de este valor (p. ej. 6)
+	MaxPerOrder        int `json:"max_per_order" firestore:"max_per_order"`
+	MaxPerCustomer     int `json:"max_per_customer" firestore:"max_per_customer"`
+	CustomerWindowDays int `json:"customer_window_days" firestore:"customer_window_days"` // Ventana de MaxPerCustomer; 0 = sin vencimiento
+}
+
+// HasCustomerLimit indica si hay que consultar las compras anteriores del cliente
+func (r *PurchaseRules) HasCustomerLimit() bool {
+	return r != nil && r.MaxPerCustomer > 0
+}
+
+// CustomerPurchases registra lo que un cliente (por usuario o por email) compró de los
+// productos con límite por cliente. Se lee y actualiza en la misma transacción que
+// descuenta el stock, así dos órdenes simultáneas no pueden superar el límite.
+type CustomerPurchases struct {
+	Key       string                  `json:"key" firestore:"key"`
+	Entries   []CustomerPurchaseEntry `json:"entries" firestore:"entries"`
+	UpdatedAt time.Time               `json:"updated_at" firestore:"updated_at"`
+}
+
+// CustomerPurchaseEntry son las unidades de un producto compradas en una orden
+type CustomerPurchaseEntry struct {
+	OrderID   uuid.UUID `json:"order_id" firestore:"order_id"`
+	ProductID uuid.UUID `json:"product_id" firestore:"product_id"`
+	Quantity  int       `json:"quantity" firestore:"quantity"`
+	At        time.Time `json:"at" firestore:"at"`
+}
+
+// CustomerLimit es la compra de un producto con límite por cliente que se verifica
+// y registra junto con el descuento de stock de la orden
+type CustomerLimit struct {
+	CustomerKeys   []string // Ver repository.CustomerPurchaseKeys
+	OrderID        uuid.UUID
+	ProductID      uuid.UUID
+	ProductName    string
+	Quantity       int // Todas las variantes del producto en la orden
+	MaxPerCustomer int
+	WindowDays     int
+}
+
+// DTOs
+
+type PurchaseRulesRequest struct {
+	MinQuantity        int `json:"min_quantity" validate:"gte=0"`
+	QuantityStep       int `json:"quantity_step" validate:"gte=0"`
+	MaxPerOrder        int `json:"max_per_order" validate:"gte=0"`
+	MaxPerCustomer     int `json:"max_per_customer" validate:"gte=0"`
+	CustomerWindowDays int `json:"customer_window_days" validate:"gte=0,lte=365"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// Origen de una recomendación
+const (
+	RecommendationBoughtTogether = "BOUGHT_TOGETHER" // Aparece en los mismos pedidos
+	RecommendationSameCategory   = "SAME_CATEGORY"   // Relleno cuando no hay suficientes datos de compra
+)
+
+// ProductRecommendation es un producto sugerido junto con el motivo
+type ProductRecommendation struct {
+	Product     *Product `json:"product"`
+	Source      string   `json:"source"`
+	CoPurchases int      `json:"co_purchases"` // Pedidos en que se compró junto a los productos de referencia
+}
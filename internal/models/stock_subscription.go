@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type StockSubscriptionStatus string
+
+const (
+	StockSubscriptionPending StockSubscriptionStatus = "PENDING"
+	StockSubscriptionSent    StockSubscriptionStatus = "SENT" // Ya avisada: la suscripción expira
+)
+
+// StockSubscription es la solicitud de un cliente (registrado o solo con email)
+// para que le avisen cuando un producto agotado vuelva a tener stock.
+type StockSubscription struct {
+	ID          uuid.UUID               `json:"id" firestore:"id"`
+	ProductID   uuid.UUID               `json:"product_id" firestore:"product_id"`
+	VariantID   *uuid.UUID              `json:"variant_id,omitempty" firestore:"variant_id,omitempty"`
+	ProductName string                  `json:"product_name" firestore:"product_name"`
+	VariantName string                  `json:"variant_name,omitempty" firestore:"variant_name,omitempty"`
+	UserID      *uuid.UUID              `json:"user_id,omitempty" firestore:"user_id,omitempty"` // nil = suscripción solo con email
+	Email       string                  `json:"email" firestore:"email"`
+	Name        string                  `json:"name,omitempty" firestore:"name,omitempty"`
+	Status      StockSubscriptionStatus `json:"status" firestore:"status"`
+	CreatedAt   time.Time               `json:"created_at" firestore:"created_at"`
+	NotifiedAt  *time.Time              `json:"notified_at,omitempty" firestore:"notified_at,omitempty"`
+	CancelToken string                  `json:"cancel_token,omitempty" firestore:"-"` // Enlace firmado para cancelar sin sesión
+}
+
+// DTOs
+
+// CreateStockSubscriptionRequest — los usuarios autenticados no necesitan enviar email
+type CreateStockSubscriptionRequest struct {
+	VariantID *uuid.UUID `json:"variant_id"`
+	Email     string     `json:"email" validate:"omitempty,email"`
+	Name      string     `json:"name" validate:"omitempty,max=100"`
+}
+
+// CancelStockSubscriptionRequest cancela un aviso con el token del enlace (sin sesión)
+type CancelStockSubscriptionRequest struct {
+	Token string `json:"token" validate:"required"`
+}
@@ -22,20 +22,21 @@ const (
 )
 
 type User struct {
-	ID           uuid.UUID  `json:"id" firestore:"id"`
-	Email        string     `json:"email" firestore:"email"`
-	Password     string     `json:"-" firestore:"password"`
-	Name         string     `json:"name" firestore:"name"`
-	Phone        string     `json:"phone" firestore:"phone"`
-	City         *string    `json:"city" firestore:"city"`
-	Municipality *string    `json:"municipality" firestore:"municipality"`
-	Neighborhood *string    `json:"neighborhood" firestore:"neighborhood"`
-	Gender       *Gender    `json:"gender" firestore:"gender"`
-	BirthDate    *string    `json:"birth_date" firestore:"birth_date"`
-	Role         UserRole   `json:"role" firestore:"role"`
-	IsActive     bool       `json:"is_active" firestore:"is_active"`
-	CreatedAt    time.Time  `json:"created_at" firestore:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at" firestore:"updated_at"`
+	ID                   uuid.UUID `json:"id" firestore:"id"`
+	Email                string    `json:"email" firestore:"email"`
+	Password             string    `json:"-" firestore:"password"`
+	Name                 string    `json:"name" firestore:"name"`
+	Phone                string    `json:"phone" firestore:"phone"`
+	City                 *string   `json:"city" firestore:"city"`
+	Municipality         *string   `json:"municipality" firestore:"municipality"`
+	Neighborhood         *string   `json:"neighborhood" firestore:"neighborhood"`
+	Gender               *Gender   `json:"gender" firestore:"gender"`
+	BirthDate            *string   `json:"birth_date" firestore:"birth_date"`
+	Role                 UserRole  `json:"role" firestore:"role"`
+	IsActive             bool      `json:"is_active" firestore:"is_active"`
+	EmailMarketingOptOut bool      `json:"email_marketing_opt_out" firestore:"email_marketing_opt_out"` // No recibir correos comerciales (p. ej. carrito abandonado)
+	CreatedAt            time.Time `json:"created_at" firestore:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at" firestore:"updated_at"`
 }
 
 // DTOs (Data Transfer Objects)
@@ -65,13 +66,19 @@ type LoginResponse struct {
 }
 
 type UpdateProfileRequest struct {
-	Name         string  `json:"name" validate:"omitempty,min=2"`
-	Phone        string  `json:"phone" validate:"omitempty"`
-	City         *string `json:"city" validate:"omitempty"`
-	Municipality *string `json:"municipality" validate:"omitempty"`
-	Neighborhood *string `json:"neighborhood" validate:"omitempty"`
-	Gender       *Gender `json:"gender" validate:"omitempty,oneof=MALE FEMALE OTHER"`
-	BirthDate    *string `json:"birth_date" validate:"omitempty"`
+	Name                 string  `json:"name" validate:"omitempty,min=2"`
+	Phone                string  `json:"phone" validate:"omitempty"`
+	City                 *string `json:"city" validate:"omitempty"`
+	Municipality         *string `json:"municipality" validate:"omitempty"`
+	Neighborhood         *string `json:"neighborhood" validate:"omitempty"`
+	Gender               *Gender `json:"gender" validate:"omitempty,oneof=MALE FEMALE OTHER"`
+	BirthDate            *string `json:"birth_date" validate:"omitempty"`
+	EmailMarketingOptOut *bool   `json:"email_marketing_opt_out"`
+}
+
+// UnsubscribeRequest da de baja de los correos comerciales con el token del enlace del correo
+type UnsubscribeRequest struct {
+	Token string `json:"token" validate:"required"`
 }
 
 type UpdateUserByIDRequest struct {
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Wishlist es la lista de deseos de un usuario (un documento por usuario, como el carrito).
+// ShareToken vacío = la lista es privada.
+type Wishlist struct {
+	UserID     uuid.UUID      `json:"user_id" firestore:"user_id"`
+	Items      []WishlistItem `json:"items" firestore:"items"`
+	ProductIDs []string       `json:"-" firestore:"product_ids"` // Índice para buscar listas por producto (array-contains)
+	ShareToken string         `json:"share_token,omitempty" firestore:"share_token"`
+	UpdatedAt  time.Time      `json:"updated_at" firestore:"updated_at"`
+}
+
+// WishlistItem es un producto guardado. Sin VariantID aplica al producto completo.
+// Los campos de aviso evitan notificar dos veces la misma oferta.
+type WishlistItem struct {
+	ProductID       uuid.UUID  `json:"product_id" firestore:"product_id"`
+	VariantID       *uuid.UUID `json:"variant_id" firestore:"variant_id"`
+	AddedAt         time.Time  `json:"added_at" firestore:"added_at"`
+	SaleNotifiedFor *float64   `json:"-" firestore:"sale_notified_for"` // Precio de oferta ya avisado
+}
+
+// Matches indica si el item corresponde al producto/variante dados
+func (i *WishlistItem) Matches(productID uuid.UUID, variantID *uuid.UUID) bool {
+	return i.ProductID == productID && SameVariant(i.VariantID, variantID)
+}
+
+// DTOs
+
+type AddWishlistItemRequest struct {
+	ProductID uuid.UUID  `json:"product_id" validate:"required"`
+	VariantID *uuid.UUID `json:"variant_id"`
+}
+
+// WishlistItemView es un item enriquecido con el precio y el stock actuales del producto
+type WishlistItemView struct {
+	ProductID      uuid.UUID  `json:"product_id"`
+	VariantID      *uuid.UUID `json:"variant_id"`
+	ProductName    string     `json:"product_name"`
+	VariantName    string     `json:"variant_name"`
+	Slug           string     `json:"slug"`
+	Image          string     `json:"image"`
+	Price          float64    `json:"price"`
+	EffectivePrice float64    `json:"effective_price"`
+	OnSale         bool       `json:"on_sale"`
+	Stock          int        `json:"stock"`
+	Available      bool       `json:"available"` // Activo y con stock
+	AddedAt        time.Time  `json:"added_at"`
+}
+
+type WishlistResponse struct {
+	Items     []WishlistItemView `json:"items"`
+	ShareURL  string             `json:"share_url,omitempty"`
+	OwnerName string             `json:"owner_name,omitempty"` // Solo en listas compartidas
+	UpdatedAt time.Time          `json:"updated_at"`
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/cheoscafe/backend/internal/database"
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/google/uuid"
+	"google.golang.org/api/iterator"
+)
+
+const cartRemindersCollection = "cart_reminders"
+
+type CartReminderRepository struct {
+	firebase *database.FirebaseClient
+}
+
+func NewCartReminderRepository(firebase *database.FirebaseClient) *CartReminderRepository {
+	return &CartReminderRepository{firebase: firebase}
+}
+
+// Create registra un recordatorio enviado
+func (r *CartReminderRepository) Create(ctx context.Context, reminder *models.CartReminder) error {
+	if reminder.ID == uuid.Nil {
+		reminder.ID = uuid.New()
+	}
+	if reminder.SentAt.IsZero() {
+		reminder.SentAt = time.Now()
+	}
+
+	_, err := r.firebase.Collection(cartRemindersCollection).Doc(reminder.ID.String()).Set(ctx, reminder)
+	return err
+}
+
+// GetLatestPending obtiene el recordatorio más reciente del usuario enviado desde
+// since que todavía no tiene orden recuperada. Retorna nil si no hay.
+// Filtramos y ordenamos en Go para evitar índices compuestos
+func (r *CartReminderRepository) GetLatestPending(ctx context.Context, userID uuid.UUID, since time.Time) (*models.CartReminder, error) {
+	iter := r.firebase.Collection(cartRemindersCollection).Where("user_id", "==", userID).Documents(ctx)
+	defer iter.Stop()
+
+	var reminders []*models.CartReminder
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var reminder models.CartReminder
+		if err := doc.DataTo(&reminder); err != nil {
+			continue
+		}
+		if reminder.OrderID != nil || reminder.SentAt.Before(since) {
+			continue
+		}
+		reminders = append(reminders, &reminder)
+	}
+
+	if len(reminders) == 0 {
+		return nil, nil
+	}
+	sort.Slice(reminders, func(i, j int) bool {
+		return reminders[i].SentAt.After(reminders[j].SentAt)
+	})
+	return reminders[0], nil
+}
+
+// MarkRecovered enlaza la orden con la que se recuperó el carrito
+func (r *CartReminderRepository) MarkRecovered(ctx context.Context, id uuid.UUID, orderID uuid.UUID) error {
+	_, err := r.firebase.Collection(cartRemindersCollection).Doc(id.String()).Update(ctx, []firestore.Update{
+		{Path: "order_id", Value: orderID},
+		{Path: "recovered_at", Value: time.Now()},
+	})
+	return err
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"time"
 
+	"cloud.google.com/go/firestore"
 	"github.com/cheoscafe/backend/internal/database"
 	"github.com/cheoscafe/backend/internal/models"
 	"github.com/google/uuid"
+	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -46,9 +48,55 @@ func (r *CartRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*mo
 	return &cart, nil
 }
 
-// Save guarda/sobreescribe el carrito completo
+// GetByOwner obtiene el carrito de un usuario o de un invitado
+func (r *CartRepository) GetByOwner(ctx context.Context, owner models.CartOwner) (*models.Cart, error) {
+	if owner.IsGuest() {
+		return r.GetGuest(ctx, owner.GuestID)
+	}
+	return r.GetByUserID(ctx, owner.UserID)
+}
+
+// GetGuest obtiene el carrito de un invitado. Si no existe o ya venció, retorna carrito vacío.
+func (r *CartRepository) GetGuest(ctx context.Context, guestID uuid.UUID) (*models.Cart, error) {
+	empty := &models.Cart{
+		GuestID:   &guestID,
+		Items:     []models.CartItem{},
+		UpdatedAt: time.Now(),
+	}
+
+	doc, err := r.firebase.Collection("guest_carts").Doc(guestID.String()).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return empty, nil
+		}
+		return nil, err
+	}
+
+	var cart models.Cart
+	if err := doc.DataTo(&cart); err != nil {
+		return nil, err
+	}
+
+	// La limpieza periódica puede no haber pasado todavía
+	if cart.ExpiresAt != nil && cart.ExpiresAt.Before(time.Now()) {
+		return empty, nil
+	}
+
+	cart.GuestID = &guestID
+	if cart.Items == nil {
+		cart.Items = []models.CartItem{}
+	}
+
+	return &cart, nil
+}
+
+// Save guarda/sobreescribe el carrito completo (de usuario o de invitado según GuestID)
 func (r *CartRepository) Save(ctx context.Context, cart *models.Cart) error {
 	cart.UpdatedAt = time.Now()
+	if cart.GuestID != nil {
+		_, err := r.firebase.Collection("guest_carts").Doc(cart.GuestID.String()).Set(ctx, cart)
+		return err
+	}
 	_, err := r.firebase.Collection("carts").Doc(cart.UserID.String()).Set(ctx, cart)
 	return err
 }
@@ -58,3 +106,89 @@ func (r *CartRepository) Delete(ctx context.Context, userID uuid.UUID) error {
 	_, err := r.firebase.Collection("carts").Doc(userID.String()).Delete(ctx)
 	return err
 }
+
+// ListStale obtiene los carritos de usuario sin cambios desde before
+func (r *CartRepository) ListStale(ctx context.Context, before time.Time) ([]*models.Cart, error) {
+	iter := r.firebase.Collection("carts").Where("updated_at", "<", before).Documents(ctx)
+	defer iter.Stop()
+
+	var carts []*models.Cart
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		userID, err := uuid.Parse(doc.Ref.ID)
+		if err != nil {
+			continue
+		}
+		var cart models.Cart
+		if err := doc.DataTo(&cart); err != nil {
+			continue
+		}
+		cart.UserID = userID
+		carts = append(carts, &cart)
+	}
+
+	return carts, nil
+}
+
+// MarkReminderSent registra el envío del recordatorio sin tocar updated_at,
+// para no "revivir" el carrito ni pisar cambios hechos en paralelo
+func (r *CartRepository) MarkReminderSent(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	_, err := r.firebase.Collection("carts").Doc(userID.String()).Update(ctx, []firestore.Update{
+		{Path: "reminder_sent_at", Value: at},
+	})
+	return err
+}
+
+// DeleteByOwner elimina el carrito de un usuario o de un invitado
+func (r *CartRepository) DeleteByOwner(ctx context.Context, owner models.CartOwner) error {
+	if owner.IsGuest() {
+		return r.DeleteGuest(ctx, owner.GuestID)
+	}
+	return r.Delete(ctx, owner.UserID)
+}
+
+// DeleteGuest elimina el carrito de un invitado
+func (r *CartRepository) DeleteGuest(ctx context.Context, guestID uuid.UUID) error {
+	_, err := r.firebase.Collection("guest_carts").Doc(guestID.String()).Delete(ctx)
+	return err
+}
+
+// DeleteExpiredGuests elimina los carritos de invitado vencidos antes de before.
+// Retorna cuántos se eliminaron.
+func (r *CartRepository) DeleteExpiredGuests(ctx context.Context, before time.Time) (int, error) {
+	iter := r.firebase.Collection("guest_carts").Where("expires_at", "<", before).Documents(ctx)
+	defer iter.Stop()
+
+	var refs []*firestore.DocumentRef
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		refs = append(refs, doc.Ref)
+	}
+
+	// Los batch de Firestore admiten hasta 500 operaciones
+	for start := 0; start < len(refs); start += 500 {
+		end := min(start+500, len(refs))
+		batch := r.firebase.Batch()
+		for _, ref := range refs[start:end] {
+			batch.Delete(ref)
+		}
+		if _, err := batch.Commit(ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(refs), nil
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/cheoscafe/backend/internal/database"
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/google/uuid"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type CategoryRepository struct {
+	firebase *database.FirebaseClient
+}
+
+func NewCategoryRepository(firebase *database.FirebaseClient) *CategoryRepository {
+	return &CategoryRepository{
+		firebase: firebase,
+	}
+}
+
+// Create crea una nueva categoría
+func (r *CategoryRepository) Create(ctx context.Context, category *models.Category) error {
+	if category.ID == uuid.Nil {
+		category.ID = uuid.New()
+	}
+
+	now := time.Now()
+	category.CreatedAt = now
+	category.UpdatedAt = now
+
+	_, err := r.firebase.Collection("categories").Doc(category.ID.String()).Set(ctx, category)
+	return err
+}
+
+// GetByID obtiene una categoría por ID
+func (r *CategoryRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Category, error) {
+	doc, err := r.firebase.Collection("categories").Doc(id.String()).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, errors.New("categoría no encontrada")
+		}
+		return nil, err
+	}
+
+	var category models.Category
+	if err := doc.DataTo(&category); err != nil {
+		return nil, err
+	}
+
+	category.ID = id
+	return &category, nil
+}
+
+// GetAll obtiene todas las categorías ordenadas por sort_order y nombre.
+// Se ordena en memoria: la colección es pequeña y así no se requiere índice.
+func (r *CategoryRepository) GetAll(ctx context.Context) ([]*models.Category, error) {
+	iter := r.firebase.Collection("categories").Documents(ctx)
+	defer iter.Stop()
+
+	var categories []*models.Category
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var category models.Category
+		if err := doc.DataTo(&category); err != nil {
+			continue
+		}
+
+		categoryID, err := uuid.Parse(doc.Ref.ID)
+		if err != nil {
+			continue
+		}
+		category.ID = categoryID
+
+		categories = append(categories, &category)
+	}
+
+	sort.SliceStable(categories, func(i, j int) bool {
+		if categories[i].SortOrder != categories[j].SortOrder {
+			return categories[i].SortOrder < categories[j].SortOrder
+		}
+		return categories[i].Name < categories[j].Name
+	})
+
+	return categories, nil
+}
+
+// Update actualiza una categoría
+func (r *CategoryRepository) Update(ctx context.Context, category *models.Category) error {
+	category.UpdatedAt = time.Now()
+
+	_, err := r.firebase.Collection("categories").Doc(category.ID.String()).Set(ctx, category)
+	return err
+}
+
+// Delete elimina una categoría físicamente (hard delete)
+func (r *CategoryRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.firebase.Collection("categories").Doc(id.String()).Delete(ctx)
+	return err
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/cheoscafe/backend/internal/database"
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const customerPurchasesCollection = "customer_purchases"
+
+// ErrCustomerLimitExceeded indica que la orden supera el máximo por cliente de un producto
+var ErrCustomerLimitExceeded = errors.New("se superó el máximo de compra por cliente")
+
+type CustomerPurchaseRepository struct {
+	firebase *database.FirebaseClient
+}
+
+func NewCustomerPurchaseRepository(firebase *database.FirebaseClient) *CustomerPurchaseRepository {
+	return &CustomerPurchaseRepository{
+		firebase: firebase,
+	}
+}
+
+// CustomerPurchaseKeys retorna los documentos de compras de un cliente: uno por usuario
+// y otro por email, así un invitado no evita el límite comprando sin iniciar sesión
+func CustomerPurchaseKeys(userID *uuid.UUID, email string) []string {
+	var keys []string
+	if userID != nil {
+		keys = append(keys, "user:"+userID.String())
+	}
+	if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+		keys = append(keys, "email:"+email)
+	}
+	return keys
+}
+
+// ReleaseOrder quita las compras de una orden (p. ej. cancelada) de los registros del cliente
+func (r *CustomerPurchaseRepository) ReleaseOrder(ctx context.Context, keys []string, orderID uuid.UUID) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return r.firebase.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		records, err := getCustomerPurchases(tx, r.firebase, keys)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, key := range keys {
+			record := records[key]
+			entries := record.Entries[:0]
+			for _, entry := range record.Entries {
+				if entry.OrderID != orderID {
+					entries = append(entries, entry)
+				}
+			}
+			if len(entries) == len(record.Entries) {
+				continue
+			}
+			record.Entries = entries
+			record.UpdatedAt = now
+			if err := tx.Set(r.firebase.Collection(customerPurchasesCollection).Doc(key), record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// getCustomerPurchases lee dentro de la transacción los registros de las claves dadas.
+// Un cliente sin compras anteriores recibe un registro vacío.
+func getCustomerPurchases(tx *firestore.Transaction, firebase *database.FirebaseClient, keys []string) (map[string]*models.CustomerPurchases, error) {
+	records := make(map[string]*models.CustomerPurchases, len(keys))
+	for _, key := range keys {
+		if _, ok := records[key]; ok {
+			continue
+		}
+		record := &models.CustomerPurchases{Key: key}
+		doc, err := tx.Get(firebase.Collection(customerPurchasesCollection).Doc(key))
+		if err != nil && status.Code(err) != codes.NotFound {
+			return nil, err
+		}
+		if err == nil {
+			if err := doc.DataTo(record); err != nil {
+				return nil, err
+			}
+		}
+		records[key] = record
+	}
+	return records, nil
+}
+
+// checkCustomerLimits verifica cada límite contra los registros leídos y agrega las
+// compras de la orden. Una orden registrada bajo las dos claves cuenta una sola vez.
+func checkCustomerLimits(records map[string]*models.CustomerPurchases, limits []*models.CustomerLimit, now time.Time) error {
+	for _, limit := range limits {
+		var start time.Time // Sin ventana: desde siempre
+		if limit.WindowDays > 0 {
+			start = now.AddDate(0, 0, -limit.WindowDays)
+		}
+
+		purchased := 0
+		counted := make(map[uuid.UUID]bool)
+		for _, key := range limit.CustomerKeys {
+			for _, entry := range records[key].Entries {
+				if entry.ProductID != limit.ProductID || entry.OrderID == limit.OrderID ||
+					entry.At.Before(start) || counted[entry.OrderID] {
+					continue
+				}
+				counted[entry.OrderID] = true
+				purchased += entry.Quantity
+			}
+		}
+		if purchased+limit.Quantity > limit.MaxPerCustomer {
+			return fmt.Errorf("%w: máximo %d unidad(es) de %s, ya compraste %d",
+				ErrCustomerLimitExceeded, limit.MaxPerCustomer, limit.ProductName, purchased)
+		}
+
+		for _, key := range limit.CustomerKeys {
+			record := records[key]
+			// Las compras fuera de la ventana de este producto ya no cuentan
+			entries := record.Entries[:0]
+			for _, entry := range record.Entries {
+				if entry.ProductID != limit.ProductID || !entry.At.Before(start) {
+					entries = append(entries, entry)
+				}
+			}
+			record.Entries = append(entries, models.CustomerPurchaseEntry{
+				OrderID:   limit.OrderID,
+				ProductID: limit.ProductID,
+				Quantity:  limit.Quantity,
+				At:        now,
+			})
+			record.UpdatedAt = now
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"sort"
 	"strings"
 	"time"
 
@@ -66,6 +67,39 @@ func (r *DiscountRepository) GetByID(ctx context.Context, id uuid.UUID) (*models
 	return &discount, nil
 }
 
+// GetByIDs gets several discount codes in a single read. Missing codes are skipped.
+func (r *DiscountRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.DiscountCode, error) {
+	if len(ids) == 0 {
+		return []*models.DiscountCode{}, nil
+	}
+
+	refs := make([]*firestore.DocumentRef, 0, len(ids))
+	for _, id := range ids {
+		refs = append(refs, r.firebase.Collection("discount_codes").Doc(id.String()))
+	}
+
+	docs, err := r.firebase.Firestore.GetAll(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	discounts := make([]*models.DiscountCode, 0, len(docs))
+	for i, doc := range docs {
+		if !doc.Exists() {
+			continue
+		}
+
+		var discount models.DiscountCode
+		if err := doc.DataTo(&discount); err != nil {
+			continue
+		}
+		discount.ID = ids[i]
+		discounts = append(discounts, &discount)
+	}
+
+	return discounts, nil
+}
+
 // GetByCode gets a discount code by its code string
 func (r *DiscountRepository) GetByCode(ctx context.Context, code string) (*models.DiscountCode, error) {
 	code = strings.ToUpper(code)
@@ -197,12 +231,24 @@ func (r *DiscountRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-// IncrementUsedCount increments the used count
-func (r *DiscountRepository) IncrementUsedCount(ctx context.Context, id uuid.UUID) error {
+// Redeem records a redemption and increments the code's used count in one transaction.
+// Max uses, the per-customer limit and first-order-only are re-checked inside the
+// transaction, so concurrent orders can't go over them.
+func (r *DiscountRepository) Redeem(ctx context.Context, redemption *models.DiscountRedemption) error {
+	if redemption.ID == uuid.Nil {
+		redemption.ID = uuid.New()
+	}
+	redemption.Email = strings.ToLower(strings.TrimSpace(redemption.Email))
+	redemption.RedeemedAt = time.Now()
+	redemption.ReleasedAt = nil
+
 	return r.firebase.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
-		docRef := r.firebase.Collection("discount_codes").Doc(id.String())
-		doc, err := tx.Get(docRef)
+		codeRef := r.firebase.Collection("discount_codes").Doc(redemption.DiscountCodeID.String())
+		doc, err := tx.Get(codeRef)
 		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return errors.New("discount code not found")
+			}
 			return err
 		}
 
@@ -211,13 +257,397 @@ func (r *DiscountRepository) IncrementUsedCount(ctx context.Context, id uuid.UUI
 			return err
 		}
 
-		newUsedCount := discount.UsedCount + 1
+		if discount.MaxUses != nil && discount.UsedCount >= *discount.MaxUses {
+			return errors.New("discount code usage limit reached")
+		}
+
+		var docs []*firestore.DocumentSnapshot
+		if discount.MaxUsesPerCustomer != nil || discount.FirstOrderOnly {
+			for _, query := range r.customerRedemptionQueries(redemption.UserID, redemption.Email) {
+				found, err := tx.Documents(query).GetAll()
+				if err != nil {
+					return err
+				}
+				docs = append(docs, found...)
+			}
+		}
+
+		if discount.MaxUsesPerCustomer != nil && countActiveRedemptions(docs, redemption.DiscountCodeID) >= *discount.MaxUsesPerCustomer {
+			return errors.New("discount code already used by this customer")
+		}
+
+		if discount.FirstOrderOnly {
+			if redemption.UserID == nil && redemption.Email == "" {
+				return errors.New("discount code is only valid on a first order")
+			}
+			placed, err := r.hasOtherOrder(tx, redemption, docs)
+			if err != nil {
+				return err
+			}
+			if placed {
+				return errors.New("discount code is only valid on a first order")
+			}
+		}
 
-		return tx.Update(docRef, []firestore.Update{
-			{Path: "used_count", Value: newUsedCount},
+		if err := tx.Update(codeRef, []firestore.Update{
+			{Path: "used_count", Value: discount.UsedCount + 1},
 			{Path: "updated_at", Value: time.Now()},
-		})
+		}); err != nil {
+			return err
+		}
+
+		redemption.BatchID = discount.BatchID
+
+		redemptionRef := r.firebase.Collection("discount_redemptions").Doc(redemption.ID.String())
+		return tx.Create(redemptionRef, redemption)
+	})
+}
+
+// hasOtherOrder reports whether the customer has an order other than the redemption's:
+// a non-cancelled order, or an active redemption of any code, which also catches an
+// order placed concurrently that hasn't been saved yet. redemptions are the customer's
+// redemptions, already read in the transaction.
+func (r *DiscountRepository) hasOtherOrder(tx *firestore.Transaction, redemption *models.DiscountRedemption, redemptions []*firestore.DocumentSnapshot) (bool, error) {
+	for _, doc := range redemptions {
+		var other models.DiscountRedemption
+		if err := doc.DataTo(&other); err != nil {
+			continue
+		}
+		if other.ReleasedAt == nil && other.OrderID != redemption.OrderID {
+			return true, nil
+		}
+	}
+
+	var queries []firestore.Query
+	if redemption.UserID != nil {
+		queries = append(queries, r.firebase.Collection("orders").Where("user_id", "==", *redemption.UserID))
+	}
+	if redemption.Email != "" {
+		queries = append(queries, r.firebase.Collection("orders").Where("customer_email", "==", redemption.Email))
+	}
+	for _, query := range queries {
+		docs, err := tx.Documents(query).GetAll()
+		if err != nil {
+			return false, err
+		}
+		for _, doc := range docs {
+			var order models.Order
+			if err := doc.DataTo(&order); err != nil {
+				continue
+			}
+			if order.Status != models.OrderCancelled && doc.Ref.ID != redemption.OrderID.String() {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// ReleaseByOrder releases the order's active redemptions and gives the uses back
+// to their codes. Returns how many were released.
+func (r *DiscountRepository) ReleaseByOrder(ctx context.Context, orderID uuid.UUID) (int, error) {
+	released := 0
+	err := r.firebase.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docs, err := tx.Documents(r.firebase.Collection("discount_redemptions").Where("order_id", "==", orderID)).GetAll()
+		if err != nil {
+			return err
+		}
+
+		var active []*firestore.DocumentRef
+		perCode := make(map[uuid.UUID]int)
+		for _, doc := range docs {
+			var redemption models.DiscountRedemption
+			if err := doc.DataTo(&redemption); err != nil || redemption.ReleasedAt != nil {
+				continue
+			}
+			active = append(active, doc.Ref)
+			perCode[redemption.DiscountCodeID]++
+		}
+
+		// Firestore transactions need every read before the first write
+		codeDocs := make(map[uuid.UUID]*firestore.DocumentSnapshot, len(perCode))
+		for codeID := range perCode {
+			doc, err := tx.Get(r.firebase.Collection("discount_codes").Doc(codeID.String()))
+			if err != nil {
+				if status.Code(err) == codes.NotFound {
+					continue // Deleted code: only the redemption is released
+				}
+				return err
+			}
+			codeDocs[codeID] = doc
+		}
+
+		now := time.Now()
+		for _, ref := range active {
+			if err := tx.Update(ref, []firestore.Update{{Path: "released_at", Value: now}}); err != nil {
+				return err
+			}
+		}
+		for codeID, doc := range codeDocs {
+			var discount models.DiscountCode
+			if err := doc.DataTo(&discount); err != nil {
+				return err
+			}
+			if err := tx.Update(doc.Ref, []firestore.Update{
+				{Path: "used_count", Value: max(discount.UsedCount-perCode[codeID], 0)},
+				{Path: "updated_at", Value: now},
+			}); err != nil {
+				return err
+			}
+		}
+
+		released = len(active)
+		return nil
+	})
+
+	return released, err
+}
+
+// CountCustomerRedemptions counts the active redemptions of a code by a user or email
+func (r *DiscountRepository) CountCustomerRedemptions(ctx context.Context, codeID uuid.UUID, userID *uuid.UUID, email string) (int, error) {
+	var docs []*firestore.DocumentSnapshot
+	for _, query := range r.customerRedemptionQueries(userID, strings.ToLower(strings.TrimSpace(email))) {
+		found, err := query.Documents(ctx).GetAll()
+		if err != nil {
+			return 0, err
+		}
+		docs = append(docs, found...)
+	}
+	return countActiveRedemptions(docs, codeID), nil
+}
+
+// customerRedemptionQueries finds a customer's redemptions by user and by email.
+// The code is filtered in Go to avoid composite indexes.
+func (r *DiscountRepository) customerRedemptionQueries(userID *uuid.UUID, email string) []firestore.Query {
+	var queries []firestore.Query
+	if userID != nil {
+		queries = append(queries, r.firebase.Collection("discount_redemptions").Where("user_id", "==", *userID))
+	}
+	if email != "" {
+		queries = append(queries, r.firebase.Collection("discount_redemptions").Where("email", "==", email))
+	}
+	return queries
+}
+
+// countActiveRedemptions counts the unreleased redemptions of a code, once per document
+func countActiveRedemptions(docs []*firestore.DocumentSnapshot, codeID uuid.UUID) int {
+	seen := make(map[string]bool)
+	count := 0
+	for _, doc := range docs {
+		if seen[doc.Ref.ID] {
+			continue
+		}
+		seen[doc.Ref.ID] = true
+
+		var redemption models.DiscountRedemption
+		if err := doc.DataTo(&redemption); err != nil {
+			continue
+		}
+		if redemption.DiscountCodeID == codeID && redemption.ReleasedAt == nil {
+			count++
+		}
+	}
+	return count
+}
+
+// CreateBatch saves a batch and its codes. Writes are committed in chunks of 499,
+// so a failure part way can leave some codes saved; they are reachable by batch_id.
+func (r *DiscountRepository) CreateBatch(ctx context.Context, discountBatch *models.DiscountBatch, discounts []*models.DiscountCode) error {
+	now := time.Now()
+	discountBatch.CreatedAt = now
+	discountBatch.UpdatedAt = now
+
+	batch := r.firebase.Batch()
+	batch.Set(r.firebase.Collection("discount_batches").Doc(discountBatch.ID.String()), discountBatch)
+	count := 1
+
+	for _, discount := range discounts {
+		if discount.ID == uuid.Nil {
+			discount.ID = uuid.New()
+		}
+		discount.Code = strings.ToUpper(discount.Code)
+		discount.UsedCount = 0
+		discount.CreatedAt = now
+		discount.UpdatedAt = now
+
+		batch.Set(r.firebase.Collection("discount_codes").Doc(discount.ID.String()), discount)
+		count++
+
+		if count == 499 {
+			if _, err := batch.Commit(ctx); err != nil {
+				return err
+			}
+			batch = r.firebase.Batch()
+			count = 0
+		}
+	}
+
+	if count > 0 {
+		_, err := batch.Commit(ctx)
+		return err
+	}
+	return nil
+}
+
+// GetBatchByID gets a code batch by ID
+func (r *DiscountRepository) GetBatchByID(ctx context.Context, id uuid.UUID) (*models.DiscountBatch, error) {
+	doc, err := r.firebase.Collection("discount_batches").Doc(id.String()).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, errors.New("discount batch not found")
+		}
+		return nil, err
+	}
+
+	var discountBatch models.DiscountBatch
+	if err := doc.DataTo(&discountBatch); err != nil {
+		return nil, err
+	}
+
+	discountBatch.ID = id
+	return &discountBatch, nil
+}
+
+// GetAllBatches gets every code batch, newest first
+func (r *DiscountRepository) GetAllBatches(ctx context.Context) ([]*models.DiscountBatch, error) {
+	iter := r.firebase.Collection("discount_batches").Documents(ctx)
+	defer iter.Stop()
+
+	var batches []*models.DiscountBatch
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var discountBatch models.DiscountBatch
+		if err := doc.DataTo(&discountBatch); err != nil {
+			continue
+		}
+
+		batchID, err := uuid.Parse(doc.Ref.ID)
+		if err != nil {
+			continue
+		}
+		discountBatch.ID = batchID
+
+		batches = append(batches, &discountBatch)
+	}
+
+	// Sorted in Go to avoid an index on created_at
+	sort.Slice(batches, func(i, j int) bool {
+		return batches[i].CreatedAt.After(batches[j].CreatedAt)
 	})
+
+	return batches, nil
+}
+
+// GetCodesByBatch gets the codes of a batch, sorted by code
+func (r *DiscountRepository) GetCodesByBatch(ctx context.Context, batchID uuid.UUID) ([]*models.DiscountCode, error) {
+	docs, err := r.firebase.Collection("discount_codes").Where("batch_id", "==", batchID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	discounts := make([]*models.DiscountCode, 0, len(docs))
+	for _, doc := range docs {
+		var discount models.DiscountCode
+		if err := doc.DataTo(&discount); err != nil {
+			continue
+		}
+
+		discountID, err := uuid.Parse(doc.Ref.ID)
+		if err != nil {
+			continue
+		}
+		discount.ID = discountID
+
+		discounts = append(discounts, &discount)
+	}
+
+	sort.Slice(discounts, func(i, j int) bool {
+		return discounts[i].Code < discounts[j].Code
+	})
+
+	return discounts, nil
+}
+
+// GetCodesWithPrefix gets every code string starting with prefix (already uppercased)
+func (r *DiscountRepository) GetCodesWithPrefix(ctx context.Context, prefix string) (map[string]bool, error) {
+	docs, err := r.firebase.Collection("discount_codes").
+		Where("code", ">=", prefix).
+		Where("code", "<", prefix+"\uf8ff").
+		Select("code").
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		if code, ok := doc.Data()["code"].(string); ok {
+			existing[code] = true
+		}
+	}
+	return existing, nil
+}
+
+// GetRedemptionsByBatch gets every redemption (released included) of a batch's codes
+func (r *DiscountRepository) GetRedemptionsByBatch(ctx context.Context, batchID uuid.UUID) ([]*models.DiscountRedemption, error) {
+	docs, err := r.firebase.Collection("discount_redemptions").Where("batch_id", "==", batchID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	redemptions := make([]*models.DiscountRedemption, 0, len(docs))
+	for _, doc := range docs {
+		var redemption models.DiscountRedemption
+		if err := doc.DataTo(&redemption); err != nil {
+			continue
+		}
+		redemptions = append(redemptions, &redemption)
+	}
+	return redemptions, nil
+}
+
+// DeactivateBatch deactivates a batch and every one of its codes
+func (r *DiscountRepository) DeactivateBatch(ctx context.Context, batchID uuid.UUID) error {
+	docs, err := r.firebase.Collection("discount_codes").Where("batch_id", "==", batchID).Documents(ctx).GetAll()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	updates := []firestore.Update{
+		{Path: "is_active", Value: false},
+		{Path: "updated_at", Value: now},
+	}
+
+	batch := r.firebase.Batch()
+	batch.Update(r.firebase.Collection("discount_batches").Doc(batchID.String()), updates)
+	count := 1
+
+	for _, doc := range docs {
+		batch.Update(doc.Ref, updates)
+		count++
+
+		if count == 499 {
+			if _, err := batch.Commit(ctx); err != nil {
+				return err
+			}
+			batch = r.firebase.Batch()
+			count = 0
+		}
+	}
+
+	if count > 0 {
+		_, err := batch.Commit(ctx)
+		return err
+	}
+	return nil
 }
 
 // CountDiscountCodes counts total discount codes
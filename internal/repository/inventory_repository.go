@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cheoscafe/backend/internal/database"
+	"github.com/cheoscafe/backend/internal/models"
+	"google.golang.org/api/iterator"
+)
+
+const inventoryMovementsCollection = "inventory_movements"
+
+// InventoryRepository solo lee el ledger: los movimientos se escriben junto con
+// el cambio de stock en ProductRepository.ApplyStockChange y nunca se modifican.
+type InventoryRepository struct {
+	firebase *database.FirebaseClient
+}
+
+func NewInventoryRepository(firebase *database.FirebaseClient) *InventoryRepository {
+	return &InventoryRepository{
+		firebase: firebase,
+	}
+}
+
+// ListByProduct obtiene los movimientos de un producto, del más reciente al más antiguo.
+// Sin Where en "created_at" ni OrderBy para evitar índice compuesto — filtramos en Go
+func (r *InventoryRepository) ListByProduct(ctx context.Context, filter *models.InventoryMovementFilter) ([]*models.InventoryMovement, error) {
+	iter := r.firebase.Collection(inventoryMovementsCollection).
+		Where("product_id", "==", filter.ProductID).
+		Documents(ctx)
+	defer iter.Stop()
+
+	movements := []*models.InventoryMovement{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var m models.InventoryMovement
+		if err := doc.DataTo(&m); err != nil {
+			continue
+		}
+
+		if filter.From != nil && m.CreatedAt.Before(*filter.From) {
+			continue
+		}
+		if filter.To != nil && !m.CreatedAt.Before(*filter.To) {
+			continue
+		}
+		if filter.Reason != "" && m.Reason != filter.Reason {
+			continue
+		}
+
+		movements = append(movements, &m)
+	}
+
+	sort.Slice(movements, func(i, j int) bool {
+		return movements[i].CreatedAt.After(movements[j].CreatedAt)
+	})
+
+	return movements, nil
+}
+
+// ListSince obtiene todos los movimientos desde la fecha dada (para reportes agregados)
+func (r *InventoryRepository) ListSince(ctx context.Context, from time.Time) ([]*models.InventoryMovement, error) {
+	iter := r.firebase.Collection(inventoryMovementsCollection).
+		Where("created_at", ">=", from).
+		Documents(ctx)
+	defer iter.Stop()
+
+	movements := []*models.InventoryMovement{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var m models.InventoryMovement
+		if err := doc.DataTo(&m); err != nil {
+			continue
+		}
+		movements = append(movements, &m)
+	}
+
+	return movements, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/firestore"
@@ -38,6 +39,8 @@ func (r *OrderRepository) Create(ctx context.Context, order *models.Order) error
 	if order.OrderNumber == "" {
 		order.OrderNumber = fmt.Sprintf("ORD-%s", order.ID.String()[:8])
 	}
+	// Normalizado para que ListByCustomerSince encuentre las órdenes del mismo email
+	order.CustomerEmail = strings.ToLower(strings.TrimSpace(order.CustomerEmail))
 
 	_, err := r.firebase.Collection("orders").Doc(order.ID.String()).Set(ctx, order)
 	if err != nil {
@@ -61,6 +64,22 @@ func (r *OrderRepository) CreateOrderItem(ctx context.Context, item *models.Orde
 	return nil
 }
 
+// Delete elimina una orden y sus items (una orden que no se pudo completar)
+func (r *OrderRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	items, err := r.firebase.Collection("order_items").Where("order_id", "==", id).Documents(ctx).GetAll()
+	if err != nil {
+		return err
+	}
+
+	batch := r.firebase.Batch()
+	for _, doc := range items {
+		batch.Delete(doc.Ref)
+	}
+	batch.Delete(r.firebase.Collection("orders").Doc(id.String()))
+	_, err = batch.Commit(ctx)
+	return err
+}
+
 // GetByID obtiene una orden por ID
 func (r *OrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Order, error) {
 	doc, err := r.firebase.Collection("orders").Doc(id.String()).Get(ctx)
@@ -147,6 +166,132 @@ func (r *OrderRepository) GetByUserID(ctx context.Context, userID uuid.UUID, lim
 	return orders, nil
 }
 
+// HasOrderSince indica si el usuario creó alguna orden después de since.
+// Filtramos la fecha en Go para evitar índices compuestos
+func (r *OrderRepository) HasOrderSince(ctx context.Context, userID uuid.UUID, since time.Time) (bool, error) {
+	iter := r.firebase.Collection("orders").Where("user_id", "==", userID).Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		var order models.Order
+		if err := doc.DataTo(&order); err != nil {
+			continue
+		}
+		if order.CreatedAt.After(since) {
+			return true, nil
+		}
+	}
+}
+
+// ListByCustomerSince obtiene las órdenes no canceladas de un cliente creadas desde since.
+// Busca por usuario y por email (las órdenes de invitado solo tienen el email), sin
+// distinguir mayúsculas: Create guarda el email normalizado.
+// Filtramos fecha y estado en Go para evitar índices compuestos
+func (r *OrderRepository) ListByCustomerSince(ctx context.Context, userID *uuid.UUID, email string, since time.Time) ([]*models.Order, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	var queries []firestore.Query
+	if userID != nil {
+		queries = append(queries, r.firebase.Collection("orders").Where("user_id", "==", *userID))
+	}
+	if email != "" {
+		queries = append(queries, r.firebase.Collection("orders").Where("customer_email", "==", email))
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	var orders []*models.Order
+	for _, query := range queries {
+		iter := query.Documents(ctx)
+		for {
+			doc, err := iter.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				iter.Stop()
+				return nil, err
+			}
+
+			var order models.Order
+			if err := doc.DataTo(&order); err != nil {
+				continue
+			}
+			orderID, err := uuid.Parse(doc.Ref.ID)
+			if err != nil || seen[orderID] {
+				continue
+			}
+			order.ID = orderID
+
+			if order.Status == models.OrderCancelled || order.CreatedAt.Before(since) {
+				continue
+			}
+			seen[orderID] = true
+			orders = append(orders, &order)
+		}
+		iter.Stop()
+	}
+
+	return orders, nil
+}
+
+// ListByCustomersBefore obtiene las órdenes no canceladas creadas antes de before de
+// cualquiera de los usuarios o emails dados. Se consulta con "in" en tandas de 30
+// (límite de Firestore) y la fecha y el estado se filtran en Go para evitar índices compuestos.
+func (r *OrderRepository) ListByCustomersBefore(ctx context.Context, userIDs []uuid.UUID, emails []string, before time.Time) ([]*models.Order, error) {
+	var queries []firestore.Query
+	for start := 0; start < len(userIDs); start += 30 {
+		chunk := userIDs[start:min(start+30, len(userIDs))]
+		values := make([]interface{}, 0, len(chunk))
+		for _, id := range chunk {
+			values = append(values, id)
+		}
+		queries = append(queries, r.firebase.Collection("orders").Where("user_id", "in", values))
+	}
+	for start := 0; start < len(emails); start += 30 {
+		chunk := emails[start:min(start+30, len(emails))]
+		values := make([]interface{}, 0, len(chunk))
+		for _, email := range chunk {
+			values = append(values, strings.ToLower(strings.TrimSpace(email)))
+		}
+		queries = append(queries, r.firebase.Collection("orders").Where("customer_email", "in", values))
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	var orders []*models.Order
+	for _, query := range queries {
+		docs, err := query.Documents(ctx).GetAll()
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range docs {
+			var order models.Order
+			if err := doc.DataTo(&order); err != nil {
+				continue
+			}
+			orderID, err := uuid.Parse(doc.Ref.ID)
+			if err != nil || seen[orderID] {
+				continue
+			}
+			order.ID = orderID
+
+			if order.Status == models.OrderCancelled || !order.CreatedAt.Before(before) {
+				continue
+			}
+			seen[orderID] = true
+			orders = append(orders, &order)
+		}
+	}
+
+	return orders, nil
+}
+
 // GetAll obtiene todas las órdenes con paginación (sin filtro)
 func (r *OrderRepository) GetAll(ctx context.Context, limit int, offset int) ([]*models.Order, error) {
 	query := r.firebase.Collection("orders").
@@ -252,6 +397,50 @@ func (r *OrderRepository) GetItemsByOrderID(ctx context.Context, orderID uuid.UU
 	return items, nil
 }
 
+// GetItemsByOrderIDs obtiene los items de varias órdenes.
+// Firestore limita el operador "in" a 30 valores, así que se consulta por tandas.
+func (r *OrderRepository) GetItemsByOrderIDs(ctx context.Context, orderIDs []uuid.UUID) ([]*models.OrderItem, error) {
+	var items []*models.OrderItem
+	for start := 0; start < len(orderIDs); start += 30 {
+		end := min(start+30, len(orderIDs))
+		chunk := make([]interface{}, 0, end-start)
+		for _, id := range orderIDs[start:end] {
+			chunk = append(chunk, id)
+		}
+
+		iter := r.firebase.Collection("order_items").
+			Where("order_id", "in", chunk).
+			Documents(ctx)
+
+		for {
+			doc, err := iter.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				iter.Stop()
+				return nil, err
+			}
+
+			var item models.OrderItem
+			if err := doc.DataTo(&item); err != nil {
+				continue
+			}
+
+			itemID, err := uuid.Parse(doc.Ref.ID)
+			if err != nil {
+				continue
+			}
+			item.ID = itemID
+
+			items = append(items, &item)
+		}
+		iter.Stop()
+	}
+
+	return items, nil
+}
+
 // Update actualiza una orden
 func (r *OrderRepository) Update(ctx context.Context, order *models.Order) error {
 	order.UpdatedAt = time.Now()
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cheoscafe/backend/internal/database"
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/google/uuid"
+	"google.golang.org/api/iterator"
+)
+
+const priceHistoryCollection = "price_history"
+
+type PriceHistoryRepository struct {
+	firebase *database.FirebaseClient
+}
+
+func NewPriceHistoryRepository(firebase *database.FirebaseClient) *PriceHistoryRepository {
+	return &PriceHistoryRepository{
+		firebase: firebase,
+	}
+}
+
+// CreateMany registra varios cambios de precio en un solo batch
+func (r *PriceHistoryRepository) CreateMany(ctx context.Context, changes []*models.PriceChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	batch := r.firebase.Batch()
+	for _, change := range changes {
+		change.ID = uuid.New()
+		change.CreatedAt = now
+		batch.Set(r.firebase.Collection(priceHistoryCollection).Doc(change.ID.String()), change)
+	}
+
+	_, err := batch.Commit(ctx)
+	return err
+}
+
+// ListByProduct obtiene el historial de precios de un producto, del más reciente al más antiguo.
+// Sin OrderBy para evitar índice compuesto — ordenamos en Go
+func (r *PriceHistoryRepository) ListByProduct(ctx context.Context, productID uuid.UUID) ([]*models.PriceChange, error) {
+	iter := r.firebase.Collection(priceHistoryCollection).
+		Where("product_id", "==", productID).
+		Documents(ctx)
+	defer iter.Stop()
+
+	changes := []*models.PriceChange{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var change models.PriceChange
+		if err := doc.DataTo(&change); err != nil {
+			continue
+		}
+		changes = append(changes, &change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].CreatedAt.After(changes[j].CreatedAt)
+	})
+
+	return changes, nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/firestore"
@@ -14,6 +15,9 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+// ErrProductNotFound se retorna cuando ningún producto coincide, para distinguirlo de un error de Firestore
+var ErrProductNotFound = errors.New("producto no encontrado")
+
 type ProductRepository struct {
 	firebase *database.FirebaseClient
 }
@@ -33,6 +37,8 @@ func (r *ProductRepository) Create(ctx context.Context, product *models.Product)
 	now := time.Now()
 	product.CreatedAt = now
 	product.UpdatedAt = now
+	product.SKUs = collectSKUs(product)
+	product.BundleProductIDs = collectBundleProductIDs(product)
 
 	_, err := r.firebase.Collection("products").Doc(product.ID.String()).Set(ctx, product)
 	if err != nil {
@@ -47,7 +53,7 @@ func (r *ProductRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.
 	doc, err := r.firebase.Collection("products").Doc(id.String()).Get(ctx)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
-			return nil, errors.New("producto no encontrado")
+			return nil, ErrProductNotFound
 		}
 		return nil, err
 	}
@@ -58,6 +64,7 @@ func (r *ProductRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.
 	}
 
 	product.ID = id
+	product.ApplyPricing(time.Now())
 	return &product, nil
 }
 
@@ -92,6 +99,7 @@ func (r *ProductRepository) GetAll(ctx context.Context, limit int, offset int) (
 		}
 		product.ID = productID
 
+		product.ApplyPricing(time.Now())
 		products = append(products, &product)
 	}
 
@@ -129,22 +137,57 @@ func (r *ProductRepository) GetFeatured(ctx context.Context, limit int) ([]*mode
 		}
 		product.ID = productID
 
+		product.ApplyPricing(time.Now())
 		products = append(products, &product)
 	}
 
 	return products, nil
 }
 
-// Update actualiza un producto
+// Update actualiza un producto. El stock (del producto y de sus variantes) se conserva
+// tal como está guardado: solo cambia con ApplyStockChanges, que deja el movimiento en el
+// ledger, o con SetStock para kits. Así una edición no pisa la venta que entró mientras tanto.
+// Las variantes nuevas empiezan en 0. Deja en product el stock que quedó guardado.
 func (r *ProductRepository) Update(ctx context.Context, product *models.Product) error {
-	product.UpdatedAt = time.Now()
+	ref := r.firebase.Collection("products").Doc(product.ID.String())
 
-	_, err := r.firebase.Collection("products").Doc(product.ID.String()).Set(ctx, product)
-	if err != nil {
-		return err
+	return r.firebase.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return ErrProductNotFound
+			}
+			return err
+		}
+
+		var current models.Product
+		if err := doc.DataTo(&current); err != nil {
+			return err
+		}
+		keepStoredStock(product, &current)
+
+		product.UpdatedAt = time.Now()
+		product.SKUs = collectSKUs(product)
+		product.BundleProductIDs = collectBundleProductIDs(product)
+
+		return tx.Set(ref, product)
+	})
+}
+
+// keepStoredStock copia en product el stock guardado en current, variante por variante
+func keepStoredStock(product, current *models.Product) {
+	stored := make(map[uuid.UUID]int, len(current.Variants))
+	for _, v := range current.Variants {
+		stored[v.ID] = v.Stock
+	}
+	for i := range product.Variants {
+		product.Variants[i].Stock = stored[product.Variants[i].ID]
 	}
 
-	return nil
+	product.Stock = current.Stock
+	if product.HasVariants() {
+		product.Stock = totalVariantStock(product.Variants)
+	}
 }
 
 // Delete elimina un producto físicamente (hard delete)
@@ -157,52 +200,349 @@ func (r *ProductRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-// UpdateStock actualiza el stock de un producto
-func (r *ProductRepository) UpdateStock(ctx context.Context, id uuid.UUID, quantity int) error {
-	return r.firebase.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
-		docRef := r.firebase.Collection("products").Doc(id.String())
-		doc, err := tx.Get(docRef)
+// ApplyStockChange aplica un cambio de stock y registra el movimiento en el ledger
+// (inventory_movements) dentro de la misma transacción, así nunca hay cambio sin registro.
+// Si el cambio es sobre una variante, el stock agregado del producto se recalcula
+// como la suma de sus variantes.
+// Retorna también el producto como quedó tras el cambio.
+func (r *ProductRepository) ApplyStockChange(ctx context.Context, change *models.StockChange) (*models.InventoryMovement, *models.Product, error) {
+	movements, products, err := r.ApplyStockChanges(ctx, []*models.StockChange{change}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return movements[0], products[0], nil
+}
+
+// ApplyStockChanges aplica varios cambios de stock en una sola transacción: o se aplican
+// todos o ninguno (p. ej. los componentes de un kit). Cada cambio genera su movimiento.
+// limits (opcional) son los límites por cliente de una venta: se verifican y registran
+// en la misma transacción, así dos órdenes simultáneas del cliente no pueden superarlos.
+// Retorna los movimientos y los productos como quedaron, en el mismo orden que changes.
+func (r *ProductRepository) ApplyStockChanges(ctx context.Context, changes []*models.StockChange, limits []*models.CustomerLimit) ([]*models.InventoryMovement, []*models.Product, error) {
+	var movements []*models.InventoryMovement
+	var result []*models.Product
+
+	err := r.firebase.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		movements = make([]*models.InventoryMovement, 0, len(changes))
+		result = make([]*models.Product, 0, len(changes))
+
+		// Firestore exige todas las lecturas antes de cualquier escritura
+		products := make(map[uuid.UUID]*models.Product)
+		order := []uuid.UUID{}
+		for _, change := range changes {
+			if _, ok := products[change.ProductID]; ok {
+				continue
+			}
+			doc, err := tx.Get(r.firebase.Collection("products").Doc(change.ProductID.String()))
+			if err != nil {
+				if status.Code(err) == codes.NotFound {
+					return ErrProductNotFound
+				}
+				return err
+			}
+
+			var product models.Product
+			if err := doc.DataTo(&product); err != nil {
+				return err
+			}
+			product.ID = change.ProductID
+			products[change.ProductID] = &product
+			order = append(order, change.ProductID)
+		}
+
+		var keys []string
+		for _, limit := range limits {
+			keys = append(keys, limit.CustomerKeys...)
+		}
+		purchases, err := getCustomerPurchases(tx, r.firebase, keys)
 		if err != nil {
 			return err
 		}
 
-		var product models.Product
-		if err := doc.DataTo(&product); err != nil {
+		now := time.Now()
+		if err := checkCustomerLimits(purchases, limits, now); err != nil {
 			return err
 		}
+		for _, change := range changes {
+			product := products[change.ProductID]
+
+			movement := &models.InventoryMovement{
+				ID:          uuid.New(),
+				ProductID:   change.ProductID,
+				VariantID:   change.VariantID,
+				ProductName: product.Name,
+				Reason:      change.Reason,
+				Reference:   change.Reference,
+				Note:        change.Note,
+				ActorID:     change.ActorID,
+				CreatedAt:   now,
+			}
+
+			// Stock actual del producto o de la variante
+			current := product.Stock
+			var variant *models.ProductVariant
+			if change.VariantID == nil {
+				if product.HasVariants() {
+					return errors.New("el producto tiene variantes, debe indicar la variante")
+				}
+			} else {
+				variant = product.FindVariant(*change.VariantID)
+				if variant == nil {
+					return errors.New("variante no encontrada")
+				}
+				current = variant.Stock
+				movement.VariantName = product.VariantLabel(variant)
+				movement.SKU = variant.SKU
+			}
+
+			delta := change.Delta
+			if change.SetTo != nil {
+				delta = *change.SetTo - current
+			}
+			newStock := current + delta
+			if newStock < 0 {
+				return errors.New("stock insuficiente")
+			}
+			movement.Delta = delta
+			movement.Balance = newStock
+
+			if variant != nil {
+				variant.Stock = newStock
+				product.Stock = totalVariantStock(product.Variants)
+			} else {
+				product.Stock = newStock
+			}
+
+			movements = append(movements, movement)
+			result = append(result, product)
+		}
 
-		newStock := product.Stock + quantity
-		if newStock < 0 {
-			return errors.New("stock insuficiente")
+		for _, id := range order {
+			product := products[id]
+			updates := []firestore.Update{
+				{Path: "updated_at", Value: now},
+				{Path: "stock", Value: product.Stock},
+			}
+			if product.HasVariants() {
+				updates = append(updates, firestore.Update{Path: "variants", Value: product.Variants})
+			}
+			if err := tx.Update(r.firebase.Collection("products").Doc(id.String()), updates); err != nil {
+				return err
+			}
 		}
 
-		return tx.Update(docRef, []firestore.Update{
-			{Path: "stock", Value: newStock},
-			{Path: "updated_at", Value: time.Now()},
-		})
+		for _, movement := range movements {
+			movementRef := r.firebase.Collection(inventoryMovementsCollection).Doc(movement.ID.String())
+			if err := tx.Create(movementRef, movement); err != nil {
+				return err
+			}
+		}
+
+		for key, record := range purchases {
+			if err := tx.Set(r.firebase.Collection(customerPurchasesCollection).Doc(key), record); err != nil {
+				return err
+			}
+		}
+		return nil
 	})
-}
+	if err != nil {
+		return nil, nil, err
+	}
 
-// Search busca productos por nombre o descripción
-func (r *ProductRepository) Search(ctx context.Context, searchTerm string, limit int) ([]*models.Product, error) {
-	// Firestore no tiene búsqueda full-text nativa, así que hacemos búsqueda simple
-	// Para producción, considera usar Algolia o Elasticsearch
+	return movements, result, nil
+}
 
-	query := r.firebase.Collection("products").
-		Where("is_active", "==", true).
-		Limit(limit * 3) // Obtenemos más para filtrar localmente
+// SetStock fija el stock derivado de un kit (no genera movimiento: el ledger
+// registra los componentes, que son los que tienen existencias físicas)
+func (r *ProductRepository) SetStock(ctx context.Context, id uuid.UUID, stock int) error {
+	_, err := r.firebase.Collection("products").Doc(id.String()).Update(ctx, []firestore.Update{
+		{Path: "stock", Value: stock},
+		{Path: "updated_at", Value: time.Now()},
+	})
+	return err
+}
 
-	iter := query.Documents(ctx)
+// ListBundlesContaining obtiene los kits que incluyen al producto dado como componente
+func (r *ProductRepository) ListBundlesContaining(ctx context.Context, productID uuid.UUID) ([]*models.Product, error) {
+	iter := r.firebase.Collection("products").
+		Where("bundle_product_ids", "array-contains", productID.String()).
+		Documents(ctx)
 	defer iter.Stop()
 
 	var products []*models.Product
-	count := 0
-
 	for {
-		if count >= limit {
+		doc, err := iter.Next()
+		if err == iterator.Done {
 			break
 		}
+		if err != nil {
+			return nil, err
+		}
+
+		var product models.Product
+		if err := doc.DataTo(&product); err != nil {
+			continue
+		}
+
+		id, err := uuid.Parse(doc.Ref.ID)
+		if err != nil {
+			continue
+		}
+		product.ID = id
+
+		product.ApplyPricing(time.Now())
+		products = append(products, &product)
+	}
+
+	return products, nil
+}
+
+// UpdateRating actualiza el promedio y la cantidad de reseñas aprobadas de un producto
+func (r *ProductRepository) UpdateRating(ctx context.Context, id uuid.UUID, rating float64, reviewCount int) error {
+	_, err := r.firebase.Collection("products").Doc(id.String()).Update(ctx, []firestore.Update{
+		{Path: "rating", Value: rating},
+		{Path: "review_count", Value: reviewCount},
+	})
+	return err
+}
+
+// SetCategory asigna la categoría de un producto junto con su nombre denormalizado
+func (r *ProductRepository) SetCategory(ctx context.Context, id uuid.UUID, categoryID uuid.UUID, categoryName string) error {
+	_, err := r.firebase.Collection("products").Doc(id.String()).Update(ctx, []firestore.Update{
+		{Path: "category_id", Value: categoryID},
+		{Path: "category", Value: categoryName},
+		{Path: "updated_at", Value: time.Now()},
+	})
+	return err
+}
+
+// UpdateCategoryName propaga el nuevo nombre de una categoría a sus productos (batch).
+// Sin Where en "category_id" — filtramos en Go como en el resto del catálogo.
+func (r *ProductRepository) UpdateCategoryName(ctx context.Context, categoryID uuid.UUID, categoryName string) error {
+	products, err := r.ListAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	batch := r.firebase.Batch()
+	count := 0
+	for _, product := range products {
+		if product.CategoryID == nil || *product.CategoryID != categoryID || product.Category == categoryName {
+			continue
+		}
+
+		batch.Update(r.firebase.Collection("products").Doc(product.ID.String()), []firestore.Update{
+			{Path: "category", Value: categoryName},
+		})
+		count++
+
+		if count == 499 {
+			if _, err := batch.Commit(ctx); err != nil {
+				return err
+			}
+			batch = r.firebase.Batch()
+			count = 0
+		}
+	}
+
+	if count > 0 {
+		_, err := batch.Commit(ctx)
+		return err
+	}
+	return nil
+}
+
+// GetBySlug obtiene un producto por su slug actual
+func (r *ProductRepository) GetBySlug(ctx context.Context, slug string) (*models.Product, error) {
+	return r.getOneWhere(ctx, "slug", "==", slug)
+}
+
+// GetByPreviousSlug obtiene el producto que usó el slug dado antes de ser renombrado
+func (r *ProductRepository) GetByPreviousSlug(ctx context.Context, slug string) (*models.Product, error) {
+	return r.getOneWhere(ctx, "previous_slugs", "array-contains", slug)
+}
+
+// GetBySKU obtiene el producto que tiene el SKU dado (propio o de cualquiera de sus variantes)
+func (r *ProductRepository) GetBySKU(ctx context.Context, sku string) (*models.Product, error) {
+	return r.getOneWhere(ctx, "skus", "array-contains", strings.ToUpper(strings.TrimSpace(sku)))
+}
+
+// getOneWhere obtiene el primer producto que cumple la condición
+func (r *ProductRepository) getOneWhere(ctx context.Context, path, op string, value interface{}) (*models.Product, error) {
+	iter := r.firebase.Collection("products").
+		Where(path, op, value).
+		Limit(1).
+		Documents(ctx)
+	defer iter.Stop()
 
+	doc, err := iter.Next()
+	if err == iterator.Done {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var product models.Product
+	if err := doc.DataTo(&product); err != nil {
+		return nil, err
+	}
+
+	productID, err := uuid.Parse(doc.Ref.ID)
+	if err != nil {
+		return nil, err
+	}
+	product.ID = productID
+	product.ApplyPricing(time.Now())
+
+	return &product, nil
+}
+
+// collectSKUs arma el índice de SKUs del producto (normalizados en mayúscula)
+func collectSKUs(product *models.Product) []string {
+	skus := make([]string, 0, len(product.Variants)+1)
+	if product.SKU != "" {
+		skus = append(skus, strings.ToUpper(product.SKU))
+	}
+	for _, v := range product.Variants {
+		if v.SKU != "" {
+			skus = append(skus, strings.ToUpper(v.SKU))
+		}
+	}
+	return skus
+}
+
+// collectBundleProductIDs arma el índice de componentes de un kit, para encontrar
+// con array-contains los kits afectados cuando cambia el stock de un componente
+func collectBundleProductIDs(product *models.Product) []string {
+	ids := make([]string, 0, len(product.BundleItems))
+	seen := make(map[uuid.UUID]bool)
+	for _, item := range product.BundleItems {
+		if !seen[item.ProductID] {
+			seen[item.ProductID] = true
+			ids = append(ids, item.ProductID.String())
+		}
+	}
+	return ids
+}
+
+// totalVariantStock suma el stock de todas las variantes
+func totalVariantStock(variants []models.ProductVariant) int {
+	total := 0
+	for _, v := range variants {
+		total += v.Stock
+	}
+	return total
+}
+
+// ListAll obtiene todos los productos sin paginar (activos e inactivos).
+// Pensado para procesos que necesitan el catálogo completo, como el índice de búsqueda.
+func (r *ProductRepository) ListAll(ctx context.Context) ([]*models.Product, error) {
+	iter := r.firebase.Collection("products").Documents(ctx)
+	defer iter.Stop()
+
+	var products []*models.Product
+	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
 			break
@@ -216,17 +556,50 @@ func (r *ProductRepository) Search(ctx context.Context, searchTerm string, limit
 			continue
 		}
 
-		// Filtro simple por nombre (case-insensitive sería mejor)
-		// En producción, usa un servicio de búsqueda dedicado
-
 		productID, err := uuid.Parse(doc.Ref.ID)
 		if err != nil {
 			continue
 		}
 		product.ID = productID
 
+		product.ApplyPricing(time.Now())
+		products = append(products, &product)
+	}
+
+	return products, nil
+}
+
+// GetByIDs obtiene varios productos en una sola lectura, respetando el orden de ids.
+// Los productos que no existen se omiten.
+func (r *ProductRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Product, error) {
+	if len(ids) == 0 {
+		return []*models.Product{}, nil
+	}
+
+	refs := make([]*firestore.DocumentRef, 0, len(ids))
+	for _, id := range ids {
+		refs = append(refs, r.firebase.Collection("products").Doc(id.String()))
+	}
+
+	docs, err := r.firebase.Firestore.GetAll(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]*models.Product, 0, len(docs))
+	for i, doc := range docs {
+		if !doc.Exists() {
+			continue
+		}
+
+		var product models.Product
+		if err := doc.DataTo(&product); err != nil {
+			continue
+		}
+		product.ID = ids[i]
+
+		product.ApplyPricing(time.Now())
 		products = append(products, &product)
-		count++
 	}
 
 	return products, nil
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/cheoscafe/backend/internal/database"
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/google/uuid"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type PromotionRepository struct {
+	firebase *database.FirebaseClient
+}
+
+func NewPromotionRepository(firebase *database.FirebaseClient) *PromotionRepository {
+	return &PromotionRepository{
+		firebase: firebase,
+	}
+}
+
+// Create creates a new promotion
+func (r *PromotionRepository) Create(ctx context.Context, promotion *models.Promotion) error {
+	if promotion.ID == uuid.Nil {
+		promotion.ID = uuid.New()
+	}
+
+	now := time.Now()
+	promotion.CreatedAt = now
+	promotion.UpdatedAt = now
+
+	_, err := r.firebase.Collection("promotions").Doc(promotion.ID.String()).Set(ctx, promotion)
+	return err
+}
+
+// GetByID gets a promotion by ID
+func (r *PromotionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Promotion, error) {
+	doc, err := r.firebase.Collection("promotions").Doc(id.String()).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, errors.New("promotion not found")
+		}
+		return nil, err
+	}
+
+	var promotion models.Promotion
+	if err := doc.DataTo(&promotion); err != nil {
+		return nil, err
+	}
+
+	promotion.ID = id
+	return &promotion, nil
+}
+
+// GetAll gets every promotion, highest priority first
+func (r *PromotionRepository) GetAll(ctx context.Context) ([]*models.Promotion, error) {
+	return r.list(ctx, false)
+}
+
+// GetActive gets the promotions marked active, highest priority first.
+// The schedule is checked by the caller.
+func (r *PromotionRepository) GetActive(ctx context.Context) ([]*models.Promotion, error) {
+	return r.list(ctx, true)
+}
+
+func (r *PromotionRepository) list(ctx context.Context, activeOnly bool) ([]*models.Promotion, error) {
+	query := r.firebase.Collection("promotions").Query
+	if activeOnly {
+		query = query.Where("is_active", "==", true)
+	}
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	var promotions []*models.Promotion
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var promotion models.Promotion
+		if err := doc.DataTo(&promotion); err != nil {
+			continue
+		}
+
+		promotionID, err := uuid.Parse(doc.Ref.ID)
+		if err != nil {
+			continue
+		}
+		promotion.ID = promotionID
+
+		promotions = append(promotions, &promotion)
+	}
+
+	// Sorted in Go to avoid composite indexes
+	sort.SliceStable(promotions, func(i, j int) bool {
+		if promotions[i].Priority != promotions[j].Priority {
+			return promotions[i].Priority > promotions[j].Priority
+		}
+		return promotions[i].CreatedAt.Before(promotions[j].CreatedAt)
+	})
+
+	return promotions, nil
+}
+
+// Update replaces a promotion
+func (r *PromotionRepository) Update(ctx context.Context, promotion *models.Promotion) error {
+	promotion.UpdatedAt = time.Now()
+
+	_, err := r.firebase.Collection("promotions").Doc(promotion.ID.String()).Set(ctx, promotion)
+	return err
+}
+
+// Delete deletes a promotion (hard delete)
+func (r *PromotionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.firebase.Collection("promotions").Doc(id.String()).Delete(ctx)
+	return err
+}
@@ -174,6 +174,38 @@ func (r *ReviewRepository) GetAllByProductID(ctx context.Context, productID uuid
 	return reviews, nil
 }
 
+// ListAll gets every review of every product (used by the rating backfill)
+func (r *ReviewRepository) ListAll(ctx context.Context) ([]*models.Review, error) {
+	iter := r.firebase.Collection("reviews").Documents(ctx)
+	defer iter.Stop()
+
+	var reviews []*models.Review
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var review models.Review
+		if err := doc.DataTo(&review); err != nil {
+			continue
+		}
+
+		reviewID, err := uuid.Parse(doc.Ref.ID)
+		if err != nil {
+			continue
+		}
+		review.ID = reviewID
+
+		reviews = append(reviews, &review)
+	}
+
+	return reviews, nil
+}
+
 // Update updates a review
 func (r *ReviewRepository) Update(ctx context.Context, review *models.Review) error {
 	review.UpdatedAt = time.Now()
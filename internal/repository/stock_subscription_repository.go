@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/cheoscafe/backend/internal/database"
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/google/uuid"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const stockSubscriptionsCollection = "stock_subscriptions"
+
+type StockSubscriptionRepository struct {
+	firebase *database.FirebaseClient
+}
+
+func NewStockSubscriptionRepository(firebase *database.FirebaseClient) *StockSubscriptionRepository {
+	return &StockSubscriptionRepository{
+		firebase: firebase,
+	}
+}
+
+// Create registra una nueva suscripción pendiente
+func (r *StockSubscriptionRepository) Create(ctx context.Context, sub *models.StockSubscription) error {
+	if sub.ID == uuid.Nil {
+		sub.ID = uuid.New()
+	}
+	sub.Status = models.StockSubscriptionPending
+	sub.CreatedAt = time.Now()
+
+	_, err := r.firebase.Collection(stockSubscriptionsCollection).Doc(sub.ID.String()).Set(ctx, sub)
+	return err
+}
+
+// GetByID obtiene una suscripción por ID
+func (r *StockSubscriptionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.StockSubscription, error) {
+	doc, err := r.firebase.Collection(stockSubscriptionsCollection).Doc(id.String()).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, errors.New("suscripción no encontrada")
+		}
+		return nil, err
+	}
+
+	var sub models.StockSubscription
+	if err := doc.DataTo(&sub); err != nil {
+		return nil, err
+	}
+
+	sub.ID = id
+	return &sub, nil
+}
+
+// ListPending obtiene las suscripciones pendientes, de la más antigua a la más reciente (FIFO)
+func (r *StockSubscriptionRepository) ListPending(ctx context.Context) ([]*models.StockSubscription, error) {
+	return r.list(ctx, "status", string(models.StockSubscriptionPending))
+}
+
+// ListPendingByProduct obtiene las suscripciones pendientes de un producto en orden FIFO
+func (r *StockSubscriptionRepository) ListPendingByProduct(ctx context.Context, productID uuid.UUID) ([]*models.StockSubscription, error) {
+	return r.list(ctx, "product_id", productID)
+}
+
+// ListPendingByUser obtiene las suscripciones pendientes de un usuario
+func (r *StockSubscriptionRepository) ListPendingByUser(ctx context.Context, userID uuid.UUID) ([]*models.StockSubscription, error) {
+	return r.list(ctx, "user_id", userID)
+}
+
+// list obtiene las suscripciones pendientes que cumplen field == value.
+// Filtramos el estado y ordenamos en Go para evitar índices compuestos
+func (r *StockSubscriptionRepository) list(ctx context.Context, field string, value interface{}) ([]*models.StockSubscription, error) {
+	iter := r.firebase.Collection(stockSubscriptionsCollection).
+		Where(field, "==", value).
+		Documents(ctx)
+	defer iter.Stop()
+
+	subs := []*models.StockSubscription{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var sub models.StockSubscription
+		if err := doc.DataTo(&sub); err != nil {
+			continue
+		}
+		if sub.Status != models.StockSubscriptionPending {
+			continue
+		}
+		subs = append(subs, &sub)
+	}
+
+	sort.Slice(subs, func(i, j int) bool {
+		return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+	})
+
+	return subs, nil
+}
+
+// MarkSent marca la suscripción como avisada; desde ese momento expira
+func (r *StockSubscriptionRepository) MarkSent(ctx context.Context, id uuid.UUID, at time.Time) error {
+	_, err := r.firebase.Collection(stockSubscriptionsCollection).Doc(id.String()).
+		Update(ctx, []firestore.Update{
+			{Path: "status", Value: string(models.StockSubscriptionSent)},
+			{Path: "notified_at", Value: at},
+		})
+	return err
+}
+
+// Delete elimina una suscripción (el cliente la canceló)
+func (r *StockSubscriptionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.firebase.Collection(stockSubscriptionsCollection).Doc(id.String()).Delete(ctx)
+	return err
+}
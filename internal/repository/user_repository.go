@@ -47,6 +47,9 @@ func userFromDoc(doc *firestore.DocumentSnapshot, id uuid.UUID) (*models.User, e
 	if v, ok := data["is_active"].(bool); ok {
 		user.IsActive = v
 	}
+	if v, ok := data["email_marketing_opt_out"].(bool); ok {
+		user.EmailMarketingOptOut = v
+	}
 	if v, ok := data["created_at"]; ok {
 		if t, ok := v.(time.Time); ok {
 			user.CreatedAt = t
@@ -91,6 +94,8 @@ func userToMap(user *models.User) map[string]interface{} {
 		"is_active":  user.IsActive,
 		"created_at": user.CreatedAt,
 		"updated_at": user.UpdatedAt,
+
+		"email_marketing_opt_out": user.EmailMarketingOptOut,
 		// Inicializar nullable como nil siempre
 		"city":         nil,
 		"municipality": nil,
@@ -198,6 +203,7 @@ func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 	if user.Gender != nil {
 		profileFields["gender"] = string(*user.Gender)
 	}
+	profileFields["email_marketing_opt_out"] = user.EmailMarketingOptOut
 
 	// ── DEBUG ─────────────────────────────────────────────────────────────────
 	log.Printf("[DEBUG Update] doc=%s profileFields=%v", user.ID.String(), profileFields)
@@ -212,6 +218,18 @@ func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 	return err
 }
 
+// SetEmailMarketingOptOut activa o desactiva la baja de los correos comerciales
+func (r *UserRepository) SetEmailMarketingOptOut(ctx context.Context, id uuid.UUID, optOut bool) error {
+	_, err := r.firebase.Collection("users").Doc(id.String()).Update(ctx, []firestore.Update{
+		{Path: "email_marketing_opt_out", Value: optOut},
+		{Path: "updated_at", Value: time.Now()},
+	})
+	if status.Code(err) == codes.NotFound {
+		return errors.New("usuario no encontrado")
+	}
+	return err
+}
+
 func (r *UserRepository) EmailExists(ctx context.Context, email string) (bool, error) {
 	iter := r.firebase.Collection("users").Where("email", "==", email).Limit(1).Documents(ctx)
 	defer iter.Stop()
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/cheoscafe/backend/internal/database"
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/google/uuid"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const wishlistsCollection = "wishlists"
+
+type WishlistRepository struct {
+	firebase *database.FirebaseClient
+}
+
+func NewWishlistRepository(firebase *database.FirebaseClient) *WishlistRepository {
+	return &WishlistRepository{firebase: firebase}
+}
+
+// GetByUserID obtiene la lista de deseos de un usuario. Si no existe, retorna una lista vacía.
+func (r *WishlistRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*models.Wishlist, error) {
+	doc, err := r.firebase.Collection(wishlistsCollection).Doc(userID.String()).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return &models.Wishlist{
+				UserID:    userID,
+				Items:     []models.WishlistItem{},
+				UpdatedAt: time.Now(),
+			}, nil
+		}
+		return nil, err
+	}
+
+	var wishlist models.Wishlist
+	if err := doc.DataTo(&wishlist); err != nil {
+		return nil, err
+	}
+
+	wishlist.UserID = userID
+	if wishlist.Items == nil {
+		wishlist.Items = []models.WishlistItem{}
+	}
+
+	return &wishlist, nil
+}
+
+// GetByShareToken obtiene una lista compartida por su token público
+func (r *WishlistRepository) GetByShareToken(ctx context.Context, token string) (*models.Wishlist, error) {
+	iter := r.firebase.Collection(wishlistsCollection).
+		Where("share_token", "==", token).
+		Limit(1).
+		Documents(ctx)
+	defer iter.Stop()
+
+	doc, err := iter.Next()
+	if err == iterator.Done {
+		return nil, errors.New("lista de deseos no encontrada")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var wishlist models.Wishlist
+	if err := doc.DataTo(&wishlist); err != nil {
+		return nil, err
+	}
+
+	userID, err := uuid.Parse(doc.Ref.ID)
+	if err != nil {
+		return nil, err
+	}
+	wishlist.UserID = userID
+
+	return &wishlist, nil
+}
+
+// ListContainingProduct obtiene las listas que incluyen el producto (cualquier variante)
+func (r *WishlistRepository) ListContainingProduct(ctx context.Context, productID uuid.UUID) ([]*models.Wishlist, error) {
+	return r.list(r.firebase.Collection(wishlistsCollection).
+		Where("product_ids", "array-contains", productID.String()).
+		Documents(ctx))
+}
+
+// ListAll obtiene todas las listas no vacías (para el job de avisos de ofertas)
+func (r *WishlistRepository) ListAll(ctx context.Context) ([]*models.Wishlist, error) {
+	return r.list(r.firebase.Collection(wishlistsCollection).Documents(ctx))
+}
+
+func (r *WishlistRepository) list(iter *firestore.DocumentIterator) ([]*models.Wishlist, error) {
+	defer iter.Stop()
+
+	var wishlists []*models.Wishlist
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var wishlist models.Wishlist
+		if err := doc.DataTo(&wishlist); err != nil {
+			continue
+		}
+
+		userID, err := uuid.Parse(doc.Ref.ID)
+		if err != nil {
+			continue
+		}
+		wishlist.UserID = userID
+
+		if len(wishlist.Items) > 0 {
+			wishlists = append(wishlists, &wishlist)
+		}
+	}
+
+	return wishlists, nil
+}
+
+// Save guarda/sobreescribe la lista completa
+func (r *WishlistRepository) Save(ctx context.Context, wishlist *models.Wishlist) error {
+	wishlist.UpdatedAt = time.Now()
+
+	ids := make([]string, 0, len(wishlist.Items))
+	seen := make(map[uuid.UUID]bool)
+	for _, item := range wishlist.Items {
+		if !seen[item.ProductID] {
+			seen[item.ProductID] = true
+			ids = append(ids, item.ProductID.String())
+		}
+	}
+	wishlist.ProductIDs = ids
+
+	_, err := r.firebase.Collection(wishlistsCollection).Doc(wishlist.UserID.String()).Set(ctx, wishlist)
+	return err
+}
+
+// SetSaleNotified actualiza solo sale_notified_for de los items dados, dentro de una
+// transacción y sobre la lista tal como está guardada: los items agregados o quitados
+// mientras tanto se conservan. Los items que ya no están en la lista se ignoran.
+func (r *WishlistRepository) SetSaleNotified(ctx context.Context, userID uuid.UUID, updates []models.WishlistItem) error {
+	ref := r.firebase.Collection(wishlistsCollection).Doc(userID.String())
+
+	return r.firebase.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return nil
+			}
+			return err
+		}
+
+		var wishlist models.Wishlist
+		if err := doc.DataTo(&wishlist); err != nil {
+			return err
+		}
+
+		changed := false
+		for i := range wishlist.Items {
+			item := &wishlist.Items[i]
+			for _, update := range updates {
+				if item.Matches(update.ProductID, update.VariantID) {
+					item.SaleNotifiedFor = update.SaleNotifiedFor
+					changed = true
+				}
+			}
+		}
+		if !changed {
+			return nil
+		}
+
+		return tx.Update(ref, []firestore.Update{{Path: "items", Value: wishlist.Items}})
+	})
+}
@@ -186,6 +186,9 @@ func (s *AuthService) UpdateProfile(ctx context.Context, userID string, req *mod
 	user.Neighborhood = req.Neighborhood
 	user.BirthDate = req.BirthDate
 	user.Gender = req.Gender
+	if req.EmailMarketingOptOut != nil {
+		user.EmailMarketingOptOut = *req.EmailMarketingOptOut
+	}
 
 	log.Printf("[DEBUG UpdateProfile] user antes de guardar: city=%v municipality=%v neighborhood=%v gender=%v birth_date=%v",
 		user.City, user.Municipality, user.Neighborhood, user.Gender, user.BirthDate)
@@ -210,6 +213,16 @@ func (s *AuthService) UpdateProfile(ctx context.Context, userID string, req *mod
 	return saved, nil
 }
 
+// Unsubscribe da de baja al usuario de los correos comerciales con el token
+// del enlace incluido en ellos (no requiere sesión)
+func (s *AuthService) Unsubscribe(ctx context.Context, token string) error {
+	userID, err := utils.ValidateUnsubscribeToken(token, s.cfg.JWTSecret)
+	if err != nil {
+		return errors.New("enlace de baja inválido")
+	}
+	return s.userRepo.SetEmailMarketingOptOut(ctx, userID, true)
+}
+
 // GetAllUsers obtiene todos los usuarios (solo admin)
 func (s *AuthService) GetAllUsers(ctx context.Context) ([]*models.User, error) {
 	users, err := s.userRepo.GetAll(ctx)
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/cheoscafe/backend/internal/config"
+	"github.com/cheoscafe/backend/internal/database"
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Claves de caché. Todo lo derivado del catálogo cuelga de cacheCatalogPrefix
+// para poder invalidarlo de una sola vez ante cualquier escritura sobre productos.
+const (
+	cacheCatalogPrefix       = "cache:catalog:"
+	cacheCatalogListPrefix   = cacheCatalogPrefix + "list:"
+	cacheKeyFeatured         = cacheCatalogPrefix + "featured"
+	cacheKeyActiveLocations  = "cache:locations:active"
+	cacheKeyCarousel         = "cache:site:carousel"
+	cacheKeyAboutUs          = "cache:site:about"
+	cacheKeyDashboardSummary = "cache:dashboard:summary"
+)
+
+// CacheService es una caché read-through sobre Redis para lecturas públicas frecuentes.
+// Redis es opcional: sin cliente (o si falla) cada operación es un no-op y los
+// servicios leen directo de Firestore, así que la caché nunca es fuente de errores.
+type CacheService struct {
+	redis database.RedisClient
+
+	CatalogTTL   time.Duration // Listados y destacados
+	ProductTTL   time.Duration // Detalle de producto
+	ConfigTTL    time.Duration // Ubicaciones, carrusel y "Sobre Nosotros"
+	DashboardTTL time.Duration // Resumen del dashboard
+}
+
+func NewCacheService(redisClient database.RedisClient, cfg *config.Config) *CacheService {
+	return &CacheService{
+		redis:        redisClient,
+		CatalogTTL:   time.Duration(cfg.CacheCatalogTTLSeconds) * time.Second,
+		ProductTTL:   time.Duration(cfg.CacheProductTTLSeconds) * time.Second,
+		ConfigTTL:    time.Duration(cfg.CacheConfigTTLSeconds) * time.Second,
+		DashboardTTL: time.Duration(cfg.CacheDashboardTTLSeconds) * time.Second,
+	}
+}
+
+// enabled indica si hay Redis disponible
+func (c *CacheService) enabled() bool {
+	return c != nil && c.redis != nil
+}
+
+// Get lee una entrada y la decodifica en dest. Retorna false si no está (o no hay caché).
+func (c *CacheService) Get(ctx context.Context, key string, dest interface{}) bool {
+	if !c.enabled() {
+		return false
+	}
+
+	raw, err := c.redis.Get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.Printf("[Cache] Error leyendo %s: %v", key, err)
+		}
+		return false
+	}
+
+	if err := json.Unmarshal([]byte(raw), dest); err != nil {
+		log.Printf("[Cache] Entrada inválida en %s: %v", key, err)
+		return false
+	}
+	return true
+}
+
+// Set guarda value como JSON. Un ttl <= 0 desactiva la caché para esa clave.
+func (c *CacheService) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) {
+	if !c.enabled() || ttl <= 0 {
+		return
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("[Cache] Error serializando %s: %v", key, err)
+		return
+	}
+	if err := c.redis.Set(ctx, key, raw, ttl); err != nil {
+		log.Printf("[Cache] Error guardando %s: %v", key, err)
+	}
+}
+
+// Delete invalida una o más claves
+func (c *CacheService) Delete(ctx context.Context, keys ...string) {
+	if !c.enabled() || len(keys) == 0 {
+		return
+	}
+
+	if err := c.redis.Del(ctx, keys...); err != nil {
+		log.Printf("[Cache] Error invalidando %v: %v", keys, err)
+	}
+}
+
+// DeletePrefix invalida todas las claves que empiezan con prefix (SCAN, no bloquea Redis)
+func (c *CacheService) DeletePrefix(ctx context.Context, prefix string) {
+	if !c.enabled() {
+		return
+	}
+
+	client := c.redis.GetClient()
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, prefix+"*", 200).Result()
+		if err != nil {
+			log.Printf("[Cache] Error buscando claves %s*: %v", prefix, err)
+			return
+		}
+		if len(keys) > 0 {
+			c.Delete(ctx, keys...)
+		}
+		cursor = next
+		if cursor == 0 {
+			return
+		}
+	}
+}
+
+// InvalidateCatalog invalida listados, destacados y detalles de producto
+func (c *CacheService) InvalidateCatalog(ctx context.Context) {
+	c.DeletePrefix(ctx, cacheCatalogPrefix)
+}
+
+// InvalidateProducts invalida solo lo que puede mostrar los productos dados: su detalle
+// (por ID y por cada slug), los listados y los destacados. Es lo que corresponde tras
+// un movimiento de stock, donde recorrer todo el catálogo sería demasiado.
+func (c *CacheService) InvalidateProducts(ctx context.Context, products ...*models.Product) {
+	if !c.enabled() {
+		return
+	}
+
+	keys := []string{cacheKeyFeatured}
+	for _, product := range products {
+		keys = append(keys, cacheKeyProduct(product.ID))
+		if product.Slug != "" {
+			keys = append(keys, cacheKeySlug(product.Slug))
+		}
+		for _, slug := range product.PreviousSlugs {
+			keys = append(keys, cacheKeySlug(slug))
+		}
+	}
+	c.Delete(ctx, keys...)
+	c.DeletePrefix(ctx, cacheCatalogListPrefix)
+}
+
+// cacheKeyProduct es la clave del detalle de un producto por ID
+func cacheKeyProduct(id uuid.UUID) string {
+	return cacheCatalogPrefix + "product:" + id.String()
+}
+
+// cacheKeySlug es la clave del detalle de un producto por slug
+func cacheKeySlug(slug string) string {
+	return cacheCatalogPrefix + "slug:" + slug
+}
+
+// cacheKey arma una clave a partir de un valor arbitrario (p. ej. los filtros de un listado)
+func cacheKey(prefix string, value interface{}) string {
+	raw, _ := json.Marshal(value)
+	sum := sha1.Sum(raw)
+	return prefix + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/cheoscafe/backend/internal/config"
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/cheoscafe/backend/internal/repository"
+	"github.com/cheoscafe/backend/internal/utils"
+)
+
+const (
+	cartReminderMaxAgeDays = 7 // Carritos sin tocar hace más tiempo no se recuerdan (p. ej. al activar el job)
+	cartReminderCodeDays   = 7 // Vigencia del código de descuento del recordatorio
+	cartRecoveryWindowDays = 7 // Días tras el recordatorio en que una compra cuenta como recuperada
+)
+
+// CartRecoveryService envía recordatorios de carrito abandonado a usuarios
+// registrados y atribuye a esos recordatorios las compras que llegan después.
+// Cada carrito recibe como máximo un recordatorio: la marca vive en el propio
+// carrito y desaparece cuando se vacía o se convierte en orden.
+type CartRecoveryService struct {
+	cartRepo         *repository.CartRepository
+	reminderRepo     *repository.CartReminderRepository
+	orderRepo        *repository.OrderRepository
+	userRepo         *repository.UserRepository
+	discountService  *DiscountService
+	emailService     *EmailService
+	dashboardService *DashboardService
+	cfg              *config.Config
+}
+
+func NewCartRecoveryService(
+	cartRepo *repository.CartRepository,
+	reminderRepo *repository.CartReminderRepository,
+	orderRepo *repository.OrderRepository,
+	userRepo *repository.UserRepository,
+	discountService *DiscountService,
+	emailService *EmailService,
+	dashboardService *DashboardService,
+	cfg *config.Config,
+) *CartRecoveryService {
+	return &CartRecoveryService{
+		cartRepo:         cartRepo,
+		reminderRepo:     reminderRepo,
+		orderRepo:        orderRepo,
+		userRepo:         userRepo,
+		discountService:  discountService,
+		emailService:     emailService,
+		dashboardService: dashboardService,
+		cfg:              cfg,
+	}
+}
+
+// SendReminders busca los carritos con productos sin tocar hace ABANDONED_CART_HOURS
+// y envía el recordatorio a los que no lo recibieron. Retorna cuántos se enviaron.
+func (s *CartRecoveryService) SendReminders(ctx context.Context) (int, error) {
+	hours := s.cfg.AbandonedCartHours
+	if hours <= 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	carts, err := s.cartRepo.ListStale(ctx, now.Add(-time.Duration(hours)*time.Hour))
+	if err != nil {
+		return 0, fmt.Errorf("error al obtener carritos: %w", err)
+	}
+
+	oldest := now.AddDate(0, 0, -cartReminderMaxAgeDays)
+	sent := 0
+	for _, cart := range carts {
+		if len(cart.Items) == 0 || cart.ReminderSentAt != nil || cart.UpdatedAt.Before(oldest) {
+			continue
+		}
+		ok, err := s.remind(ctx, cart)
+		if err != nil {
+			log.Printf("[CartRecovery] Error con el carrito de %s: %v", cart.UserID, err)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// StartReminders revisa los carritos abandonados cada hora.
+// Bloquea hasta que ctx se cancele; lanzar en una goroutine.
+func (s *CartRecoveryService) StartReminders(ctx context.Context) {
+	if s.cfg.AbandonedCartHours <= 0 {
+		log.Printf("[CartRecovery] Recordatorios de carrito desactivados (ABANDONED_CART_HOURS=0)")
+		return
+	}
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sent, err := s.SendReminders(ctx)
+			if err != nil {
+				log.Printf("[CartRecovery] Error enviando recordatorios: %v", err)
+				continue
+			}
+			if sent > 0 {
+				log.Printf("[CartRecovery] %d recordatorio(s) de carrito enviado(s)", sent)
+			}
+		}
+	}
+}
+
+// remind envía el recordatorio de un carrito. Retorna false sin error si el
+// usuario no debe recibirlo (inactivo, dado de baja o ya compró).
+func (s *CartRecoveryService) remind(ctx context.Context, cart *models.Cart) (bool, error) {
+	user, err := s.userRepo.GetByID(ctx, cart.UserID)
+	if err != nil {
+		return false, err
+	}
+	if !user.IsActive || user.EmailMarketingOptOut {
+		return false, nil
+	}
+
+	ordered, err := s.orderRepo.HasOrderSince(ctx, user.ID, cart.UpdatedAt)
+	if err != nil {
+		return false, fmt.Errorf("error al obtener órdenes: %w", err)
+	}
+	if ordered {
+		return false, nil
+	}
+
+	unsubscribeToken, err := utils.GenerateUnsubscribeToken(user.ID, s.cfg.JWTSecret)
+	if err != nil {
+		return false, err
+	}
+
+	// Se marca antes de enviar: ante un fallo es preferible no reintentar a enviar dos veces
+	now := time.Now()
+	if err := s.cartRepo.MarkReminderSent(ctx, user.ID, now); err != nil {
+		return false, err
+	}
+
+	reminder := &models.CartReminder{
+		UserID: user.ID,
+		Email:  user.Email,
+		SentAt: now,
+	}
+	for _, item := range cart.Items {
+		reminder.ItemCount += item.Quantity
+		reminder.CartSubtotal += item.ProductPrice * float64(item.Quantity)
+	}
+
+	percent := s.cfg.AbandonedCartDiscountPercent
+	if percent > 0 {
+		code, err := s.createDiscountCode(ctx, percent, now)
+		if err != nil {
+			// El recordatorio sigue siendo útil sin código
+			log.Printf("[CartRecovery] Error creando código para %s: %v", user.Email, err)
+		} else {
+			reminder.DiscountCode = code
+		}
+	}
+
+	unsubscribeURL := s.cfg.FrontendURL + "/desuscribir?token=" + unsubscribeToken
+	if err := s.emailService.SendAbandonedCartEmail(user.Email, user.Name, cart.Items, reminder.DiscountCode, percent, unsubscribeURL); err != nil {
+		return false, fmt.Errorf("error enviando email: %w", err)
+	}
+
+	if err := s.reminderRepo.Create(ctx, reminder); err != nil {
+		return false, fmt.Errorf("error registrando recordatorio: %w", err)
+	}
+	if s.dashboardService != nil {
+		if err := s.dashboardService.OnCartReminderSent(ctx, now); err != nil {
+			log.Printf("Error actualizando metricas de dashboard: %v", err)
+		}
+	}
+
+	return true, nil
+}
+
+// createDiscountCode crea el código de un solo uso incluido en el recordatorio
+func (s *CartRecoveryService) createDiscountCode(ctx context.Context, percent int, now time.Time) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	maxUses := 1
+
+	discount, err := s.discountService.CreateDiscountCode(ctx, &models.CreateDiscountCodeRequest{
+		Code:        "VUELVE-" + strings.ToUpper(hex.EncodeToString(b)),
+		Description: "Recordatorio de carrito abandonado",
+		Type:        models.DiscountPercentage,
+		Value:       float64(percent),
+		MaxUses:     &maxUses,
+		StartDate:   now,
+		EndDate:     now.AddDate(0, 0, cartReminderCodeDays),
+		IsActive:    true,
+	})
+	if err != nil {
+		return "", err
+	}
+	return discount.Code, nil
+}
+
+// AttributeOrder marca la orden como recuperada si el usuario recibió un
+// recordatorio en los últimos días que todavía no tiene compra asociada.
+// Se llama antes de guardar la orden; retorna el recordatorio para enlazarlo después.
+func (s *CartRecoveryService) AttributeOrder(ctx context.Context, order *models.Order) *models.CartReminder {
+	if s == nil || order.UserID == nil {
+		return nil
+	}
+
+	since := time.Now().AddDate(0, 0, -cartRecoveryWindowDays)
+	reminder, err := s.reminderRepo.GetLatestPending(ctx, *order.UserID, since)
+	if err != nil {
+		log.Printf("[CartRecovery] Error buscando recordatorios de %s: %v", order.UserID, err)
+		return nil
+	}
+	if reminder != nil {
+		order.RecoveredCart = true
+	}
+	return reminder
+}
+
+// LinkOrder enlaza la orden ya guardada con el recordatorio que la recuperó
+func (s *CartRecoveryService) LinkOrder(ctx context.Context, reminder *models.CartReminder, order *models.Order) {
+	if s == nil || reminder == nil {
+		return
+	}
+	if err := s.reminderRepo.MarkRecovered(ctx, reminder.ID, order.ID); err != nil {
+		log.Printf("[CartRecovery] Error enlazando la orden %s al recordatorio %s: %v", order.OrderNumber, reminder.ID, err)
+	}
+}
@@ -4,62 +4,256 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"time"
 
+	"github.com/cheoscafe/backend/internal/config"
 	"github.com/cheoscafe/backend/internal/models"
 	"github.com/cheoscafe/backend/internal/repository"
+	"github.com/cheoscafe/backend/internal/utils"
 	"github.com/google/uuid"
 )
 
+// CartService maneja los carritos de usuarios y de invitados. Los invitados se
+// identifican con un token de carrito firmado (JWT_SECRET) que solo contiene el
+// ID del carrito; el carrito vive en Firestore y vence tras GUEST_CART_TTL_DAYS
+// sin actividad. Al iniciar sesión o registrarse se fusiona en el del usuario.
 type CartService struct {
-	cartRepo    *repository.CartRepository
-	productRepo *repository.ProductRepository
+	cartRepo         *repository.CartRepository
+	productRepo      *repository.ProductRepository
+	orderRepo        *repository.OrderRepository
+	promotionService *PromotionService
+	cfg              *config.Config
 }
 
-func NewCartService(cartRepo *repository.CartRepository, productRepo *repository.ProductRepository) *CartService {
+func NewCartService(cartRepo *repository.CartRepository, productRepo *repository.ProductRepository, orderRepo *repository.OrderRepository, promotionService *PromotionService, cfg *config.Config) *CartService {
 	return &CartService{
-		cartRepo:    cartRepo,
-		productRepo: productRepo,
+		cartRepo:         cartRepo,
+		productRepo:      productRepo,
+		orderRepo:        orderRepo,
+		promotionService: promotionService,
+		cfg:              cfg,
 	}
 }
 
-// GetCart obtiene el carrito de un usuario
-func (s *CartService) GetCart(ctx context.Context, userID uuid.UUID) (*models.Cart, error) {
-	return s.cartRepo.GetByUserID(ctx, userID)
+// GuestCartTTL es cuánto dura un carrito de invitado (y su token) sin actividad
+func (s *CartService) GuestCartTTL() time.Duration {
+	days := s.cfg.GuestCartTTLDays
+	if days < 1 {
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// GuestToken firma (o renueva) el token del carrito de invitado dado.
+// El carrito en sí no se guarda hasta que se le agregue algo.
+func (s *CartService) GuestToken(guestID uuid.UUID) (string, error) {
+	return utils.GenerateCartToken(guestID, s.cfg.JWTSecret, s.GuestCartTTL())
+}
+
+// ParseGuestToken valida un token de carrito y retorna el ID del carrito de invitado
+func (s *CartService) ParseGuestToken(token string) (uuid.UUID, error) {
+	return utils.ValidateCartToken(token, s.cfg.JWTSecret)
+}
+
+// GetCart obtiene el carrito de un usuario o invitado revalidado contra el catálogo
+// actual: refresca nombre, imagen y precio de cada item, baja las cantidades al
+// stock disponible y quita lo que ya no se puede comprar, avisando cada ajuste.
+// Si algo cambió, el carrito se guarda ya corregido. También muestra las promociones
+// automáticas que aplicarían sin código de descuento.
+func (s *CartService) GetCart(ctx context.Context, owner models.CartOwner) (*models.CartResponse, error) {
+	cart, err := s.cartRepo.GetByOwner(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	warnings, products, changed, err := s.revalidate(ctx, cart)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		if err := s.save(ctx, cart); err != nil {
+			return nil, err
+		}
+	}
+
+	response := &models.CartResponse{
+		Cart:     cart,
+		Warnings: warnings,
+	}
+	lines := make([]models.PromotionLine, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		response.ItemCount += item.Quantity
+		response.Subtotal += item.ProductPrice * float64(item.Quantity)
+		lines = append(lines, models.PromotionLine{
+			ProductID:  item.ProductID,
+			CategoryID: products[item.ProductID].CategoryID,
+			UnitPrice:  item.ProductPrice,
+			Quantity:   item.Quantity,
+		})
+	}
+
+	promotions, err := s.promotionService.Evaluate(ctx, lines, false)
+	if err != nil {
+		return nil, fmt.Errorf("error al evaluar promociones: %w", err)
+	}
+	response.Promotions = promotions.applied
+	response.Discount = promotions.total
+
+	return response, nil
+}
+
+// revalidate compara cada item con su producto vigente. Los productos eliminados o
+// inactivos, las variantes inactivas y lo agotado se quitan del carrito con aviso.
+// Retorna también los productos por ID.
+func (s *CartService) revalidate(ctx context.Context, cart *models.Cart) ([]models.CartWarning, map[uuid.UUID]*models.Product, bool, error) {
+	warnings := []models.CartWarning{}
+	if len(cart.Items) == 0 {
+		return warnings, nil, false, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(cart.Items))
+	seen := make(map[uuid.UUID]bool)
+	for _, item := range cart.Items {
+		if !seen[item.ProductID] {
+			seen[item.ProductID] = true
+			ids = append(ids, item.ProductID)
+		}
+	}
+	products, err := s.productRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("error al obtener productos: %w", err)
+	}
+	byID := make(map[uuid.UUID]*models.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+
+	changed := false
+	items := make([]models.CartItem, 0, len(cart.Items))
+	for _, cartItem := range cart.Items {
+		warning := models.CartWarning{
+			ProductID:   cartItem.ProductID,
+			VariantID:   cartItem.VariantID,
+			ProductName: cartItem.ProductName,
+		}
+
+		product, ok := byID[cartItem.ProductID]
+		if !ok || !product.IsActive {
+			warning.Type = models.CartWarningItemUnavailable
+			warning.Message = fmt.Sprintf("%s ya no está disponible y se quitó del carrito", cartItem.ProductName)
+			warnings = append(warnings, warning)
+			changed = true
+			continue
+		}
+
+		item, err := resolvePurchasable(product, cartItem.VariantID)
+		if err != nil || item.Stock <= 0 {
+			warning.Type = models.CartWarningItemUnavailable
+			warning.Message = fmt.Sprintf("%s ya no está disponible y se quitó del carrito", cartItem.ProductName)
+			if err == nil {
+				warning.Message = fmt.Sprintf("%s se agotó y se quitó del carrito", displayName(product, item))
+			}
+			warnings = append(warnings, warning)
+			changed = true
+			continue
+		}
+		warning.ProductName = displayName(product, item)
+
+		if cartItem.ProductPrice != item.Price {
+			warnings = append(warnings, models.CartWarning{
+				Type:          models.CartWarningPriceChanged,
+				ProductID:     cartItem.ProductID,
+				VariantID:     cartItem.VariantID,
+				ProductName:   warning.ProductName,
+				Message:       fmt.Sprintf("el precio de %s cambió de $%.0f a $%.0f", warning.ProductName, cartItem.ProductPrice, item.Price),
+				PreviousPrice: cartItem.ProductPrice,
+				CurrentPrice:  item.Price,
+			})
+			cartItem.ProductPrice = item.Price
+			changed = true
+		}
+
+		if cartItem.Quantity > item.Stock {
+			warnings = append(warnings, models.CartWarning{
+				Type:             models.CartWarningQuantityReduced,
+				ProductID:        cartItem.ProductID,
+				VariantID:        cartItem.VariantID,
+				ProductName:      warning.ProductName,
+				Message:          fmt.Sprintf("solo hay %d unidad(es) disponible(s) de %s", item.Stock, warning.ProductName),
+				PreviousQuantity: cartItem.Quantity,
+				Quantity:         item.Stock,
+			})
+			cartItem.Quantity = item.Stock
+			changed = true
+		}
+
+		// Datos de presentación: se refrescan sin aviso
+		productImage := ""
+		if len(product.Images) > 0 {
+			productImage = product.Images[0]
+		}
+		if cartItem.ProductName != product.Name || cartItem.VariantName != item.VariantName ||
+			cartItem.SKU != item.SKU || cartItem.ProductImage != productImage {
+			cartItem.ProductName = product.Name
+			cartItem.VariantName = item.VariantName
+			cartItem.SKU = item.SKU
+			cartItem.ProductImage = productImage
+			changed = true
+		}
+
+		items = append(items, cartItem)
+	}
+	cart.Items = items
+
+	return warnings, byID, changed, nil
 }
 
-// AddItem agrega un producto al carrito o suma cantidad si ya existe.
-// Solo valida que el producto exista y que haya stock suficiente.
-// is_active no se verifica aquí — un producto puede estar "inactivo"
-// visualmente pero aún tener stock comprable.
-func (s *CartService) AddItem(ctx context.Context, userID uuid.UUID, req *models.AddToCartRequest) (*models.Cart, error) {
+// AddItem agrega un producto (o variante) al carrito o suma cantidad si ya existe.
+// Valida que el producto exista y esté activo, que haya stock suficiente y sus reglas
+// de compra. Un producto inactivo se quitaría del carrito al revalidarlo.
+func (s *CartService) AddItem(ctx context.Context, owner models.CartOwner, req *models.AddToCartRequest) (*models.Cart, error) {
 	product, err := s.productRepo.GetByID(ctx, req.ProductID)
 	if err != nil {
 		return nil, errors.New("producto no encontrado")
 	}
+	if !product.IsActive {
+		return nil, fmt.Errorf("%s no está disponible", product.Name)
+	}
 
-	cart, err := s.cartRepo.GetByUserID(ctx, userID)
+	item, err := resolvePurchasable(product, req.VariantID)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := s.cartRepo.GetByOwner(ctx, owner)
 	if err != nil {
 		return nil, err
 	}
 
 	// Calcular cantidad total resultante para validar contra stock
 	newQuantity := req.Quantity
-	for _, item := range cart.Items {
-		if item.ProductID == req.ProductID {
-			newQuantity += item.Quantity
+	for _, cartItem := range cart.Items {
+		if cartItem.Matches(req.ProductID, req.VariantID) {
+			newQuantity += cartItem.Quantity
 			break
 		}
 	}
 
-	if product.Stock < newQuantity {
-		return nil, fmt.Errorf("solo hay %d unidad(es) disponible(s) de %s", product.Stock, product.Name)
+	if item.Stock < newQuantity {
+		return nil, fmt.Errorf("solo hay %d unidad(es) disponible(s) de %s", item.Stock, displayName(product, item))
+	}
+
+	if err := s.checkPurchaseRules(ctx, owner, product, productQuantity(cart, product.ID)+req.Quantity); err != nil {
+		return nil, err
 	}
 
 	// Buscar si el producto ya está en el carrito
 	found := false
-	for i, item := range cart.Items {
-		if item.ProductID == req.ProductID {
+	for i := range cart.Items {
+		if cart.Items[i].Matches(req.ProductID, req.VariantID) {
 			cart.Items[i].Quantity += req.Quantity
+			cart.Items[i].ProductPrice = item.Price // Precio vigente (puede haber empezado o terminado una oferta)
 			found = true
 			break
 		}
@@ -73,14 +267,17 @@ func (s *CartService) AddItem(ctx context.Context, userID uuid.UUID, req *models
 
 		cart.Items = append(cart.Items, models.CartItem{
 			ProductID:    product.ID,
+			VariantID:    req.VariantID,
+			VariantName:  item.VariantName,
+			SKU:          item.SKU,
 			ProductName:  product.Name,
-			ProductPrice: product.Price,
+			ProductPrice: item.Price,
 			ProductImage: productImage,
 			Quantity:     req.Quantity,
 		})
 	}
 
-	if err := s.cartRepo.Save(ctx, cart); err != nil {
+	if err := s.save(ctx, cart); err != nil {
 		return nil, err
 	}
 
@@ -88,25 +285,30 @@ func (s *CartService) AddItem(ctx context.Context, userID uuid.UUID, req *models
 }
 
 // UpdateItemQuantity actualiza la cantidad de un item en el carrito.
-// Valida que la nueva cantidad no supere el stock disponible.
-func (s *CartService) UpdateItemQuantity(ctx context.Context, userID uuid.UUID, productID uuid.UUID, req *models.UpdateCartItemRequest) (*models.Cart, error) {
+// Valida que la nueva cantidad no supere el stock disponible ni las reglas de compra.
+func (s *CartService) UpdateItemQuantity(ctx context.Context, owner models.CartOwner, productID uuid.UUID, variantID *uuid.UUID, req *models.UpdateCartItemRequest) (*models.Cart, error) {
 	product, err := s.productRepo.GetByID(ctx, productID)
 	if err != nil {
 		return nil, errors.New("producto no encontrado")
 	}
 
-	if product.Stock < req.Quantity {
-		return nil, fmt.Errorf("solo hay %d unidad(es) disponible(s) de %s", product.Stock, product.Name)
+	item, err := resolvePurchasable(product, variantID)
+	if err != nil {
+		return nil, err
 	}
 
-	cart, err := s.cartRepo.GetByUserID(ctx, userID)
+	if item.Stock < req.Quantity {
+		return nil, fmt.Errorf("solo hay %d unidad(es) disponible(s) de %s", item.Stock, displayName(product, item))
+	}
+
+	cart, err := s.cartRepo.GetByOwner(ctx, owner)
 	if err != nil {
 		return nil, err
 	}
 
 	found := false
-	for i, item := range cart.Items {
-		if item.ProductID == productID {
+	for i := range cart.Items {
+		if cart.Items[i].Matches(productID, variantID) {
 			cart.Items[i].Quantity = req.Quantity
 			found = true
 			break
@@ -117,85 +319,111 @@ func (s *CartService) UpdateItemQuantity(ctx context.Context, userID uuid.UUID,
 		return nil, errors.New("producto no encontrado en el carrito")
 	}
 
-	if err := s.cartRepo.Save(ctx, cart); err != nil {
+	if err := s.checkPurchaseRules(ctx, owner, product, productQuantity(cart, productID)); err != nil {
+		return nil, err
+	}
+
+	if err := s.save(ctx, cart); err != nil {
 		return nil, err
 	}
 
 	return cart, nil
 }
 
-// RemoveItem elimina un producto del carrito
-func (s *CartService) RemoveItem(ctx context.Context, userID uuid.UUID, productID uuid.UUID) (*models.Cart, error) {
-	cart, err := s.cartRepo.GetByUserID(ctx, userID)
+// RemoveItem elimina un producto (o variante) del carrito
+func (s *CartService) RemoveItem(ctx context.Context, owner models.CartOwner, productID uuid.UUID, variantID *uuid.UUID) (*models.Cart, error) {
+	cart, err := s.cartRepo.GetByOwner(ctx, owner)
 	if err != nil {
 		return nil, err
 	}
 
 	newItems := make([]models.CartItem, 0)
 	for _, item := range cart.Items {
-		if item.ProductID != productID {
+		if !item.Matches(productID, variantID) {
 			newItems = append(newItems, item)
 		}
 	}
 	cart.Items = newItems
 
-	if err := s.cartRepo.Save(ctx, cart); err != nil {
+	if err := s.save(ctx, cart); err != nil {
 		return nil, err
 	}
 
 	return cart, nil
 }
 
-// ClearCart vacía el carrito de un usuario
-func (s *CartService) ClearCart(ctx context.Context, userID uuid.UUID) error {
-	return s.cartRepo.Delete(ctx, userID)
+// ClearCart vacía el carrito de un usuario o invitado
+func (s *CartService) ClearCart(ctx context.Context, owner models.CartOwner) error {
+	return s.cartRepo.DeleteByOwner(ctx, owner)
 }
 
-// SyncCart fusiona el carrito local (invitado) con el guardado en Firebase.
-// Ignora productos que no existen. Respeta el stock — si la cantidad
-// fusionada supera el stock, se clampea al máximo disponible.
-func (s *CartService) SyncCart(ctx context.Context, userID uuid.UUID, req *models.SyncCartRequest) (*models.Cart, error) {
+// MergeGuestCart fusiona el carrito de invitado en el del usuario al iniciar sesión
+// o registrarse, y elimina el de invitado. Ignora productos o variantes que ya no
+// existen. Respeta el stock — si la cantidad fusionada supera el stock, se clampea
+// al máximo disponible, y las líneas sin stock se quitan.
+func (s *CartService) MergeGuestCart(ctx context.Context, userID uuid.UUID, guestID uuid.UUID) (*models.Cart, error) {
+	guest, err := s.cartRepo.GetGuest(ctx, guestID)
+	if err != nil {
+		return nil, err
+	}
+
 	cart, err := s.cartRepo.GetByUserID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
+	if len(guest.Items) == 0 {
+		return cart, nil
+	}
 
-	for _, reqItem := range req.Items {
-		product, err := s.productRepo.GetByID(ctx, reqItem.ProductID)
+	for _, guestItem := range guest.Items {
+		product, err := s.productRepo.GetByID(ctx, guestItem.ProductID)
 		if err != nil {
 			continue // Producto no existe, ignorar
 		}
 
+		item, err := resolvePurchasable(product, guestItem.VariantID)
+		if err != nil {
+			continue // Variante inválida, ignorar
+		}
+
 		// Buscar si ya existe en el carrito guardado
 		found := false
-		for i, item := range cart.Items {
-			if item.ProductID == reqItem.ProductID {
-				merged := item.Quantity + reqItem.Quantity
+		for i, cartItem := range cart.Items {
+			if cartItem.Matches(guestItem.ProductID, guestItem.VariantID) {
+				found = true
+				// Sin stock la línea se quita en vez de quedar en 0
+				if item.Stock <= 0 {
+					cart.Items = append(cart.Items[:i], cart.Items[i+1:]...)
+					break
+				}
+
+				merged := cartItem.Quantity + guestItem.Quantity
 				// Clampear al stock disponible
-				if merged > product.Stock {
-					merged = product.Stock
+				if merged > item.Stock {
+					merged = item.Stock
 				}
 				cart.Items[i].Quantity = merged
 				// Actualizar datos del producto por si cambiaron
 				cart.Items[i].ProductName = product.Name
-				cart.Items[i].ProductPrice = product.Price
+				cart.Items[i].ProductPrice = item.Price
+				cart.Items[i].VariantName = item.VariantName
+				cart.Items[i].SKU = item.SKU
 				if len(product.Images) > 0 {
 					cart.Items[i].ProductImage = product.Images[0]
 				}
-				found = true
 				break
 			}
 		}
 
 		if !found {
 			// Solo agregar si hay stock
-			if product.Stock <= 0 {
+			if item.Stock <= 0 {
 				continue
 			}
 
-			qty := reqItem.Quantity
-			if qty > product.Stock {
-				qty = product.Stock
+			qty := guestItem.Quantity
+			if qty > item.Stock {
+				qty = item.Stock
 			}
 
 			productImage := ""
@@ -205,17 +433,83 @@ func (s *CartService) SyncCart(ctx context.Context, userID uuid.UUID, req *model
 
 			cart.Items = append(cart.Items, models.CartItem{
 				ProductID:    product.ID,
+				VariantID:    guestItem.VariantID,
+				VariantName:  item.VariantName,
+				SKU:          item.SKU,
 				ProductName:  product.Name,
-				ProductPrice: product.Price,
+				ProductPrice: item.Price,
 				ProductImage: productImage,
 				Quantity:     qty,
 			})
 		}
 	}
 
-	if err := s.cartRepo.Save(ctx, cart); err != nil {
+	if err := s.save(ctx, cart); err != nil {
 		return nil, err
 	}
 
+	if err := s.cartRepo.DeleteGuest(ctx, guestID); err != nil {
+		// El carrito ya quedó fusionado; el de invitado vencerá solo
+		log.Printf("[Cart] Error eliminando carrito de invitado %s: %v", guestID, err)
+	}
+
 	return cart, nil
 }
+
+// StartGuestCartCleanup elimina cada día los carritos de invitado vencidos.
+// Bloquea hasta que ctx se cancele; lanzar en una goroutine.
+func (s *CartService) StartGuestCartCleanup(ctx context.Context) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.cartRepo.DeleteExpiredGuests(ctx, time.Now())
+			if err != nil {
+				log.Printf("[Cart] Error limpiando carritos de invitado: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("[Cart] %d carrito(s) de invitado vencido(s) eliminado(s)", deleted)
+			}
+		}
+	}
+}
+
+// checkPurchaseRules valida las reglas de compra del producto con la cantidad total
+// que quedaría en el carrito. El límite por cliente solo se conoce para usuarios.
+func (s *CartService) checkPurchaseRules(ctx context.Context, owner models.CartOwner, product *models.Product, quantity int) error {
+	purchased := 0
+	if product.PurchaseRules.HasCustomerLimit() && !owner.IsGuest() {
+		userID := owner.UserID
+		counts, err := purchasedQuantities(ctx, s.orderRepo, purchaseCustomer{userID: &userID}, []*models.Product{product})
+		if err != nil {
+			return err
+		}
+		purchased = counts[product.ID]
+	}
+	return checkPurchaseRules(product, quantity, purchased)
+}
+
+// productQuantity suma las unidades del producto en el carrito (todas sus variantes)
+func productQuantity(cart *models.Cart, productID uuid.UUID) int {
+	total := 0
+	for _, item := range cart.Items {
+		if item.ProductID == productID {
+			total += item.Quantity
+		}
+	}
+	return total
+}
+
+// save guarda el carrito; los de invitado renuevan su vencimiento con cada cambio
+func (s *CartService) save(ctx context.Context, cart *models.Cart) error {
+	if cart.GuestID != nil {
+		expiresAt := time.Now().Add(s.GuestCartTTL())
+		cart.ExpiresAt = &expiresAt
+	}
+	return s.cartRepo.Save(ctx, cart)
+}
@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/cheoscafe/backend/internal/repository"
+	"github.com/cheoscafe/backend/internal/utils"
+	"github.com/google/uuid"
+)
+
+type CategoryService struct {
+	categoryRepo *repository.CategoryRepository
+	productRepo  *repository.ProductRepository
+	searchIndex  *ProductSearchIndex
+	cache        *CacheService
+}
+
+func NewCategoryService(categoryRepo *repository.CategoryRepository, productRepo *repository.ProductRepository, searchIndex *ProductSearchIndex, cache *CacheService) *CategoryService {
+	return &CategoryService{
+		categoryRepo: categoryRepo,
+		productRepo:  productRepo,
+		searchIndex:  searchIndex,
+		cache:        cache,
+	}
+}
+
+// CreateCategory crea una nueva categoría (solo admin)
+func (s *CategoryService) CreateCategory(ctx context.Context, req *models.CreateCategoryRequest) (*models.Category, error) {
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	all, err := s.categoryRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener categorías: %w", err)
+	}
+
+	category := &models.Category{
+		ID:          uuid.New(),
+		Name:        strings.TrimSpace(req.Name),
+		ParentID:    req.ParentID,
+		Description: req.Description,
+		Image:       req.Image,
+		SortOrder:   req.SortOrder,
+		IsActive:    true,
+	}
+	if req.IsActive != nil {
+		category.IsActive = *req.IsActive
+	}
+
+	slug, err := resolveCategorySlug(all, category.ID, req.Slug, category.Name)
+	if err != nil {
+		return nil, err
+	}
+	category.Slug = slug
+
+	if err := validateCategoryParent(all, category.ID, category.ParentID); err != nil {
+		return nil, err
+	}
+
+	if err := s.categoryRepo.Create(ctx, category); err != nil {
+		return nil, fmt.Errorf("error al crear categoría: %w", err)
+	}
+
+	return category, nil
+}
+
+// GetCategory obtiene una categoría por ID
+func (s *CategoryService) GetCategory(ctx context.Context, id uuid.UUID) (*models.Category, error) {
+	return s.categoryRepo.GetByID(ctx, id)
+}
+
+// GetAllCategories obtiene todas las categorías en lista plana, incluidas las inactivas (admin)
+func (s *CategoryService) GetAllCategories(ctx context.Context) ([]*models.Category, error) {
+	categories, err := s.categoryRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener categorías: %w", err)
+	}
+	return categories, nil
+}
+
+// GetCategoryTree arma el árbol público de categorías activas.
+// Una subcategoría de una categoría inactiva no se muestra.
+func (s *CategoryService) GetCategoryTree(ctx context.Context) ([]*models.CategoryNode, error) {
+	categories, err := s.categoryRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener categorías: %w", err)
+	}
+
+	nodes := make(map[uuid.UUID]*models.CategoryNode, len(categories))
+	for _, c := range categories {
+		if c.IsActive {
+			nodes[c.ID] = &models.CategoryNode{Category: *c, Children: []*models.CategoryNode{}}
+		}
+	}
+
+	// categories ya viene ordenado, así que los hijos quedan en orden
+	roots := []*models.CategoryNode{}
+	for _, c := range categories {
+		node, ok := nodes[c.ID]
+		if !ok {
+			continue
+		}
+		if c.ParentID == nil {
+			roots = append(roots, node)
+			continue
+		}
+		if parent, ok := nodes[*c.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+
+	return roots, nil
+}
+
+// UpdateCategory actualiza una categoría (solo admin).
+// Si cambia el nombre, se propaga a los productos que la referencian.
+func (s *CategoryService) UpdateCategory(ctx context.Context, id uuid.UUID, req *models.UpdateCategoryRequest) (*models.Category, error) {
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	category, err := s.categoryRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := s.categoryRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener categorías: %w", err)
+	}
+
+	oldName := category.Name
+	if req.Name != nil {
+		category.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Slug != nil {
+		slug, err := resolveCategorySlug(all, category.ID, *req.Slug, category.Name)
+		if err != nil {
+			return nil, err
+		}
+		category.Slug = slug
+	}
+	if req.RemoveParent {
+		category.ParentID = nil
+	} else if req.ParentID != nil {
+		category.ParentID = req.ParentID
+	}
+	if req.Description != nil {
+		category.Description = *req.Description
+	}
+	if req.Image != nil {
+		category.Image = *req.Image
+	}
+	if req.SortOrder != nil {
+		category.SortOrder = *req.SortOrder
+	}
+	if req.IsActive != nil {
+		category.IsActive = *req.IsActive
+	}
+
+	if err := validateCategoryParent(all, category.ID, category.ParentID); err != nil {
+		return nil, err
+	}
+
+	if err := s.categoryRepo.Update(ctx, category); err != nil {
+		return nil, fmt.Errorf("error al actualizar categoría: %w", err)
+	}
+
+	if category.Name != oldName {
+		if err := s.productRepo.UpdateCategoryName(ctx, category.ID, category.Name); err != nil {
+			log.Printf("Warning: no se pudo propagar el nombre de la categoría %s: %v", category.ID, err)
+		}
+		s.searchIndex.Invalidate()
+	}
+	// El filtro por categoría del catálogo resuelve slug y subcategorías
+	s.cache.InvalidateCatalog(ctx)
+
+	return category, nil
+}
+
+// DeleteCategory elimina una categoría (solo admin).
+// No se permite si tiene subcategorías o productos asignados.
+func (s *CategoryService) DeleteCategory(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.categoryRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+
+	all, err := s.categoryRepo.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("error al obtener categorías: %w", err)
+	}
+	for _, c := range all {
+		if c.ParentID != nil && *c.ParentID == id {
+			return errors.New("la categoría tiene subcategorías, muévalas o elimínelas primero")
+		}
+	}
+
+	products, err := s.productRepo.ListAll(ctx)
+	if err != nil {
+		return fmt.Errorf("error al obtener productos: %w", err)
+	}
+	for _, p := range products {
+		if p.CategoryID != nil && *p.CategoryID == id {
+			return errors.New("la categoría tiene productos asignados, reasígnelos primero")
+		}
+	}
+
+	if err := s.categoryRepo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("error al eliminar categoría: %w", err)
+	}
+
+	return nil
+}
+
+// MigrateProductCategories convierte las categorías en texto libre de los productos
+// en documentos de la colección categories. Los nombres que solo difieren en
+// mayúsculas o tildes ("Cafe", "café ") se unifican en una sola categoría.
+// Es idempotente: los productos que ya tienen category_id se omiten.
+func (s *CategoryService) MigrateProductCategories(ctx context.Context) (*models.CategoryMigrationResult, error) {
+	all, err := s.categoryRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener categorías: %w", err)
+	}
+
+	bySlug := make(map[string]*models.Category, len(all))
+	for _, c := range all {
+		bySlug[c.Slug] = c
+	}
+
+	products, err := s.productRepo.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener productos: %w", err)
+	}
+
+	result := &models.CategoryMigrationResult{CategoriesCreated: []models.Category{}}
+	for _, p := range products {
+		name := strings.TrimSpace(p.Category)
+		if p.CategoryID != nil || name == "" {
+			result.ProductsSkipped++
+			continue
+		}
+
+		slug := utils.Slugify(name)
+		category, ok := bySlug[slug]
+		if !ok {
+			category = &models.Category{
+				ID:       uuid.New(),
+				Name:     name,
+				Slug:     slug,
+				IsActive: true,
+			}
+			if err := s.categoryRepo.Create(ctx, category); err != nil {
+				return result, fmt.Errorf("error al crear categoría %q: %w", name, err)
+			}
+			bySlug[slug] = category
+			result.CategoriesCreated = append(result.CategoriesCreated, *category)
+		}
+
+		if err := s.productRepo.SetCategory(ctx, p.ID, category.ID, category.Name); err != nil {
+			return result, fmt.Errorf("error al actualizar producto %s: %w", p.ID, err)
+		}
+		result.ProductsUpdated++
+	}
+
+	if result.ProductsUpdated > 0 {
+		s.searchIndex.Invalidate()
+		s.cache.InvalidateCatalog(ctx)
+	}
+
+	return result, nil
+}
+
+// resolveCategorySlug normaliza el slug pedido (o lo genera desde el nombre) y verifica
+// que sea único. Un slug explícito repetido es un error; uno generado recibe sufijo -2, -3...
+func resolveCategorySlug(all []*models.Category, id uuid.UUID, requested, name string) (string, error) {
+	taken := func(slug string) bool {
+		for _, c := range all {
+			if c.Slug == slug && c.ID != id {
+				return true
+			}
+		}
+		return false
+	}
+
+	if strings.TrimSpace(requested) != "" {
+		slug := utils.Slugify(requested)
+		if slug == "" {
+			return "", errors.New("slug inválido")
+		}
+		if taken(slug) {
+			return "", fmt.Errorf("el slug %s ya está en uso", slug)
+		}
+		return slug, nil
+	}
+
+	base := utils.Slugify(name)
+	if base == "" {
+		return "", errors.New("no se pudo generar un slug a partir del nombre")
+	}
+	slug := base
+	for n := 2; taken(slug); n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	return slug, nil
+}
+
+// validateCategoryParent verifica que el padre exista y que no se formen ciclos
+func validateCategoryParent(all []*models.Category, id uuid.UUID, parentID *uuid.UUID) error {
+	if parentID == nil {
+		return nil
+	}
+
+	byID := make(map[uuid.UUID]*models.Category, len(all))
+	for _, c := range all {
+		byID[c.ID] = c
+	}
+
+	current := *parentID
+	for {
+		if current == id {
+			return errors.New("una categoría no puede ser subcategoría de sí misma ni de sus descendientes")
+		}
+		parent, ok := byID[current]
+		if !ok {
+			return errors.New("categoría padre no encontrada")
+		}
+		if parent.ParentID == nil {
+			return nil
+		}
+		current = *parent.ParentID
+	}
+}
+
+// categoryWithDescendants retorna el ID de la categoría junto con los de todas sus subcategorías
+func categoryWithDescendants(all []*models.Category, root uuid.UUID) map[uuid.UUID]bool {
+	ids := map[uuid.UUID]bool{root: true}
+	for changed := true; changed; {
+		changed = false
+		for _, c := range all {
+			if c.ParentID != nil && ids[*c.ParentID] && !ids[c.ID] {
+				ids[c.ID] = true
+				changed = true
+			}
+		}
+	}
+	return ids
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/cheoscafe/backend/internal/config"
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/cheoscafe/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+// CheckoutService calcula precios, descuento, envío e impuestos de un checkout.
+// Es el único lugar donde se hace esa cuenta: la cotización pública y la creación
+// de la orden pasan por price, así que nunca pueden diferir.
+type CheckoutService struct {
+	productRepo      *repository.ProductRepository
+	orderRepo        *repository.OrderRepository
+	discountService  *DiscountService
+	promotionService *PromotionService
+	cfg              *config.Config
+}
+
+func NewCheckoutService(productRepo *repository.ProductRepository, orderRepo *repository.OrderRepository, discountService *DiscountService, promotionService *PromotionService, cfg *config.Config) *CheckoutService {
+	return &CheckoutService{
+		productRepo:      productRepo,
+		orderRepo:        orderRepo,
+		discountService:  discountService,
+		promotionService: promotionService,
+		cfg:              cfg,
+	}
+}
+
+// pricedCheckout es el resultado de price: el desglose público más lo que
+// necesita la orden para guardarse (items y código de descuento aplicado)
+type pricedCheckout struct {
+	quote        *models.CheckoutQuote
+	items        []models.OrderItem
+	discountCode *models.DiscountCode
+	limits       []*models.CustomerLimit // Productos con límite por cliente; la orden completa cliente y orden
+}
+
+// Quote cotiza un checkout sin reservar stock ni consumir el código de descuento.
+// Los problemas (producto agotado, código inválido...) se informan en la cotización.
+// userID (opcional) y el email del request permiten verificar los límites por cliente.
+func (s *CheckoutService) Quote(ctx context.Context, req *models.CheckoutQuoteRequest, userID *uuid.UUID) (*models.CheckoutQuote, error) {
+	priced, err := s.price(ctx, req, purchaseCustomer{userID: userID, email: req.CustomerEmail})
+	if err != nil {
+		return nil, err
+	}
+	return priced.quote, nil
+}
+
+// price arma el desglose línea por línea con el precio vigente de cada producto o
+// variante. Solo retorna error si falla la lectura; lo que impide comprar queda
+// en quote.Errors (y en la línea correspondiente).
+func (s *CheckoutService) price(ctx context.Context, req *models.CheckoutQuoteRequest, customer purchaseCustomer) (*pricedCheckout, error) {
+	quote := &models.CheckoutQuote{
+		Lines:  make([]models.CheckoutQuoteLine, 0, len(req.Items)),
+		Errors: []string{},
+	}
+	priced := &pricedCheckout{quote: quote}
+	var products []*models.Product // Productos con reglas de compra, en orden de aparición
+	quantities := make(map[uuid.UUID]int)
+	requested := make(map[string]int)         // Unidades pedidas por producto/variante, sumando líneas repetidas
+	var promotionLines []models.PromotionLine // Paralelo a priced.items
+	var lineIndexes []int                     // Línea de la cotización de cada item
+
+	if len(req.Items) == 0 {
+		quote.Errors = append(quote.Errors, "la orden debe tener al menos un producto")
+	}
+
+	for _, itemReq := range req.Items {
+		line := models.CheckoutQuoteLine{
+			ProductID: itemReq.ProductID,
+			VariantID: itemReq.VariantID,
+			Quantity:  itemReq.Quantity,
+		}
+
+		product, err := s.productRepo.GetByID(ctx, itemReq.ProductID)
+		if err != nil {
+			line.Error = fmt.Sprintf("producto %s no encontrado", itemReq.ProductID)
+			addQuoteLine(quote, line)
+			continue
+		}
+		line.ProductName = product.Name
+
+		item, err := resolvePurchasable(product, itemReq.VariantID)
+		if err != nil {
+			line.Error = err.Error()
+			addQuoteLine(quote, line)
+			continue
+		}
+		line.VariantName = item.VariantName
+		line.SKU = item.SKU
+		line.UnitPrice = item.Price
+
+		key := stockKey(product.ID, itemReq.VariantID)
+		requested[key] += itemReq.Quantity
+		if item.Stock < requested[key] {
+			line.Error = fmt.Sprintf("el producto %s no está disponible", displayName(product, item))
+			addQuoteLine(quote, line)
+			continue
+		}
+
+		line.Subtotal = item.Price * float64(itemReq.Quantity)
+		addQuoteLine(quote, line)
+
+		priced.items = append(priced.items, models.OrderItem{
+			ProductID:   product.ID,
+			VariantID:   itemReq.VariantID,
+			VariantName: item.VariantName,
+			SKU:         item.SKU,
+			ProductName: product.Name,
+			Quantity:    itemReq.Quantity,
+			Price:       item.Price,
+			Subtotal:    line.Subtotal,
+			BundleItems: product.BundleItems,
+		})
+		promotionLines = append(promotionLines, models.PromotionLine{
+			ProductID:  product.ID,
+			CategoryID: product.CategoryID,
+			UnitPrice:  item.Price,
+			Quantity:   itemReq.Quantity,
+		})
+		lineIndexes = append(lineIndexes, len(quote.Lines)-1)
+		if product.PurchaseRules != nil {
+			if _, ok := quantities[product.ID]; !ok {
+				products = append(products, product)
+			}
+			quantities[product.ID] += itemReq.Quantity
+		}
+	}
+
+	if len(products) > 0 {
+		purchased, err := purchasedQuantities(ctx, s.orderRepo, customer, products)
+		if err != nil {
+			return nil, err
+		}
+		for _, product := range products {
+			if err := checkPurchaseRules(product, quantities[product.ID], purchased[product.ID]); err != nil {
+				quote.Errors = append(quote.Errors, err.Error())
+			}
+			if product.PurchaseRules.HasCustomerLimit() {
+				priced.limits = append(priced.limits, &models.CustomerLimit{
+					ProductID:      product.ID,
+					ProductName:    product.Name,
+					Quantity:       quantities[product.ID],
+					MaxPerCustomer: product.PurchaseRules.MaxPerCustomer,
+					WindowDays:     product.PurchaseRules.CustomerWindowDays,
+				})
+			}
+		}
+	}
+
+	// Primero las promociones automáticas; el código de descuento se calcula sobre lo que dejan
+	promotions, err := s.promotionService.Evaluate(ctx, promotionLines, req.DiscountCode != "")
+	if err != nil {
+		return nil, fmt.Errorf("error al evaluar promociones: %w", err)
+	}
+	quote.Promotions = promotions.applied
+	quote.PromotionDiscount = promotions.total
+	quote.Discount = promotions.total
+
+	discountLines := make([]models.DiscountLine, len(promotionLines))
+	for i, line := range promotionLines {
+		amount := promotions.lineDiscounts[i]
+		priced.items[i].Discount = amount
+		quote.Lines[lineIndexes[i]].Discount = amount
+		discountLines[i] = models.DiscountLine{
+			ProductID:  line.ProductID,
+			CategoryID: line.CategoryID,
+			Subtotal:   priced.items[i].Subtotal - amount,
+		}
+	}
+
+	// Los códigos con alcance descuentan por línea elegible; el envío gratis descuenta el envío
+	freeShipping := false
+	if req.DiscountCode != "" && s.discountService != nil {
+		quote.DiscountCode = req.DiscountCode
+		validation, err := s.discountService.ValidateDiscountCode(ctx, &models.ValidateDiscountRequest{
+			Code:          req.DiscountCode,
+			PurchaseTotal: quote.Subtotal - quote.PromotionDiscount,
+			Email:         customer.email,
+			UserID:        customer.userID,
+			Lines:         discountLines,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error al validar código de descuento: %w", err)
+		}
+		if validation.Valid {
+			priced.discountCode = validation.DiscountCode
+			freeShipping = validation.FreeShipping
+			if !freeShipping {
+				quote.Discount += validation.DiscountAmount
+				for i, amount := range validation.LineDiscounts {
+					priced.items[i].Discount += amount
+					quote.Lines[lineIndexes[i]].Discount += amount
+				}
+			}
+		} else {
+			quote.Errors = append(quote.Errors, fmt.Sprintf("código de descuento inválido: %s", validation.Message))
+		}
+	}
+
+	if len(priced.items) > 0 {
+		quote.Shipping = s.shippingCost(req.ShippingAddress, quote.Subtotal-quote.Discount)
+	}
+	if freeShipping {
+		quote.Discount += quote.Shipping
+	}
+	quote.Total = quote.Subtotal - quote.Discount + quote.Shipping
+	if rate := float64(s.cfg.TaxRatePercent); rate > 0 {
+		quote.Tax = math.Round(quote.Total - quote.Total/(1+rate/100))
+	}
+	quote.Valid = len(quote.Errors) == 0
+
+	return priced, nil
+}
+
+// shippingCost aplica la tarifa plana de envío, gratis desde FREE_SHIPPING_FROM.
+// La tarifa todavía no depende del destino.
+func (s *CheckoutService) shippingCost(_ *models.ShippingAddress, amount float64) float64 {
+	if s.cfg.FreeShippingFrom > 0 && amount >= float64(s.cfg.FreeShippingFrom) {
+		return 0
+	}
+	return float64(s.cfg.ShippingFlatRate)
+}
+
+// addQuoteLine suma la línea a la cotización; una línea con error se muestra pero no suma
+func addQuoteLine(quote *models.CheckoutQuote, line models.CheckoutQuoteLine) {
+	quote.Lines = append(quote.Lines, line)
+	if line.Error != "" {
+		quote.Errors = append(quote.Errors, line.Error)
+		return
+	}
+	quote.ItemCount += line.Quantity
+	quote.Subtotal += line.Subtotal
+}
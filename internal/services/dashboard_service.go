@@ -5,27 +5,35 @@ import (
 	"fmt"
 	"log"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/cheoscafe/backend/internal/models"
 	"github.com/cheoscafe/backend/internal/repository"
+	"github.com/google/uuid"
 )
 
 type DashboardService struct {
 	dashboardRepo *repository.DashboardRepository
 	orderRepo     *repository.OrderRepository
 	userRepo      *repository.UserRepository
+	discountRepo  *repository.DiscountRepository
+	cache         *CacheService
 }
 
 func NewDashboardService(
 	dashboardRepo *repository.DashboardRepository,
 	orderRepo *repository.OrderRepository,
 	userRepo *repository.UserRepository,
+	discountRepo *repository.DiscountRepository,
+	cache *CacheService,
 ) *DashboardService {
 	return &DashboardService{
 		dashboardRepo: dashboardRepo,
 		orderRepo:     orderRepo,
 		userRepo:      userRepo,
+		discountRepo:  discountRepo,
+		cache:         cache,
 	}
 }
 
@@ -134,6 +142,7 @@ func sortAndSliceTopProducts(metrics *models.TopProductsMetrics) {
 			TotalQuantity: p.Quantity,
 			TotalRevenue:  p.Revenue,
 			OrderCount:    p.Orders,
+			Variants:      p.Variants,
 		})
 	}
 
@@ -158,6 +167,33 @@ func sortAndSliceTopProducts(metrics *models.TopProductsMetrics) {
 	}
 }
 
+// accumulateProductStats suma un item vendido al mapa all_products,
+// desglosando por variante cuando el item tiene una.
+func accumulateProductStats(metrics *models.TopProductsMetrics, item *models.OrderItem) {
+	pid := item.ProductID.String()
+	p := metrics.AllProducts[pid]
+	p.Name = item.ProductName
+	p.Quantity += item.Quantity
+	p.Revenue += item.Subtotal
+	p.Orders++
+
+	if item.VariantID != nil {
+		if p.Variants == nil {
+			p.Variants = make(map[string]models.VariantStatsMap)
+		}
+		vid := item.VariantID.String()
+		v := p.Variants[vid]
+		v.Name = item.VariantName
+		v.SKU = item.SKU
+		v.Quantity += item.Quantity
+		v.Revenue += item.Subtotal
+		v.Orders++
+		p.Variants[vid] = v
+	}
+
+	metrics.AllProducts[pid] = p
+}
+
 func recalculateAverageTicket(totalRevenue float64, completedOrders int) float64 {
 	if completedOrders > 0 {
 		return totalRevenue / float64(completedOrders)
@@ -173,6 +209,8 @@ func recalculateAverageTicket(totalRevenue float64, completedOrders int) float64
 // SOLO incrementa PendingOrders y registra el comprador.
 // El revenue y productos se cuentan en OnOrderStatusChanged cuando llega a DELIVERED.
 func (s *DashboardService) OnOrderCreated(ctx context.Context, order *models.Order, items []models.OrderItem) error {
+	defer s.cache.Delete(ctx, cacheKeyDashboardSummary)
+
 	year := order.CreatedAt.Year()
 	month := int(order.CreatedAt.Month())
 
@@ -186,6 +224,9 @@ func (s *DashboardService) OnOrderCreated(ctx context.Context, order *models.Ord
 	}
 
 	salesMonthly.PendingOrders++
+	if order.RecoveredCart {
+		salesMonthly.RecoveredOrders++
+	}
 	salesMonthly.UpdatedAt = time.Now()
 
 	if err := s.dashboardRepo.SaveSalesMonthly(ctx, year, month, salesMonthly); err != nil {
@@ -304,6 +345,30 @@ func (s *DashboardService) OnOrderCreated(ctx context.Context, order *models.Ord
 	return nil
 }
 
+// OnCartReminderSent cuenta un recordatorio de carrito abandonado en el mes de envío
+func (s *DashboardService) OnCartReminderSent(ctx context.Context, sentAt time.Time) error {
+	defer s.cache.Delete(ctx, cacheKeyDashboardSummary)
+
+	year := sentAt.Year()
+	month := int(sentAt.Month())
+
+	salesMonthly, err := s.dashboardRepo.GetSalesMonthly(ctx, year, month)
+	if err != nil {
+		return fmt.Errorf("error obteniendo sales_monthly: %w", err)
+	}
+	if salesMonthly == nil {
+		salesMonthly = newSalesMonthlyMetrics(year, month)
+	}
+
+	salesMonthly.CartRemindersSent++
+	salesMonthly.UpdatedAt = time.Now()
+
+	if err := s.dashboardRepo.SaveSalesMonthly(ctx, year, month, salesMonthly); err != nil {
+		return fmt.Errorf("error guardando sales_monthly: %w", err)
+	}
+	return nil
+}
+
 // OnOrderStatusChanged se llama cuando un admin cambia el estado de una orden.
 //
 // Lógica de revenue:
@@ -311,6 +376,8 @@ func (s *DashboardService) OnOrderCreated(ctx context.Context, order *models.Ord
 //   - CANCELLED  → restar pending. Si venía de DELIVERED, restar revenue también
 //   - Otros cambios dentro de "pending" → solo mover contadores de estado
 func (s *DashboardService) OnOrderStatusChanged(ctx context.Context, order *models.Order, oldStatus, newStatus models.OrderStatus) error {
+	defer s.cache.Delete(ctx, cacheKeyDashboardSummary)
+
 	year := order.CreatedAt.Year()
 	month := int(order.CreatedAt.Month())
 	dayKey := order.CreatedAt.Format("2006-01-02")
@@ -377,11 +444,18 @@ func (s *DashboardService) OnOrderStatusChanged(ctx context.Context, order *mode
 		daily.Revenue += order.Total
 		daily.Orders++
 		salesMonthly.DailyBreakdown[dayKey] = daily
+
+		if order.RecoveredCart {
+			salesMonthly.RecoveredRevenue += order.Total
+		}
 	}
 
 	// CANCELLED desde DELIVERED → restar revenue (devolución)
 	if newStatus == models.OrderCancelled && oldStatus == models.OrderDelivered {
 		salesMonthly.TotalRevenue -= order.Total
+		if order.RecoveredCart {
+			salesMonthly.RecoveredRevenue -= order.Total
+		}
 		if salesMonthly.TotalOrders > 0 {
 			salesMonthly.TotalOrders--
 		}
@@ -489,13 +563,7 @@ func (s *DashboardService) OnOrderStatusChanged(ctx context.Context, order *mode
 				topMonthly = newTopProductsMetrics(year, month)
 			}
 			for _, item := range items {
-				pid := item.ProductID.String()
-				p := topMonthly.AllProducts[pid]
-				p.Name = item.ProductName
-				p.Quantity += item.Quantity
-				p.Revenue += item.Subtotal
-				p.Orders++
-				topMonthly.AllProducts[pid] = p
+				accumulateProductStats(topMonthly, item)
 			}
 			sortAndSliceTopProducts(topMonthly)
 			topMonthly.UpdatedAt = time.Now()
@@ -512,13 +580,7 @@ func (s *DashboardService) OnOrderStatusChanged(ctx context.Context, order *mode
 				topYearly = newTopProductsMetrics(year, 0)
 			}
 			for _, item := range items {
-				pid := item.ProductID.String()
-				p := topYearly.AllProducts[pid]
-				p.Name = item.ProductName
-				p.Quantity += item.Quantity
-				p.Revenue += item.Subtotal
-				p.Orders++
-				topYearly.AllProducts[pid] = p
+				accumulateProductStats(topYearly, item)
 			}
 			sortAndSliceTopProducts(topYearly)
 			topYearly.UpdatedAt = time.Now()
@@ -601,7 +663,14 @@ func (s *DashboardService) GetTopProductsYearly(ctx context.Context, year int) (
 	return metrics, nil
 }
 
+// GetSummary arma el resumen del mes y año en curso. Se cachea por poco tiempo
+// (CACHE_DASHBOARD_TTL_SECONDS) y se invalida con cada cambio en las métricas.
 func (s *DashboardService) GetSummary(ctx context.Context) (*models.DashboardSummary, error) {
+	var cached models.DashboardSummary
+	if s.cache.Get(ctx, cacheKeyDashboardSummary, &cached) {
+		return &cached, nil
+	}
+
 	now := time.Now()
 	year := now.Year()
 	month := int(now.Month())
@@ -641,6 +710,9 @@ func (s *DashboardService) GetSummary(ctx context.Context) (*models.DashboardSum
 			Orders:        salesMonthly.TotalOrders,
 			AverageTicket: salesMonthly.AverageTicket,
 			NewBuyers:     buyersMonthly.NewRegisteredThisMonth + buyersMonthly.GuestBuyers,
+
+			CartRemindersSent: salesMonthly.CartRemindersSent,
+			RecoveredOrders:   salesMonthly.RecoveredOrders,
 		},
 		CurrentYear: models.DashboardSummaryCurrentYear{
 			Revenue: salesYearly.TotalRevenue,
@@ -650,6 +722,7 @@ func (s *DashboardService) GetSummary(ctx context.Context) (*models.DashboardSum
 		GenderBreakdown: buyersMonthly.GenderBreakdown,
 		AgeBreakdown:    buyersMonthly.AgeBreakdown,
 	}
+	s.cache.Set(ctx, cacheKeyDashboardSummary, summary, s.cache.DashboardTTL)
 
 	return summary, nil
 }
@@ -659,6 +732,8 @@ func (s *DashboardService) GetSummary(ctx context.Context) (*models.DashboardSum
 // ============================================================
 
 func (s *DashboardService) RecalculateMonth(ctx context.Context, year, month int) error {
+	defer s.cache.Delete(ctx, cacheKeyDashboardSummary)
+
 	startOfMonth := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
 	endOfMonth := startOfMonth.AddDate(0, 1, 0)
 	startOfYear := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
@@ -675,6 +750,15 @@ func (s *DashboardService) RecalculateMonth(ctx context.Context, year, month int
 
 	salesMonthly := newSalesMonthlyMetrics(year, month)
 	buyersMonthly := newBuyersMonthlyMetrics(year, month)
+
+	// Los recordatorios enviados no salen de las órdenes: se conservan
+	previousSales, err := s.dashboardRepo.GetSalesMonthly(ctx, year, month)
+	if err != nil {
+		return fmt.Errorf("error obteniendo sales_monthly: %w", err)
+	}
+	if previousSales != nil {
+		salesMonthly.CartRemindersSent = previousSales.CartRemindersSent
+	}
 	topProductsMonthly := newTopProductsMetrics(year, month)
 
 	salesYearly := newSalesYearlyMetrics(year)
@@ -760,6 +844,13 @@ func (s *DashboardService) recalcProcessOrder(
 		sales.CancelledOrders++
 	}
 
+	if order.RecoveredCart {
+		sales.RecoveredOrders++
+		if isDelivered {
+			sales.RecoveredRevenue += order.Total
+		}
+	}
+
 	if isDelivered {
 		sales.TotalRevenue += order.Total
 		sales.TotalOrders++
@@ -781,13 +872,7 @@ func (s *DashboardService) recalcProcessOrder(
 		sales.DailyBreakdown[dayKey] = daily
 
 		for _, item := range items {
-			pid := item.ProductID.String()
-			p := topProducts.AllProducts[pid]
-			p.Name = item.ProductName
-			p.Quantity += item.Quantity
-			p.Revenue += item.Subtotal
-			p.Orders++
-			topProducts.AllProducts[pid] = p
+			accumulateProductStats(topProducts, item)
 		}
 	}
 
@@ -866,13 +951,7 @@ func (s *DashboardService) recalcProcessOrderYearly(
 		sales.MonthlyBreakdown[monthKey] = mb
 
 		for _, item := range items {
-			pid := item.ProductID.String()
-			p := topProducts.AllProducts[pid]
-			p.Name = item.ProductName
-			p.Quantity += item.Quantity
-			p.Revenue += item.Subtotal
-			p.Orders++
-			topProducts.AllProducts[pid] = p
+			accumulateProductStats(topProducts, item)
 		}
 	}
 
@@ -912,3 +991,230 @@ func (s *DashboardService) recalcProcessOrderYearly(
 		}
 	}
 }
+
+// ============================================================
+// Reporte de Descuentos y Campañas
+// ============================================================
+
+// discountAccumulator acumula el rendimiento de un código o campaña
+type discountAccumulator struct {
+	performance models.DiscountPerformance
+	customers   map[string]bool // Cliente -> alguna de sus órdenes fue su primera compra
+}
+
+func (a *discountAccumulator) add(order *models.Order, customer string, firstPurchase bool) {
+	if order.Status == models.OrderCancelled {
+		a.performance.CancelledOrders++
+		return
+	}
+
+	a.performance.Orders++
+	if order.Status == models.OrderDelivered {
+		a.performance.CompletedOrders++
+	}
+	a.performance.GrossRevenue += order.Subtotal
+	a.performance.NetRevenue += order.Total
+	if order.DiscountCodeID != nil {
+		a.performance.DiscountCost += order.Discount - order.PromotionDiscount
+	}
+
+	if a.customers == nil {
+		a.customers = make(map[string]bool)
+	}
+	a.customers[customer] = a.customers[customer] || firstPurchase
+}
+
+func (a *discountAccumulator) result() models.DiscountPerformance {
+	performance := a.performance
+	performance.AverageTicket = recalculateAverageTicket(performance.NetRevenue, performance.Orders)
+	for _, isNew := range a.customers {
+		if isNew {
+			performance.NewCustomers++
+		} else {
+			performance.ReturningCustomers++
+		}
+	}
+	return performance
+}
+
+// GetDiscountReport calcula el rendimiento por código y por campaña de las órdenes
+// creadas entre from y to (ambos días incluidos). La campaña de una orden es la del
+// código usado; si el código no tiene campaña, su utm_campaign.
+func (s *DashboardService) GetDiscountReport(ctx context.Context, from, to time.Time) (*models.DiscountReport, error) {
+	end := to.AddDate(0, 0, 1)
+
+	orders, err := s.orderRepo.GetOrdersByDateRange(ctx, from, end)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo ordenes: %w", err)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.Before(orders[j].CreatedAt)
+	})
+
+	// Igual que los límites por cliente: se reconoce al cliente por usuario y por email
+	customerKeys := func(order *models.Order) []string {
+		var keys []string
+		if order.UserID != nil {
+			keys = append(keys, "user:"+order.UserID.String())
+		}
+		if email := strings.ToLower(strings.TrimSpace(order.CustomerEmail)); email != "" {
+			keys = append(keys, "email:"+email)
+		}
+		return keys
+	}
+
+	// Para saber si cada compra fue la primera del cliente solo se leen las órdenes
+	// anteriores de los clientes que aparecen en el rango
+	var userIDs []uuid.UUID
+	var emails []string
+	listed := make(map[string]bool)
+	for _, order := range orders {
+		if order.UserID != nil && !listed["user:"+order.UserID.String()] {
+			listed["user:"+order.UserID.String()] = true
+			userIDs = append(userIDs, *order.UserID)
+		}
+		if email := strings.ToLower(strings.TrimSpace(order.CustomerEmail)); email != "" && !listed["email:"+email] {
+			listed["email:"+email] = true
+			emails = append(emails, email)
+		}
+	}
+	previous, err := s.orderRepo.ListByCustomersBefore(ctx, userIDs, emails, from)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo ordenes anteriores: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, order := range previous {
+		for _, key := range customerKeys(order) {
+			seen[key] = true
+		}
+	}
+	firstPurchase := make(map[uuid.UUID]bool)
+	for _, order := range orders {
+		if order.Status == models.OrderCancelled {
+			continue
+		}
+		first := true
+		for _, key := range customerKeys(order) {
+			if seen[key] {
+				first = false
+			}
+			seen[key] = true
+		}
+		firstPurchase[order.ID] = first
+	}
+
+	// Los códigos usados en el rango se leen de una sola vez
+	codeIDs := make([]uuid.UUID, 0)
+	listedCodes := make(map[uuid.UUID]bool)
+	for _, order := range orders {
+		if order.DiscountCodeID != nil && !listedCodes[*order.DiscountCodeID] {
+			listedCodes[*order.DiscountCodeID] = true
+			codeIDs = append(codeIDs, *order.DiscountCodeID)
+		}
+	}
+	discounts, err := s.discountRepo.GetByIDs(ctx, codeIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo códigos de descuento: %w", err)
+	}
+	discountsByID := make(map[uuid.UUID]*models.DiscountCode, len(discounts))
+	for _, discount := range discounts {
+		discountsByID[discount.ID] = discount
+	}
+
+	var totals discountAccumulator
+	codeStats := make(map[uuid.UUID]*discountAccumulator)
+	codeReports := make(map[uuid.UUID]*models.DiscountCodeReport)
+	campaignStats := make(map[string]*discountAccumulator)
+	campaignReports := make(map[string]*models.CampaignReport)
+
+	for _, order := range orders {
+		var customer string
+		if order.UserID != nil {
+			customer = order.UserID.String()
+		} else {
+			customer = strings.ToLower(order.CustomerEmail)
+		}
+		first := firstPurchase[order.ID]
+
+		campaign := order.UTMCampaign
+		code := ""
+		if order.DiscountCodeID != nil {
+			report, ok := codeReports[*order.DiscountCodeID]
+			if !ok {
+				report = &models.DiscountCodeReport{
+					DiscountCodeID: *order.DiscountCodeID,
+					UTMCampaigns:   make(map[string]int),
+				}
+				// Un código eliminado después se reporta solo con su ID
+				if discount, ok := discountsByID[*order.DiscountCodeID]; ok {
+					report.Code = discount.Code
+					report.Type = discount.Type
+					report.CampaignID = discount.CampaignID
+				}
+				codeReports[*order.DiscountCodeID] = report
+				codeStats[*order.DiscountCodeID] = &discountAccumulator{}
+			}
+
+			codeStats[*order.DiscountCodeID].add(order, customer, first)
+			totals.add(order, customer, first)
+			if order.Status != models.OrderCancelled && order.UTMCampaign != "" {
+				report.UTMCampaigns[order.UTMCampaign]++
+			}
+
+			code = report.Code
+			if report.CampaignID != "" {
+				campaign = report.CampaignID
+			}
+		}
+
+		if campaign == "" {
+			continue
+		}
+		report, ok := campaignReports[campaign]
+		if !ok {
+			report = &models.CampaignReport{CampaignID: campaign, Codes: []string{}}
+			campaignReports[campaign] = report
+			campaignStats[campaign] = &discountAccumulator{}
+		}
+		campaignStats[campaign].add(order, customer, first)
+		if order.DiscountCodeID != nil && order.Status != models.OrderCancelled {
+			report.OrdersWithCode++
+		}
+		if code != "" && !containsString(report.Codes, code) {
+			report.Codes = append(report.Codes, code)
+		}
+	}
+
+	result := &models.DiscountReport{
+		From:      from,
+		To:        to,
+		Totals:    totals.result(),
+		Codes:     make([]models.DiscountCodeReport, 0, len(codeReports)),
+		Campaigns: make([]models.CampaignReport, 0, len(campaignReports)),
+	}
+	for id, report := range codeReports {
+		report.DiscountPerformance = codeStats[id].result()
+		result.Codes = append(result.Codes, *report)
+	}
+	for campaign, report := range campaignReports {
+		report.DiscountPerformance = campaignStats[campaign].result()
+		sort.Strings(report.Codes)
+		result.Campaigns = append(result.Campaigns, *report)
+	}
+
+	sort.Slice(result.Codes, func(i, j int) bool {
+		if result.Codes[i].NetRevenue != result.Codes[j].NetRevenue {
+			return result.Codes[i].NetRevenue > result.Codes[j].NetRevenue
+		}
+		return result.Codes[i].Code < result.Codes[j].Code
+	})
+	sort.Slice(result.Campaigns, func(i, j int) bool {
+		if result.Campaigns[i].NetRevenue != result.Campaigns[j].NetRevenue {
+			return result.Campaigns[i].NetRevenue > result.Campaigns[j].NetRevenue
+		}
+		return result.Campaigns[i].CampaignID < result.Campaigns[j].CampaignID
+	})
+
+	return result, nil
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	discountBatchCodeLength = 8
+	discountBatchAlphabet   = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789" // No 0/O or 1/I, so codes can be read aloud
+)
+
+// GenerateDiscountBatch creates req.Quantity single-use codes PREFIX-XXXXXXXX that share
+// the request rules, linked to a new batch for the campaign.
+func (s *DiscountService) GenerateDiscountBatch(ctx context.Context, req *models.GenerateDiscountBatchRequest) (*models.DiscountBatch, error) {
+	if req.EndDate.Before(req.StartDate) {
+		return nil, errors.New("end date must be after start date")
+	}
+	if req.Type == models.DiscountPercentage && req.Value > 100 {
+		return nil, errors.New("percentage discount cannot be greater than 100%")
+	}
+
+	length := req.Length
+	if length == 0 {
+		length = discountBatchCodeLength
+	}
+
+	discountBatch := &models.DiscountBatch{
+		ID:             uuid.New(),
+		CampaignID:     strings.TrimSpace(req.CampaignID),
+		Prefix:         strings.ToUpper(req.Prefix),
+		Length:         length,
+		Quantity:       req.Quantity,
+		Description:    req.Description,
+		Type:           req.Type,
+		Value:          req.Value,
+		MinPurchase:    req.MinPurchase,
+		MaxDiscount:    req.MaxDiscount,
+		ProductIDs:     req.ProductIDs,
+		CategoryIDs:    req.CategoryIDs,
+		FirstOrderOnly: req.FirstOrderOnly,
+		StartDate:      req.StartDate,
+		EndDate:        req.EndDate,
+		IsActive:       req.IsActive,
+	}
+
+	maxUses := 1
+	template := models.DiscountCode{
+		Description:    discountBatch.Description,
+		Type:           discountBatch.Type,
+		Value:          discountBatch.Value,
+		MinPurchase:    discountBatch.MinPurchase,
+		MaxUses:        &maxUses,
+		MaxDiscount:    discountBatch.MaxDiscount,
+		ProductIDs:     discountBatch.ProductIDs,
+		CategoryIDs:    discountBatch.CategoryIDs,
+		FirstOrderOnly: discountBatch.FirstOrderOnly,
+		BatchID:        &discountBatch.ID,
+		CampaignID:     discountBatch.CampaignID,
+		StartDate:      discountBatch.StartDate,
+		EndDate:        discountBatch.EndDate,
+		IsActive:       discountBatch.IsActive,
+	}
+	if err := s.validateRules(ctx, &template); err != nil {
+		return nil, err
+	}
+
+	prefix := discountBatch.Prefix + "-"
+	taken, err := s.discountRepo.GetCodesWithPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	discounts := make([]*models.DiscountCode, 0, req.Quantity)
+	for attempts := 0; len(discounts) < req.Quantity; attempts++ {
+		if attempts >= req.Quantity*10 {
+			return nil, errors.New("could not generate enough unique codes, use a longer length")
+		}
+
+		suffix, err := randomCode(length)
+		if err != nil {
+			return nil, err
+		}
+		code := prefix + suffix
+		if taken[code] {
+			continue
+		}
+		taken[code] = true
+
+		discount := template
+		discount.ID = uuid.New()
+		discount.Code = code
+		discounts = append(discounts, &discount)
+	}
+
+	if err := s.discountRepo.CreateBatch(ctx, discountBatch, discounts); err != nil {
+		return nil, err
+	}
+
+	return discountBatch, nil
+}
+
+// randomCode returns n random characters of discountBatchAlphabet
+func randomCode(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = discountBatchAlphabet[int(b[i])%len(discountBatchAlphabet)] // 256 is a multiple of 32: no bias
+	}
+	return string(b), nil
+}
+
+// GetDiscountBatch gets a batch with its redemption stats
+func (s *DiscountService) GetDiscountBatch(ctx context.Context, id uuid.UUID) (*models.DiscountBatchResponse, error) {
+	discountBatch, err := s.discountRepo.GetBatchByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.batchStats(ctx, discountBatch.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.DiscountBatchResponse{Batch: discountBatch, Stats: stats}, nil
+}
+
+// GetAllDiscountBatches gets every batch with its redemption stats, newest first
+func (s *DiscountService) GetAllDiscountBatches(ctx context.Context) (*models.DiscountBatchListResponse, error) {
+	batches, err := s.discountRepo.GetAllBatches(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.DiscountBatchListResponse{
+		Batches: make([]models.DiscountBatchResponse, 0, len(batches)),
+		Total:   len(batches),
+	}
+	for _, discountBatch := range batches {
+		stats, err := s.batchStats(ctx, discountBatch.ID)
+		if err != nil {
+			return nil, err
+		}
+		response.Batches = append(response.Batches, models.DiscountBatchResponse{Batch: discountBatch, Stats: stats})
+	}
+
+	return response, nil
+}
+
+func (s *DiscountService) batchStats(ctx context.Context, batchID uuid.UUID) (models.DiscountBatchStats, error) {
+	var stats models.DiscountBatchStats
+
+	discounts, err := s.discountRepo.GetCodesByBatch(ctx, batchID)
+	if err != nil {
+		return stats, err
+	}
+	redemptions, err := s.discountRepo.GetRedemptionsByBatch(ctx, batchID)
+	if err != nil {
+		return stats, err
+	}
+
+	stats.Codes = len(discounts)
+	for _, discount := range discounts {
+		if discount.IsActive {
+			stats.ActiveCodes++
+		}
+	}
+
+	redeemed := make(map[uuid.UUID]bool)
+	for _, redemption := range redemptions {
+		if redemption.ReleasedAt != nil {
+			stats.ReleasedRedemptions++
+			continue
+		}
+		stats.Redemptions++
+		stats.DiscountGiven += redemption.Amount
+		redeemed[redemption.DiscountCodeID] = true
+	}
+	stats.RedeemedCodes = len(redeemed)
+	if stats.Codes > 0 {
+		stats.RedemptionRate = math.Round(float64(stats.RedeemedCodes)/float64(stats.Codes)*10000) / 100
+	}
+
+	return stats, nil
+}
+
+// ExportDiscountBatchCSV writes one row per code of the batch with its active redemption, if any
+func (s *DiscountService) ExportDiscountBatchCSV(ctx context.Context, id uuid.UUID, w io.Writer) (*models.DiscountBatch, error) {
+	discountBatch, err := s.discountRepo.GetBatchByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	discounts, err := s.discountRepo.GetCodesByBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	redemptions, err := s.discountRepo.GetRedemptionsByBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	byCode := make(map[uuid.UUID]*models.DiscountRedemption, len(redemptions))
+	for _, redemption := range redemptions {
+		if redemption.ReleasedAt == nil {
+			byCode[redemption.DiscountCodeID] = redemption
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"code", "campaign_id", "is_active", "redeemed", "order_id", "email", "amount", "redeemed_at"}); err != nil {
+		return nil, err
+	}
+
+	for _, discount := range discounts {
+		record := []string{
+			discount.Code,
+			discount.CampaignID,
+			strconv.FormatBool(discount.IsActive),
+			"false", "", "", "", "",
+		}
+		if redemption, ok := byCode[discount.ID]; ok {
+			record[3] = "true"
+			record[4] = redemption.OrderID.String()
+			record[5] = redemption.Email
+			record[6] = strconv.FormatFloat(redemption.Amount, 'f', -1, 64)
+			record[7] = redemption.RedeemedAt.Format(time.RFC3339)
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return discountBatch, nil
+}
+
+// DeactivateDiscountBatch deactivates a batch and all its codes at once
+func (s *DiscountService) DeactivateDiscountBatch(ctx context.Context, id uuid.UUID) (*models.DiscountBatch, error) {
+	discountBatch, err := s.discountRepo.GetBatchByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.discountRepo.DeactivateBatch(ctx, id); err != nil {
+		return nil, fmt.Errorf("error deactivating batch codes: %w", err)
+	}
+
+	discountBatch.IsActive = false
+	discountBatch.UpdatedAt = time.Now()
+	return discountBatch, nil
+}
@@ -3,7 +3,9 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
+	"strings"
 	"time"
 
 	"github.com/cheoscafe/backend/internal/models"
@@ -13,11 +15,15 @@ import (
 
 type DiscountService struct {
 	discountRepo *repository.DiscountRepository
+	orderRepo    *repository.OrderRepository
+	categoryRepo *repository.CategoryRepository
 }
 
-func NewDiscountService(discountRepo *repository.DiscountRepository) *DiscountService {
+func NewDiscountService(discountRepo *repository.DiscountRepository, orderRepo *repository.OrderRepository, categoryRepo *repository.CategoryRepository) *DiscountService {
 	return &DiscountService{
 		discountRepo: discountRepo,
+		orderRepo:    orderRepo,
+		categoryRepo: categoryRepo,
 	}
 }
 
@@ -42,16 +48,26 @@ func (s *DiscountService) CreateDiscountCode(ctx context.Context, req *models.Cr
 	}
 
 	discount := &models.DiscountCode{
-		Code:        req.Code,
-		Description: req.Description,
-		Type:        req.Type,
-		Value:       req.Value,
-		MinPurchase: req.MinPurchase,
-		MaxUses:     req.MaxUses,
-		UsedCount:   0,
-		StartDate:   req.StartDate,
-		EndDate:     req.EndDate,
-		IsActive:    req.IsActive,
+		Code:               req.Code,
+		Description:        req.Description,
+		Type:               req.Type,
+		Value:              req.Value,
+		MinPurchase:        req.MinPurchase,
+		MaxUses:            req.MaxUses,
+		MaxUsesPerCustomer: req.MaxUsesPerCustomer,
+		MaxDiscount:        req.MaxDiscount,
+		ProductIDs:         req.ProductIDs,
+		CategoryIDs:        req.CategoryIDs,
+		FirstOrderOnly:     req.FirstOrderOnly,
+		CampaignID:         strings.TrimSpace(req.CampaignID),
+		UsedCount:          0,
+		StartDate:          req.StartDate,
+		EndDate:            req.EndDate,
+		IsActive:           req.IsActive,
+	}
+
+	if err := s.validateRules(ctx, discount); err != nil {
+		return nil, err
 	}
 
 	if err := s.discountRepo.Create(ctx, discount); err != nil {
@@ -145,6 +161,34 @@ func (s *DiscountService) UpdateDiscountCode(ctx context.Context, id uuid.UUID,
 		discount.MaxUses = req.MaxUses
 	}
 
+	if req.MaxUsesPerCustomer != nil {
+		discount.MaxUsesPerCustomer = req.MaxUsesPerCustomer
+	}
+
+	if req.MaxDiscount != nil {
+		if *req.MaxDiscount == 0 {
+			discount.MaxDiscount = nil
+		} else {
+			discount.MaxDiscount = req.MaxDiscount
+		}
+	}
+
+	if req.ProductIDs != nil {
+		discount.ProductIDs = req.ProductIDs
+	}
+
+	if req.CategoryIDs != nil {
+		discount.CategoryIDs = req.CategoryIDs
+	}
+
+	if req.FirstOrderOnly != nil {
+		discount.FirstOrderOnly = *req.FirstOrderOnly
+	}
+
+	if req.CampaignID != nil {
+		discount.CampaignID = strings.TrimSpace(*req.CampaignID)
+	}
+
 	if !req.StartDate.IsZero() {
 		discount.StartDate = req.StartDate
 	}
@@ -162,6 +206,10 @@ func (s *DiscountService) UpdateDiscountCode(ctx context.Context, id uuid.UUID,
 		return nil, errors.New("end date must be after start date")
 	}
 
+	if err := s.validateRules(ctx, discount); err != nil {
+		return nil, err
+	}
+
 	if err := s.discountRepo.Update(ctx, discount); err != nil {
 		return nil, err
 	}
@@ -169,6 +217,34 @@ func (s *DiscountService) UpdateDiscountCode(ctx context.Context, id uuid.UUID,
 	return discount, nil
 }
 
+// validateRules checks the type-specific settings and the scope of a code
+func (s *DiscountService) validateRules(ctx context.Context, discount *models.DiscountCode) error {
+	if discount.MaxDiscount != nil && discount.Type != models.DiscountPercentage {
+		return errors.New("max discount only applies to percentage codes")
+	}
+	if discount.Type != models.DiscountFreeShipping && discount.Value <= 0 {
+		return errors.New("discount value must be greater than 0")
+	}
+
+	if len(discount.CategoryIDs) > 0 {
+		categories, err := s.categoryRepo.GetAll(ctx)
+		if err != nil {
+			return err
+		}
+		known := make(map[uuid.UUID]bool, len(categories))
+		for _, c := range categories {
+			known[c.ID] = true
+		}
+		for _, id := range discount.CategoryIDs {
+			if !known[id] {
+				return fmt.Errorf("category %s not found", id)
+			}
+		}
+	}
+
+	return nil
+}
+
 // DeleteDiscountCode deletes a discount code (soft delete)
 func (s *DiscountService) DeleteDiscountCode(ctx context.Context, id uuid.UUID) error {
 	return s.discountRepo.Delete(ctx, id)
@@ -227,6 +303,46 @@ func (s *DiscountService) ValidateDiscountCode(ctx context.Context, req *models.
 		}, nil
 	}
 
+	// Validate per-customer limit (only when the customer is known)
+	if discount.MaxUsesPerCustomer != nil && (req.UserID != nil || req.Email != "") {
+		used, err := s.discountRepo.CountCustomerRedemptions(ctx, discount.ID, req.UserID, req.Email)
+		if err != nil {
+			return nil, err
+		}
+		if used >= *discount.MaxUsesPerCustomer {
+			return &models.ValidateDiscountResponse{
+				Valid:          false,
+				Message:        "Discount code already used by this customer",
+				DiscountAmount: 0,
+				DiscountCode:   discount,
+			}, nil
+		}
+	}
+
+	// Validate first order (at checkout the customer is always known by the order email)
+	if discount.FirstOrderOnly {
+		if req.UserID == nil && req.Email == "" {
+			return &models.ValidateDiscountResponse{
+				Valid:          false,
+				Message:        "Discount code is only valid on a first order; sign in or provide an email",
+				DiscountAmount: 0,
+				DiscountCode:   discount,
+			}, nil
+		}
+		orders, err := s.orderRepo.ListByCustomerSince(ctx, req.UserID, req.Email, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		if len(orders) > 0 {
+			return &models.ValidateDiscountResponse{
+				Valid:          false,
+				Message:        "Discount code is only valid on a first order",
+				DiscountAmount: 0,
+				DiscountCode:   discount,
+			}, nil
+		}
+	}
+
 	// Validate minimum purchase
 	if discount.MinPurchase != nil && req.PurchaseTotal < *discount.MinPurchase {
 		return &models.ValidateDiscountResponse{
@@ -237,28 +353,148 @@ func (s *DiscountService) ValidateDiscountCode(ctx context.Context, req *models.
 		}, nil
 	}
 
+	// Free shipping: checkout waives the shipping cost, so there's no amount off the products
+	if discount.Type == models.DiscountFreeShipping {
+		return &models.ValidateDiscountResponse{
+			Valid:          true,
+			Message:        "Discount code is valid: free shipping",
+			DiscountAmount: 0,
+			FreeShipping:   true,
+			DiscountCode:   discount,
+		}, nil
+	}
+
+	// Eligible amount: the whole purchase, or only the lines in the code's scope
+	eligibleTotal := req.PurchaseTotal
+	var eligible []bool
+	if discount.IsScoped() {
+		if len(req.Lines) == 0 {
+			// Without the lines the amount can only be calculated at checkout
+			return &models.ValidateDiscountResponse{
+				Valid:          true,
+				Message:        "Discount code is valid on selected products; the amount is calculated at checkout",
+				DiscountAmount: 0,
+				DiscountCode:   discount,
+			}, nil
+		}
+
+		eligible, err = s.eligibleLines(ctx, discount, req.Lines)
+		if err != nil {
+			return nil, err
+		}
+		eligibleTotal = 0
+		for i, line := range req.Lines {
+			if eligible[i] {
+				eligibleTotal += line.Subtotal
+			}
+		}
+		if eligibleTotal == 0 {
+			return &models.ValidateDiscountResponse{
+				Valid:          false,
+				Message:        "Discount code does not apply to any product in the cart",
+				DiscountAmount: 0,
+				DiscountCode:   discount,
+			}, nil
+		}
+	}
+
 	// Calculate discount
 	var discountAmount float64
 	if discount.Type == models.DiscountPercentage {
-		discountAmount = req.PurchaseTotal * (discount.Value / 100)
+		discountAmount = eligibleTotal * (discount.Value / 100)
+		if discount.MaxDiscount != nil && discountAmount > *discount.MaxDiscount {
+			discountAmount = *discount.MaxDiscount
+		}
 	} else {
 		discountAmount = discount.Value
 	}
 
-	// Don't allow discount greater than total
-	if discountAmount > req.PurchaseTotal {
-		discountAmount = req.PurchaseTotal
+	// Don't allow discount greater than the eligible amount
+	if discountAmount > eligibleTotal {
+		discountAmount = eligibleTotal
 	}
 
 	return &models.ValidateDiscountResponse{
 		Valid:          true,
 		Message:        "Discount code is valid",
 		DiscountAmount: discountAmount,
+		LineDiscounts:  splitDiscount(discountAmount, req.Lines, eligible),
 		DiscountCode:   discount,
 	}, nil
 }
 
-// ApplyDiscountCode marks a code as used (increments counter)
-func (s *DiscountService) ApplyDiscountCode(ctx context.Context, id uuid.UUID) error {
-	return s.discountRepo.IncrementUsedCount(ctx, id)
+// eligibleLines marks the lines in the code's scope: a listed product or a product
+// in one of the listed categories (or their subcategories)
+func (s *DiscountService) eligibleLines(ctx context.Context, discount *models.DiscountCode, lines []models.DiscountLine) ([]bool, error) {
+	products := make(map[uuid.UUID]bool, len(discount.ProductIDs))
+	for _, id := range discount.ProductIDs {
+		products[id] = true
+	}
+
+	categories := make(map[uuid.UUID]bool)
+	if len(discount.CategoryIDs) > 0 {
+		all, err := s.categoryRepo.GetAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range discount.CategoryIDs {
+			for descendant := range categoryWithDescendants(all, id) {
+				categories[descendant] = true
+			}
+		}
+	}
+
+	eligible := make([]bool, len(lines))
+	for i, line := range lines {
+		eligible[i] = products[line.ProductID] || (line.CategoryID != nil && categories[*line.CategoryID])
+	}
+	return eligible, nil
+}
+
+// splitDiscount spreads the discount over the eligible lines in proportion to their
+// subtotals (nil eligible = every line). The last eligible line takes the rounding
+// remainder, so the shares always add up to the amount.
+func splitDiscount(amount float64, lines []models.DiscountLine, eligible []bool) []float64 {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	shares := make([]float64, len(lines))
+	total := 0.0
+	last := -1
+	for i, line := range lines {
+		if eligible == nil || eligible[i] {
+			total += line.Subtotal
+			last = i
+		}
+	}
+	if total <= 0 || last < 0 {
+		return shares
+	}
+
+	assigned := 0.0
+	for i, line := range lines {
+		if eligible != nil && !eligible[i] {
+			continue
+		}
+		if i == last {
+			shares[i] = amount - assigned
+			break
+		}
+		shares[i] = math.Round(amount * line.Subtotal / total)
+		assigned += shares[i]
+	}
+	return shares
+}
+
+// RedeemDiscountCode records the use of a code by an order. The usage limits are
+// enforced transactionally, so it can fail even right after a successful validation.
+func (s *DiscountService) RedeemDiscountCode(ctx context.Context, redemption *models.DiscountRedemption) error {
+	return s.discountRepo.Redeem(ctx, redemption)
+}
+
+// ReleaseOrderRedemptions gives back the uses of an order's codes (e.g. when it's cancelled)
+func (s *DiscountService) ReleaseOrderRedemptions(ctx context.Context, orderID uuid.UUID) error {
+	_, err := s.discountRepo.ReleaseByOrder(ctx, orderID)
+	return err
 }
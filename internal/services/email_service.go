@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"html"
 	"io"
 	"log"
+	"mime"
 	"net"
 	"net/http"
 	"net/smtp"
@@ -13,6 +15,8 @@ import (
 	"time"
 
 	"crypto/tls"
+
+	"github.com/cheoscafe/backend/internal/models"
 )
 
 type EmailService struct {
@@ -74,11 +78,16 @@ func (s *EmailService) SendPasswordResetEmail(toEmail, toName, resetToken string
 	body = strings.ReplaceAll(body, "{NOMBRE}", toName)
 	body = strings.ReplaceAll(body, "{RESET_URL}", resetURL)
 
+	return s.send(toEmail, "Cheos Cafe - Restablecer contraseña", body)
+}
+
+// send envía un correo HTML por el proveedor configurado
+func (s *EmailService) send(toEmail, subject, htmlBody string) error {
 	var err error
 	if s.useResend {
-		err = s.sendWithResend(toEmail, body)
+		err = s.sendWithResend(toEmail, subject, htmlBody)
 	} else {
-		err = s.sendWithSMTP(toEmail, body)
+		err = s.sendWithSMTP(toEmail, subject, htmlBody)
 	}
 
 	if err != nil {
@@ -89,6 +98,96 @@ func (s *EmailService) SendPasswordResetEmail(toEmail, toName, resetToken string
 	return nil
 }
 
+// SendLowStockDigest envía el resumen diario de productos en o bajo su umbral de reorden
+func (s *EmailService) SendLowStockDigest(toEmail, toName string, report *models.LowStockReport) error {
+	log.Printf("[EMAIL] Enviando resumen de stock bajo a: %s (%d productos)", toEmail, len(report.Items))
+
+	var rows strings.Builder
+	for _, item := range report.Items {
+		name := html.EscapeString(item.ProductName)
+		if item.VariantName != "" {
+			name += " <span style=\"color: #888;\">(" + html.EscapeString(item.VariantName) + ")</span>"
+		}
+		cover := "-"
+		if item.DaysOfCover != nil {
+			cover = fmt.Sprintf("%.0f días", *item.DaysOfCover)
+		}
+		rows.WriteString(fmt.Sprintf(
+			"<tr><td style=\"padding: 6px; border-bottom: 1px solid #eee;\">%s</td>"+
+				"<td style=\"padding: 6px; border-bottom: 1px solid #eee; text-align: center;\">%d / %d</td>"+
+				"<td style=\"padding: 6px; border-bottom: 1px solid #eee; text-align: center;\">%.1f</td>"+
+				"<td style=\"padding: 6px; border-bottom: 1px solid #eee; text-align: center;\">%s</td></tr>",
+			name, item.Stock, item.ReorderThreshold, item.DailyVelocity, cover,
+		))
+	}
+
+	body := lowStockDigestTemplate
+	body = strings.ReplaceAll(body, "{NOMBRE}", html.EscapeString(toName))
+	body = strings.ReplaceAll(body, "{DIAS}", fmt.Sprintf("%d", report.Days))
+	body = strings.ReplaceAll(body, "{FILAS}", rows.String())
+	body = strings.ReplaceAll(body, "{ADMIN_URL}", s.frontendURL+"/admin/productos")
+
+	subject := fmt.Sprintf("Cheos Cafe - %d producto(s) con stock bajo", len(report.Items))
+	return s.send(toEmail, subject, body)
+}
+
+// SendBackInStockEmail avisa a un suscriptor que el producto volvió a tener stock
+func (s *EmailService) SendBackInStockEmail(toEmail, toName, productName, productURL string) error {
+	log.Printf("[EMAIL] Enviando aviso de reposición a: %s (%s)", toEmail, productName)
+
+	if toName == "" {
+		toName = "cliente"
+	}
+
+	body := backInStockTemplate
+	body = strings.ReplaceAll(body, "{NOMBRE}", html.EscapeString(toName))
+	body = strings.ReplaceAll(body, "{PRODUCTO}", html.EscapeString(productName))
+	body = strings.ReplaceAll(body, "{PRODUCT_URL}", productURL)
+
+	return s.send(toEmail, "Cheos Cafe - "+productName+" volvió a estar disponible", body)
+}
+
+// SendAbandonedCartEmail recuerda al usuario lo que dejó en el carrito. Si hay
+// código de descuento se incluye; unsubscribeURL permite darse de baja.
+func (s *EmailService) SendAbandonedCartEmail(toEmail, toName string, items []models.CartItem, discountCode string, discountPercent int, unsubscribeURL string) error {
+	log.Printf("[EMAIL] Enviando recordatorio de carrito a: %s (%d productos)", toEmail, len(items))
+
+	if toName == "" {
+		toName = "cliente"
+	}
+
+	var rows strings.Builder
+	for _, item := range items {
+		name := html.EscapeString(item.ProductName)
+		if item.VariantName != "" {
+			name += " <span style=\"color: #888;\">(" + html.EscapeString(item.VariantName) + ")</span>"
+		}
+		rows.WriteString(fmt.Sprintf(
+			"<tr><td style=\"padding: 6px; border-bottom: 1px solid #eee;\">%s</td>"+
+				"<td style=\"padding: 6px; border-bottom: 1px solid #eee; text-align: center;\">%d</td></tr>",
+			name, item.Quantity,
+		))
+	}
+
+	discount := ""
+	if discountCode != "" {
+		discount = fmt.Sprintf(
+			"<p style=\"text-align: center; background-color: #f5f0eb; border-radius: 8px; padding: 12px;\">"+
+				"Usa el código <strong>%s</strong> y obtén %d%% de descuento. Es de un solo uso y vence en %d días.</p>",
+			html.EscapeString(discountCode), discountPercent, cartReminderCodeDays,
+		)
+	}
+
+	body := abandonedCartTemplate
+	body = strings.ReplaceAll(body, "{NOMBRE}", html.EscapeString(toName))
+	body = strings.ReplaceAll(body, "{FILAS}", rows.String())
+	body = strings.ReplaceAll(body, "{DESCUENTO}", discount)
+	body = strings.ReplaceAll(body, "{CART_URL}", s.frontendURL+"/carrito")
+	body = strings.ReplaceAll(body, "{UNSUBSCRIBE_URL}", unsubscribeURL)
+
+	return s.send(toEmail, "Cheos Cafe - Tu carrito te está esperando", body)
+}
+
 func (s *EmailService) modeLabel() string {
 	if s.useResend {
 		return "RESEND"
@@ -105,11 +204,11 @@ type resendRequest struct {
 	HTML    string   `json:"html"`
 }
 
-func (s *EmailService) sendWithResend(toEmail, htmlBody string) error {
+func (s *EmailService) sendWithResend(toEmail, subject, htmlBody string) error {
 	payload := resendRequest{
 		From:    fmt.Sprintf("Cheos Cafe <%s>", s.resendFrom),
 		To:      []string{toEmail},
-		Subject: "Cheos Cafe - Restablecer contraseña",
+		Subject: subject,
 		HTML:    htmlBody,
 	}
 
@@ -145,16 +244,16 @@ func (s *EmailService) sendWithResend(toEmail, htmlBody string) error {
 
 // ==================== SMTP (Gmail) ====================
 
-func (s *EmailService) sendWithSMTP(toEmail, htmlBody string) error {
+func (s *EmailService) sendWithSMTP(toEmail, subject, htmlBody string) error {
 	message := fmt.Sprintf(
 		"From: Cheos Cafe <%s>\r\n"+
 			"To: %s\r\n"+
-			"Subject: Cheos Cafe - Restablecer contraseña\r\n"+
+			"Subject: %s\r\n"+
 			"MIME-Version: 1.0\r\n"+
 			"Content-Type: text/html; charset=UTF-8\r\n"+
 			"\r\n"+
 			"%s",
-		s.smtpEmail, toEmail, htmlBody,
+		s.smtpEmail, toEmail, mime.QEncoding.Encode("UTF-8", subject), htmlBody,
 	)
 
 	addr := s.smtpHost + ":" + s.smtpPort
@@ -229,3 +328,88 @@ const passwordResetTemplate = `<!DOCTYPE html>
   </div>
 </body>
 </html>`
+
+const lowStockDigestTemplate = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="UTF-8">
+</head>
+<body style="font-family: Arial, sans-serif; background-color: #f5f0eb; padding: 20px;">
+  <div style="max-width: 600px; margin: 0 auto; background: white; border-radius: 12px; padding: 30px; box-shadow: 0 2px 10px rgba(0,0,0,0.1);">
+    <h1 style="color: #6F4E37; text-align: center;">Cheos Cafe</h1>
+    <p>Hola <strong>{NOMBRE}</strong>,</p>
+    <p>Estos productos están en o por debajo de su umbral de reorden:</p>
+    <table style="width: 100%; border-collapse: collapse; font-size: 14px;">
+      <tr style="background-color: #f5f0eb;">
+        <th style="padding: 6px; text-align: left;">Producto</th>
+        <th style="padding: 6px;">Stock / umbral</th>
+        <th style="padding: 6px;">Ventas/día</th>
+        <th style="padding: 6px;">Cobertura</th>
+      </tr>
+      {FILAS}
+    </table>
+    <p style="color: #888; font-size: 13px;">Ventas por día calculadas sobre los últimos {DIAS} días.</p>
+    <div style="text-align: center; margin: 25px 0;">
+      <a href="{ADMIN_URL}" style="background-color: #6F4E37; color: white; padding: 12px 30px; text-decoration: none; border-radius: 8px; font-size: 16px;">
+        Ver inventario
+      </a>
+    </div>
+    <hr style="border: none; border-top: 1px solid #eee; margin: 20px 0;">
+    <p style="color: #aaa; font-size: 11px; text-align: center;">Cheos Cafe - Café de especialidad colombiano</p>
+  </div>
+</body>
+</html>`
+
+const backInStockTemplate = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="UTF-8">
+</head>
+<body style="font-family: Arial, sans-serif; background-color: #f5f0eb; padding: 20px;">
+  <div style="max-width: 500px; margin: 0 auto; background: white; border-radius: 12px; padding: 30px; box-shadow: 0 2px 10px rgba(0,0,0,0.1);">
+    <h1 style="color: #6F4E37; text-align: center;">Cheos Cafe</h1>
+    <p>Hola <strong>{NOMBRE}</strong>,</p>
+    <p>Buenas noticias: <strong>{PRODUCTO}</strong> volvió a estar disponible.</p>
+    <p>Las unidades son limitadas, así que te recomendamos no esperar mucho.</p>
+    <div style="text-align: center; margin: 25px 0;">
+      <a href="{PRODUCT_URL}" style="background-color: #6F4E37; color: white; padding: 12px 30px; text-decoration: none; border-radius: 8px; font-size: 16px;">
+        Ver producto
+      </a>
+    </div>
+    <p style="color: #888; font-size: 13px;">Recibiste este correo porque pediste que te avisáramos. Este aviso se envía una sola vez.</p>
+    <hr style="border: none; border-top: 1px solid #eee; margin: 20px 0;">
+    <p style="color: #aaa; font-size: 11px; text-align: center;">Cheos Cafe - Café de especialidad colombiano</p>
+  </div>
+</body>
+</html>`
+
+const abandonedCartTemplate = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="UTF-8">
+</head>
+<body style="font-family: Arial, sans-serif; background-color: #f5f0eb; padding: 20px;">
+  <div style="max-width: 500px; margin: 0 auto; background: white; border-radius: 12px; padding: 30px; box-shadow: 0 2px 10px rgba(0,0,0,0.1);">
+    <h1 style="color: #6F4E37; text-align: center;">Cheos Cafe</h1>
+    <p>Hola <strong>{NOMBRE}</strong>,</p>
+    <p>Dejaste estos productos en tu carrito:</p>
+    <table style="width: 100%; border-collapse: collapse; font-size: 14px;">
+      <tr style="background-color: #f5f0eb;">
+        <th style="padding: 6px; text-align: left;">Producto</th>
+        <th style="padding: 6px;">Cantidad</th>
+      </tr>
+      {FILAS}
+    </table>
+    {DESCUENTO}
+    <div style="text-align: center; margin: 25px 0;">
+      <a href="{CART_URL}" style="background-color: #6F4E37; color: white; padding: 12px 30px; text-decoration: none; border-radius: 8px; font-size: 16px;">
+        Terminar mi compra
+      </a>
+    </div>
+    <p style="color: #888; font-size: 13px;">Los precios y la disponibilidad se confirman al pagar.</p>
+    <hr style="border: none; border-top: 1px solid #eee; margin: 20px 0;">
+    <p style="color: #aaa; font-size: 11px; text-align: center;">Cheos Cafe - Café de especialidad colombiano<br>
+      <a href="{UNSUBSCRIBE_URL}" style="color: #aaa;">No quiero recibir más correos como este</a></p>
+  </div>
+</body>
+</html>`
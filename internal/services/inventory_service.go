@@ -0,0 +1,524 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/cheoscafe/backend/internal/config"
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/cheoscafe/backend/internal/repository"
+	"github.com/cheoscafe/backend/internal/utils"
+	"github.com/google/uuid"
+)
+
+// InventoryService es el único punto por el que cambia el stock:
+// cada cambio queda registrado en el ledger de movimientos.
+// Al cruzar el umbral de reorden avisa a los admins (notificación + SSE)
+// y al volver de 0 a positivo avisa a los clientes suscritos.
+type InventoryService struct {
+	productRepo         *repository.ProductRepository
+	inventoryRepo       *repository.InventoryRepository
+	userRepo            *repository.UserRepository
+	notificationService *NotificationService
+	emailService        *EmailService
+	stockAlertService   *StockAlertService
+	wishlistService     *WishlistService
+	cache               *CacheService
+	cfg                 *config.Config
+}
+
+func NewInventoryService(
+	productRepo *repository.ProductRepository,
+	inventoryRepo *repository.InventoryRepository,
+	userRepo *repository.UserRepository,
+	notificationService *NotificationService,
+	emailService *EmailService,
+	stockAlertService *StockAlertService,
+	wishlistService *WishlistService,
+	cache *CacheService,
+	cfg *config.Config,
+) *InventoryService {
+	return &InventoryService{
+		productRepo:         productRepo,
+		inventoryRepo:       inventoryRepo,
+		userRepo:            userRepo,
+		notificationService: notificationService,
+		emailService:        emailService,
+		stockAlertService:   stockAlertService,
+		wishlistService:     wishlistService,
+		cache:               cache,
+		cfg:                 cfg,
+	}
+}
+
+// ApplyStockChange aplica un cambio de stock y registra su movimiento.
+// Un kit no tiene stock propio: la venta y su devolución se aplican a los componentes
+// (en una sola transacción) y el movimiento retornado es el del primer componente.
+func (s *InventoryService) ApplyStockChange(ctx context.Context, change *models.StockChange) (*models.InventoryMovement, error) {
+	movements, err := s.ApplyStockChanges(ctx, []*models.StockChange{change})
+	if err != nil {
+		return nil, err
+	}
+	return movements[0], nil
+}
+
+// ApplyStockChanges aplica varios cambios (p. ej. las líneas de una orden) en una sola
+// transacción: o se aplican todos o ninguno. Los kits se expanden a sus componentes.
+// La caché se invalida una sola vez y solo para los productos afectados.
+func (s *InventoryService) ApplyStockChanges(ctx context.Context, changes []*models.StockChange) ([]*models.InventoryMovement, error) {
+	return s.ApplySale(ctx, changes, nil)
+}
+
+// ApplySale es ApplyStockChanges verificando además, dentro de la misma transacción,
+// los límites por cliente de los productos vendidos
+func (s *InventoryService) ApplySale(ctx context.Context, changes []*models.StockChange, limits []*models.CustomerLimit) ([]*models.InventoryMovement, error) {
+	expanded := make([]*models.StockChange, 0, len(changes))
+	for _, change := range changes {
+		if change.Reason == "" {
+			return nil, errors.New("el motivo del movimiento es requerido")
+		}
+		components, err := s.expandBundle(ctx, change)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, components...)
+	}
+	if len(expanded) == 0 {
+		return nil, errors.New("no hay cambios de stock para aplicar")
+	}
+
+	movements, products, err := s.productRepo.ApplyStockChanges(ctx, expanded, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	// products trae el stock final; se reconstruye el total de cada producto movimiento
+	// a movimiento para detectar reposiciones aunque haya varios cambios sobre él
+	productStock := make(map[uuid.UUID]int, len(products))
+	for i, movement := range movements {
+		productStock[movement.ProductID] = products[i].Stock
+	}
+	for _, movement := range movements {
+		productStock[movement.ProductID] -= movement.Delta
+	}
+
+	affected := make([]uuid.UUID, 0, len(movements))
+	seen := make(map[uuid.UUID]bool, len(products))
+	changed := make([]*models.Product, 0, len(products))
+	for i, movement := range movements {
+		productStock[movement.ProductID] += movement.Delta
+		s.checkLowStock(ctx, products[i], movement)
+		s.checkRestock(movement, productStock[movement.ProductID])
+		affected = append(affected, movement.ProductID)
+		if !seen[movement.ProductID] {
+			seen[movement.ProductID] = true
+			changed = append(changed, products[i])
+		}
+	}
+	changed = append(changed, s.RefreshBundles(ctx, affected)...)
+	s.cache.InvalidateProducts(ctx, changed...)
+
+	return movements, nil
+}
+
+// expandBundle convierte un cambio sobre un kit en los cambios de sus componentes.
+// Un cambio sobre un producto normal se retorna tal cual.
+func (s *InventoryService) expandBundle(ctx context.Context, change *models.StockChange) ([]*models.StockChange, error) {
+	// Con la composición guardada en el pedido, el kit puede haberse eliminado después
+	product, err := s.productRepo.GetByID(ctx, change.ProductID)
+	if err != nil && len(change.BundleItems) == 0 {
+		return nil, err
+	}
+
+	items := change.BundleItems
+	if len(items) == 0 {
+		if !product.IsBundle() {
+			return []*models.StockChange{change}, nil
+		}
+		items = product.BundleItems
+	}
+
+	if change.Reason != models.InventorySale && change.Reason != models.InventoryCancellationReturn {
+		return nil, errors.New("el stock de un kit se deriva de sus componentes; ajuste el stock de cada componente")
+	}
+	bundleName := change.ProductID.String()
+	if product != nil {
+		bundleName = product.Name
+	}
+
+	changes := make([]*models.StockChange, 0, len(items))
+	for _, item := range items {
+		changes = append(changes, &models.StockChange{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Delta:     change.Delta * item.Quantity,
+			Reason:    change.Reason,
+			Reference: change.Reference,
+			Note:      "Kit: " + bundleName,
+			ActorID:   change.ActorID,
+		})
+	}
+	return changes, nil
+}
+
+// RefreshBundles recalcula el stock derivado de los kits que incluyen alguno de los
+// productos dados (tras mover stock, desactivar o eliminar un componente).
+// Si un kit vuelve a tener stock, avisa a sus suscriptores.
+// Retorna los kits cuyo stock cambió, para que el llamador invalide su caché.
+func (s *InventoryService) RefreshBundles(ctx context.Context, productIDs []uuid.UUID) []*models.Product {
+	var refreshed []*models.Product
+	seen := make(map[uuid.UUID]bool)
+	for _, productID := range productIDs {
+		if seen[productID] {
+			continue
+		}
+		seen[productID] = true
+
+		bundles, err := s.productRepo.ListBundlesContaining(ctx, productID)
+		if err != nil {
+			log.Printf("Warning: no se pudieron obtener los kits de %s: %v", productID, err)
+			continue
+		}
+		for _, bundle := range bundles {
+			if seen[bundle.ID] {
+				continue
+			}
+			seen[bundle.ID] = true
+			if s.refreshBundleStock(ctx, bundle) {
+				refreshed = append(refreshed, bundle)
+			}
+		}
+	}
+	return refreshed
+}
+
+// refreshBundleStock recalcula y guarda el stock de un kit a partir de sus componentes.
+// Retorna true si el stock cambió.
+func (s *InventoryService) refreshBundleStock(ctx context.Context, bundle *models.Product) bool {
+	ids := make([]uuid.UUID, 0, len(bundle.BundleItems))
+	for _, item := range bundle.BundleItems {
+		ids = append(ids, item.ProductID)
+	}
+	components, err := s.productRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		log.Printf("Warning: no se pudieron obtener los componentes del kit %s: %v", bundle.ID, err)
+		return false
+	}
+
+	byID := make(map[uuid.UUID]*models.Product, len(components))
+	for _, c := range components {
+		byID[c.ID] = c
+	}
+
+	stock := bundle.BundleStock(byID)
+	if stock == bundle.Stock {
+		return false
+	}
+	if err := s.productRepo.SetStock(ctx, bundle.ID, stock); err != nil {
+		log.Printf("Warning: no se pudo actualizar el stock del kit %s: %v", bundle.ID, err)
+		return false
+	}
+
+	if bundle.Stock <= 0 && stock > 0 {
+		if s.stockAlertService != nil {
+			go s.stockAlertService.NotifyRestock(context.Background(), bundle.ID, nil)
+		}
+		if s.wishlistService != nil {
+			go s.wishlistService.NotifyBackInStock(context.Background(), bundle.ID, nil, true)
+		}
+	}
+	return true
+}
+
+// checkRestock dispara los avisos de reposición cuando el stock pasa de 0 a positivo:
+// a los suscritos al producto y a quienes lo tienen en su lista de deseos.
+// Corre en segundo plano para no demorar el ajuste; usa un contexto propio porque
+// el de la petición se cancela al responder. productStock es el stock total del
+// producto justo después del movimiento.
+func (s *InventoryService) checkRestock(movement *models.InventoryMovement, productStock int) {
+	previous := movement.Balance - movement.Delta
+	restocked := previous <= 0 && movement.Balance > 0
+	// Con variantes, el producto completo solo "vuelve" si todas estaban agotadas
+	productRestocked := productStock > 0 && productStock-movement.Delta <= 0
+
+	if restocked && s.stockAlertService != nil {
+		go s.stockAlertService.NotifyRestock(context.Background(), movement.ProductID, movement.VariantID)
+	}
+	if (restocked || productRestocked) && s.wishlistService != nil {
+		go s.wishlistService.NotifyBackInStock(context.Background(), movement.ProductID, movement.VariantID, productRestocked)
+	}
+}
+
+// checkLowStock alerta solo cuando el movimiento cruza el umbral hacia abajo,
+// no en cada venta posterior mientras el stock siga bajo.
+func (s *InventoryService) checkLowStock(ctx context.Context, product *models.Product, movement *models.InventoryMovement) {
+	previous := movement.Balance - movement.Delta
+	if movement.Delta >= 0 || previous <= product.ReorderThreshold || movement.Balance > product.ReorderThreshold {
+		return
+	}
+	if s.notificationService == nil {
+		return
+	}
+
+	admins, err := s.adminUsers(ctx)
+	if err != nil {
+		log.Printf("Warning: no se pudieron obtener los admins para la alerta de stock bajo: %v", err)
+		return
+	}
+
+	adminIDs := make([]uuid.UUID, 0, len(admins))
+	for _, admin := range admins {
+		adminIDs = append(adminIDs, admin.ID)
+	}
+	s.notificationService.CreateLowStockNotification(ctx, adminIDs, product, movement)
+}
+
+func (s *InventoryService) adminUsers(ctx context.Context) ([]*models.User, error) {
+	users, err := s.userRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	admins := make([]*models.User, 0)
+	for _, u := range users {
+		if u.Role == models.RoleAdmin && u.IsActive {
+			admins = append(admins, u)
+		}
+	}
+	return admins, nil
+}
+
+// AdjustStock aplica un ajuste manual desde el panel (solo admin)
+func (s *InventoryService) AdjustStock(ctx context.Context, productID string, req *models.UpdateStockRequest, actorID *uuid.UUID) (*models.InventoryMovement, error) {
+	id, err := uuid.Parse(productID)
+	if err != nil {
+		return nil, errors.New("ID de producto inválido")
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	change := &models.StockChange{
+		ProductID: id,
+		VariantID: req.VariantID,
+		Reason:    req.Reason,
+		Reference: req.Reference,
+		Note:      req.Note,
+		ActorID:   actorID,
+	}
+	if change.Reason == "" {
+		change.Reason = models.InventoryManualAdjustment
+	}
+
+	switch change.Reason {
+	case models.InventoryStocktake:
+		if req.Counted == nil {
+			return nil, errors.New("el conteo físico (counted) es requerido para un inventario")
+		}
+		change.SetTo = req.Counted
+	case models.InventoryReturnRestock:
+		if req.Quantity <= 0 {
+			return nil, errors.New("la cantidad devuelta debe ser mayor a 0")
+		}
+		change.Delta = req.Quantity
+	default:
+		if req.Quantity == 0 {
+			return nil, errors.New("la cantidad del ajuste no puede ser 0")
+		}
+		change.Delta = req.Quantity
+	}
+
+	movement, err := s.ApplyStockChange(ctx, change)
+	if err != nil {
+		return nil, fmt.Errorf("error al actualizar stock: %w", err)
+	}
+
+	return movement, nil
+}
+
+// GetMovements obtiene el ledger de un producto con los totales del período
+func (s *InventoryService) GetMovements(ctx context.Context, filter *models.InventoryMovementFilter) (*models.InventoryMovementsResponse, error) {
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return nil, errors.New("el rango de fechas es inválido")
+	}
+
+	movements, err := s.inventoryRepo.ListByProduct(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener movimientos: %w", err)
+	}
+
+	response := &models.InventoryMovementsResponse{Movements: movements}
+	for _, m := range movements {
+		if m.Delta > 0 {
+			response.TotalIn += m.Delta
+		} else {
+			response.TotalOut -= m.Delta
+		}
+	}
+	response.Net = response.TotalIn - response.TotalOut
+
+	return response, nil
+}
+
+// GetLowStockReport lista los productos (o variantes) en o por debajo de su umbral de reorden,
+// con la velocidad de venta de los últimos días, ordenados por días de cobertura.
+func (s *InventoryService) GetLowStockReport(ctx context.Context, days int) (*models.LowStockReport, error) {
+	if days < 1 || days > 365 {
+		days = s.cfg.LowStockVelocityDays
+	}
+
+	products, err := s.productRepo.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener productos: %w", err)
+	}
+
+	now := time.Now()
+	movements, err := s.inventoryRepo.ListSince(ctx, now.AddDate(0, 0, -days))
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener movimientos: %w", err)
+	}
+
+	// Ventas netas por producto/variante: las cancelaciones restan
+	sold := make(map[string]int)
+	for _, m := range movements {
+		if m.Reason == models.InventorySale || m.Reason == models.InventoryCancellationReturn {
+			sold[stockKey(m.ProductID, m.VariantID)] -= m.Delta
+		}
+	}
+
+	items := []models.LowStockItem{}
+	add := func(p *models.Product, variant *models.ProductVariant) {
+		item := models.LowStockItem{
+			ProductID:        p.ID,
+			ProductName:      p.Name,
+			Stock:            p.Stock,
+			ReorderThreshold: p.ReorderThreshold,
+		}
+		if variant != nil {
+			variantID := variant.ID
+			item.VariantID = &variantID
+			item.VariantName = p.VariantLabel(variant)
+			item.SKU = variant.SKU
+			item.Stock = variant.Stock
+		}
+		if item.Stock > item.ReorderThreshold {
+			return
+		}
+
+		item.UnitsSold = sold[stockKey(p.ID, item.VariantID)]
+		if item.UnitsSold < 0 {
+			item.UnitsSold = 0
+		}
+		item.DailyVelocity = math.Round(float64(item.UnitsSold)/float64(days)*100) / 100
+		if item.DailyVelocity > 0 {
+			cover := math.Round(float64(item.Stock)/item.DailyVelocity*10) / 10
+			item.DaysOfCover = &cover
+		}
+		items = append(items, item)
+	}
+
+	// Los kits no se reponen: se reponen sus componentes
+	for _, p := range products {
+		if !p.IsActive || p.IsBundle() {
+			continue
+		}
+		if !p.HasVariants() {
+			add(p, nil)
+			continue
+		}
+		for i := range p.Variants {
+			if p.Variants[i].IsActive {
+				add(p, &p.Variants[i])
+			}
+		}
+	}
+
+	// Primero lo que se acaba antes; sin ventas al final
+	sort.SliceStable(items, func(i, j int) bool {
+		ci, cj := items[i].DaysOfCover, items[j].DaysOfCover
+		switch {
+		case ci != nil && cj != nil:
+			return *ci < *cj
+		case ci != nil:
+			return true
+		case cj != nil:
+			return false
+		default:
+			return items[i].Stock < items[j].Stock
+		}
+	})
+
+	return &models.LowStockReport{
+		Days:        days,
+		Items:       items,
+		GeneratedAt: now,
+	}, nil
+}
+
+// SendLowStockDigest envía el reporte de stock bajo por correo a cada admin.
+// No envía nada si no hay productos bajo el umbral.
+func (s *InventoryService) SendLowStockDigest(ctx context.Context) error {
+	report, err := s.GetLowStockReport(ctx, s.cfg.LowStockVelocityDays)
+	if err != nil {
+		return err
+	}
+	if len(report.Items) == 0 {
+		return nil
+	}
+
+	admins, err := s.adminUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("error al obtener admins: %w", err)
+	}
+
+	for _, admin := range admins {
+		if err := s.emailService.SendLowStockDigest(admin.Email, admin.Name, report); err != nil {
+			log.Printf("Warning: no se pudo enviar el resumen de stock bajo a %s: %v", admin.Email, err)
+		}
+	}
+	return nil
+}
+
+// StartLowStockDigest envía el resumen una vez al día a la hora configurada
+// (LOW_STOCK_DIGEST_HOUR). Bloquea hasta que ctx se cancele; lanzar en una goroutine.
+func (s *InventoryService) StartLowStockDigest(ctx context.Context) {
+	hour := s.cfg.LowStockDigestHour
+	if hour < 0 || hour > 23 {
+		log.Printf("[Inventory] Resumen de stock bajo desactivado")
+		return
+	}
+
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	lastSent := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			today := now.Format("2006-01-02")
+			if now.Hour() != hour || lastSent == today {
+				continue
+			}
+			lastSent = today
+			if err := s.SendLowStockDigest(ctx); err != nil {
+				log.Printf("[Inventory] Error enviando resumen de stock bajo: %v", err)
+			}
+		}
+	}
+}
+
+// stockKey identifica un producto o una de sus variantes
+func stockKey(productID uuid.UUID, variantID *uuid.UUID) string {
+	if variantID == nil {
+		return productID.String()
+	}
+	return productID.String() + "/" + variantID.String()
+}
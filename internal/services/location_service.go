@@ -12,11 +12,13 @@ import (
 
 type LocationService struct {
 	locationRepo *repository.LocationRepository
+	cache        *CacheService
 }
 
-func NewLocationService(locationRepo *repository.LocationRepository) *LocationService {
+func NewLocationService(locationRepo *repository.LocationRepository, cache *CacheService) *LocationService {
 	return &LocationService{
 		locationRepo: locationRepo,
+		cache:        cache,
 	}
 }
 
@@ -38,6 +40,7 @@ func (s *LocationService) CreateLocation(ctx context.Context, req *models.Create
 	if err := s.locationRepo.Create(ctx, location); err != nil {
 		return nil, err
 	}
+	s.cache.Delete(ctx, cacheKeyActiveLocations)
 
 	return location, nil
 }
@@ -90,8 +93,14 @@ func (s *LocationService) GetAllLocations(ctx context.Context, page int, pageSiz
 	}, nil
 }
 
-// GetActiveLocations gets all active locations (for public display)
+// GetActiveLocations gets all active locations (for public display).
+// Served from cache when available; admin writes invalidate it.
 func (s *LocationService) GetActiveLocations(ctx context.Context) ([]models.Location, error) {
+	var cached []models.Location
+	if s.cache.Get(ctx, cacheKeyActiveLocations, &cached) {
+		return cached, nil
+	}
+
 	locations, err := s.locationRepo.GetActive(ctx)
 	if err != nil {
 		return nil, err
@@ -102,6 +111,7 @@ func (s *LocationService) GetActiveLocations(ctx context.Context) ([]models.Loca
 	for _, loc := range locations {
 		locationList = append(locationList, *loc)
 	}
+	s.cache.Set(ctx, cacheKeyActiveLocations, locationList, s.cache.ConfigTTL)
 
 	return locationList, nil
 }
@@ -148,6 +158,7 @@ func (s *LocationService) UpdateLocation(ctx context.Context, id uuid.UUID, req
 	if err := s.locationRepo.Update(ctx, id, existing); err != nil {
 		return nil, err
 	}
+	s.cache.Delete(ctx, cacheKeyActiveLocations)
 
 	return existing, nil
 }
@@ -162,6 +173,7 @@ func (s *LocationService) DeleteLocation(ctx context.Context, id uuid.UUID) erro
 	if err := s.locationRepo.Delete(ctx, id); err != nil {
 		return err
 	}
+	s.cache.Delete(ctx, cacheKeyActiveLocations)
 
 	return nil
 }
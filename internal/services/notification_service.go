@@ -142,3 +142,86 @@ func (s *NotificationService) MarkRead(ctx context.Context, notifID uuid.UUID, u
 func (s *NotificationService) MarkAllRead(ctx context.Context, userID uuid.UUID) error {
 	return s.repo.MarkAllRead(ctx, userID)
 }
+
+// CreateLowStockNotification avisa a cada admin que un producto (o variante) llegó a su
+// umbral de reorden, y la empuja por SSE a los que estén conectados.
+func (s *NotificationService) CreateLowStockNotification(
+	ctx context.Context,
+	adminIDs []uuid.UUID,
+	product *models.Product,
+	movement *models.InventoryMovement,
+) {
+	name := product.Name
+	if movement.VariantName != "" {
+		name = fmt.Sprintf("%s (%s)", product.Name, movement.VariantName)
+	}
+
+	title := "Stock bajo"
+	body := fmt.Sprintf("%s quedó con %d unidad(es). Umbral de reorden: %d.", name, movement.Balance, product.ReorderThreshold)
+	if movement.Balance == 0 {
+		title = "Producto agotado"
+		body = fmt.Sprintf("%s se agotó. Umbral de reorden: %d.", name, product.ReorderThreshold)
+	}
+
+	for _, adminID := range adminIDs {
+		n := &models.Notification{
+			UserID:    adminID,
+			Type:      models.NotificationLowStock,
+			ProductID: &product.ID,
+			Title:     title,
+			Body:      body,
+			Icon:      "low_stock",
+		}
+
+		if err := s.repo.Create(ctx, n); err != nil {
+			log.Printf("[Notif] ERROR guardando alerta de stock bajo para admin %s: %v", adminID, err)
+			continue
+		}
+
+		if b, err := json.Marshal(n); err == nil {
+			s.hub.Publish(n.UserID.String(), b)
+		}
+	}
+}
+
+// CreateWishlistSaleNotification avisa a un cliente que un producto de su lista de deseos está en oferta
+func (s *NotificationService) CreateWishlistSaleNotification(ctx context.Context, userID, productID uuid.UUID, productName string, price float64) error {
+	n := &models.Notification{
+		UserID:    userID,
+		Type:      models.NotificationWishlistSale,
+		ProductID: &productID,
+		Title:     "¡Un favorito está en oferta!",
+		Body:      fmt.Sprintf("%s de tu lista de deseos ahora cuesta $%.0f.", productName, price),
+		Icon:      "wishlist_sale",
+	}
+
+	if err := s.repo.Create(ctx, n); err != nil {
+		return err
+	}
+
+	if b, err := json.Marshal(n); err == nil {
+		s.hub.Publish(n.UserID.String(), b)
+	}
+	return nil
+}
+
+// CreateBackInStockNotification avisa a un cliente suscrito que el producto volvió a tener stock
+func (s *NotificationService) CreateBackInStockNotification(ctx context.Context, userID, productID uuid.UUID, productName string) error {
+	n := &models.Notification{
+		UserID:    userID,
+		Type:      models.NotificationBackInStock,
+		ProductID: &productID,
+		Title:     "¡Volvió a estar disponible!",
+		Body:      fmt.Sprintf("%s ya tiene stock de nuevo. Aprovecha antes de que se agote.", productName),
+		Icon:      "back_in_stock",
+	}
+
+	if err := s.repo.Create(ctx, n); err != nil {
+		return err
+	}
+
+	if b, err := json.Marshal(n); err == nil {
+		s.hub.Publish(n.UserID.String(), b)
+	}
+	return nil
+}
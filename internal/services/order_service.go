@@ -14,117 +14,153 @@ import (
 type OrderService struct {
 	orderRepo           *repository.OrderRepository
 	productRepo         *repository.ProductRepository
+	inventoryService    *InventoryService
 	cartRepo            *repository.CartRepository
 	dashboardService    *DashboardService
 	discountService     *DiscountService
+	checkoutService     *CheckoutService
+	cartRecoveryService *CartRecoveryService
 	notificationService *NotificationService
+	customerPurchases   *repository.CustomerPurchaseRepository
 }
 
 func NewOrderService(
 	orderRepo *repository.OrderRepository,
 	productRepo *repository.ProductRepository,
+	inventoryService *InventoryService,
 	cartRepo *repository.CartRepository,
 	dashboardService *DashboardService,
 	discountService *DiscountService,
+	checkoutService *CheckoutService,
+	cartRecoveryService *CartRecoveryService,
 	notificationService *NotificationService,
+	customerPurchases *repository.CustomerPurchaseRepository,
 ) *OrderService {
 	return &OrderService{
 		orderRepo:           orderRepo,
 		productRepo:         productRepo,
+		inventoryService:    inventoryService,
 		cartRepo:            cartRepo,
 		dashboardService:    dashboardService,
 		discountService:     discountService,
+		checkoutService:     checkoutService,
+		cartRecoveryService: cartRecoveryService,
 		notificationService: notificationService,
+		customerPurchases:   customerPurchases,
 	}
 }
 
-// CreateOrder crea una nueva orden y descuenta el stock
-func (s *OrderService) CreateOrder(ctx context.Context, req *models.CreateOrderRequest, userID *uuid.UUID) (*models.OrderWithItems, error) {
-	if len(req.Items) == 0 {
-		return nil, errors.New("la orden debe tener al menos un producto")
+// CreateOrder crea una nueva orden y descuenta el stock. cart es el carrito con el que
+// se compró (del usuario o del invitado); se vacía al crear la orden. Puede ser nil.
+func (s *OrderService) CreateOrder(ctx context.Context, req *models.CreateOrderRequest, userID *uuid.UUID, cart *models.CartOwner) (*models.OrderWithItems, error) {
+	priced, err := s.checkoutService.price(ctx, &models.CheckoutQuoteRequest{
+		Items:           req.Items,
+		DiscountCode:    req.DiscountCode,
+		ShippingAddress: &req.ShippingAddress,
+	}, purchaseCustomer{userID: userID, email: req.CustomerEmail})
+	if err != nil {
+		return nil, err
 	}
-
-	var subtotal float64
-	var orderItems []models.OrderItem
-
-	for _, itemReq := range req.Items {
-		product, err := s.productRepo.GetByID(ctx, itemReq.ProductID)
-		if err != nil {
-			return nil, fmt.Errorf("producto %s no encontrado", itemReq.ProductID)
-		}
-		if product.Stock < itemReq.Quantity {
-			return nil, fmt.Errorf("el producto %s no está disponible", product.Name)
-		}
-		itemSubtotal := product.Price * float64(itemReq.Quantity)
-		subtotal += itemSubtotal
-		orderItems = append(orderItems, models.OrderItem{
-			ProductID:   product.ID,
-			ProductName: product.Name,
-			Quantity:    itemReq.Quantity,
-			Price:       product.Price,
-			Subtotal:    itemSubtotal,
-		})
+	quote := priced.quote
+	if !quote.Valid {
+		return nil, errors.New(quote.Errors[0])
 	}
+	orderItems := priced.items
 
-	discount := 0.0
 	var discountCodeID *uuid.UUID
+	if priced.discountCode != nil {
+		discountCodeID = &priced.discountCode.ID
+	}
 
-	if req.DiscountCode != "" && s.discountService != nil {
-		validation, err := s.discountService.ValidateDiscountCode(ctx, &models.ValidateDiscountRequest{
-			Code:          req.DiscountCode,
-			PurchaseTotal: subtotal,
-		})
-		if err != nil {
-			return nil, fmt.Errorf("error al validar código de descuento: %w", err)
-		}
-		if !validation.Valid {
-			return nil, fmt.Errorf("código de descuento inválido: %s", validation.Message)
-		}
-		discount = validation.DiscountAmount
-		discountCodeID = &validation.DiscountCode.ID
-		if err := s.discountService.ApplyDiscountCode(ctx, validation.DiscountCode.ID); err != nil {
+	order := &models.Order{
+		ID:                uuid.New(), // Se genera antes para registrar el uso del código de descuento
+		UserID:            userID,
+		CustomerName:      req.CustomerName,
+		CustomerEmail:     req.CustomerEmail,
+		CustomerPhone:     req.CustomerPhone,
+		Subtotal:          quote.Subtotal,
+		Discount:          quote.Discount,
+		Promotions:        quote.Promotions,
+		PromotionDiscount: quote.PromotionDiscount,
+		Shipping:          quote.Shipping,
+		Tax:               quote.Tax,
+		Total:             quote.Total,
+		PaymentMethod:     req.PaymentMethod,
+		PaymentStatus:     models.PaymentPending,
+		Status:            models.OrderPending,
+		ShippingAddress:   &req.ShippingAddress,
+		DiscountCodeID:    discountCodeID,
+		UTMSource:         req.UTMSource,
+		UTMMedium:         req.UTMMedium,
+		UTMCampaign:       req.UTMCampaign,
+	}
+
+	// El canje es transaccional: si otra orden agotó el código, esta falla acá
+	if priced.discountCode != nil {
+		if err := s.discountService.RedeemDiscountCode(ctx, &models.DiscountRedemption{
+			DiscountCodeID: priced.discountCode.ID,
+			Code:           priced.discountCode.Code,
+			OrderID:        order.ID,
+			UserID:         userID,
+			Email:          req.CustomerEmail,
+			Amount:         quote.Discount - quote.PromotionDiscount,
+		}); err != nil {
 			return nil, fmt.Errorf("error al aplicar código de descuento: %w", err)
 		}
 	}
 
-	total := subtotal - discount
-
-	order := &models.Order{
-		UserID:          userID,
-		CustomerName:    req.CustomerName,
-		CustomerEmail:   req.CustomerEmail,
-		CustomerPhone:   req.CustomerPhone,
-		Subtotal:        subtotal,
-		Discount:        discount,
-		Total:           total,
-		PaymentMethod:   req.PaymentMethod,
-		PaymentStatus:   models.PaymentPending,
-		Status:          models.OrderPending,
-		ShippingAddress: &req.ShippingAddress,
-		DiscountCodeID:  discountCodeID,
-		UTMSource:       req.UTMSource,
-		UTMMedium:       req.UTMMedium,
-		UTMCampaign:     req.UTMCampaign,
-	}
+	reminder := s.cartRecoveryService.AttributeOrder(ctx, order)
 
 	if err := s.orderRepo.Create(ctx, order); err != nil {
+		s.discardOrder(ctx, order, false)
 		return nil, err
 	}
 
 	var savedItems []models.OrderItem
+	sales := make([]*models.StockChange, 0, len(orderItems))
 	for _, item := range orderItems {
 		item.OrderID = order.ID
 		if err := s.orderRepo.CreateOrderItem(ctx, &item); err != nil {
+			s.discardOrder(ctx, order, true)
 			return nil, err
 		}
 		savedItems = append(savedItems, item)
-		if err := s.productRepo.UpdateStock(ctx, item.ProductID, -item.Quantity); err != nil {
-			return nil, fmt.Errorf("error al actualizar stock: %v", err)
+		sales = append(sales, &models.StockChange{
+			ProductID:   item.ProductID,
+			VariantID:   item.VariantID,
+			Delta:       -item.Quantity,
+			Reason:      models.InventorySale,
+			Reference:   order.OrderNumber,
+			ActorID:     userID,
+			BundleItems: item.BundleItems,
+		})
+	}
+	// Los límites por cliente se verifican de nuevo en la transacción del stock:
+	// la verificación del checkout no ve las órdenes simultáneas del mismo cliente
+	var limits []*models.CustomerLimit
+	if keys := repository.CustomerPurchaseKeys(userID, req.CustomerEmail); len(keys) > 0 {
+		for _, limit := range priced.limits {
+			limit.CustomerKeys = keys
+			limit.OrderID = order.ID
+			limits = append(limits, limit)
+		}
+	}
+
+	// Todas las líneas en una sola transacción (y una sola invalidación de caché).
+	// Si falla (p. ej. otra venta se llevó el stock) la orden no queda registrada.
+	if _, err := s.inventoryService.ApplySale(ctx, sales, limits); err != nil {
+		s.discardOrder(ctx, order, true)
+		if errors.Is(err, repository.ErrCustomerLimitExceeded) {
+			return nil, err
 		}
+		return nil, fmt.Errorf("error al actualizar stock: %v", err)
 	}
+	s.cartRecoveryService.LinkOrder(ctx, reminder, order)
 
-	if userID != nil {
-		_ = s.cartRepo.Delete(ctx, *userID)
+	if cart != nil {
+		if err := s.cartRepo.DeleteByOwner(ctx, *cart); err != nil {
+			log.Printf("[Order] Error vaciando el carrito de la orden %s: %v", order.OrderNumber, err)
+		}
 	}
 
 	if s.dashboardService != nil {
@@ -136,6 +172,22 @@ func (s *OrderService) CreateOrder(ctx context.Context, req *models.CreateOrderR
 	return &models.OrderWithItems{Order: *order, Items: savedItems}, nil
 }
 
+// discardOrder deshace una orden que no se pudo completar: libera el uso del código de
+// descuento y, si ya se guardó, elimina la orden y sus items. Solo registra los errores,
+// porque el llamador ya está retornando el error original.
+func (s *OrderService) discardOrder(ctx context.Context, order *models.Order, saved bool) {
+	if order.DiscountCodeID != nil {
+		if err := s.discountService.ReleaseOrderRedemptions(ctx, order.ID); err != nil {
+			log.Printf("[Order] Error liberando el código de descuento de la orden fallida: %v", err)
+		}
+	}
+	if saved {
+		if err := s.orderRepo.Delete(ctx, order.ID); err != nil {
+			log.Printf("[Order] Error eliminando la orden fallida %s: %v", order.OrderNumber, err)
+		}
+	}
+}
+
 // GetOrderByID obtiene una orden por ID con sus items
 func (s *OrderService) GetOrderByID(ctx context.Context, id uuid.UUID) (*models.OrderWithItems, error) {
 	order, err := s.orderRepo.GetByID(ctx, id)
@@ -267,7 +319,7 @@ func (s *OrderService) GetAllOrders(ctx context.Context, page int, pageSize int,
 }
 
 // UpdateOrderStatus actualiza el estado de una orden y crea la notificación correspondiente
-func (s *OrderService) UpdateOrderStatus(ctx context.Context, id uuid.UUID, req *models.UpdateOrderStatusRequest) (*models.Order, error) {
+func (s *OrderService) UpdateOrderStatus(ctx context.Context, id uuid.UUID, req *models.UpdateOrderStatusRequest, actorID *uuid.UUID) (*models.Order, error) {
 	// 1. Obtener la orden actual
 	order, err := s.orderRepo.GetByID(ctx, id)
 	if err != nil {
@@ -290,11 +342,36 @@ func (s *OrderService) UpdateOrderStatus(ctx context.Context, id uuid.UUID, req
 		if err != nil {
 			return nil, err
 		}
+		returns := make([]*models.StockChange, 0, len(items))
 		for _, item := range items {
-			if err := s.productRepo.UpdateStock(ctx, item.ProductID, item.Quantity); err != nil {
+			returns = append(returns, &models.StockChange{
+				ProductID:   item.ProductID,
+				VariantID:   item.VariantID,
+				Delta:       item.Quantity,
+				Reason:      models.InventoryCancellationReturn,
+				Reference:   order.OrderNumber,
+				ActorID:     actorID,
+				BundleItems: item.BundleItems,
+			})
+		}
+		if len(returns) > 0 {
+			if _, err := s.inventoryService.ApplyStockChanges(ctx, returns); err != nil {
 				return nil, fmt.Errorf("error al devolver stock: %v", err)
 			}
 		}
+
+		// Las unidades canceladas dejan de contar para el límite por cliente
+		keys := repository.CustomerPurchaseKeys(order.UserID, order.CustomerEmail)
+		if err := s.customerPurchases.ReleaseOrder(ctx, keys, order.ID); err != nil {
+			log.Printf("[Order] Error liberando las compras por cliente de %s: %v", order.OrderNumber, err)
+		}
+
+		// Liberar el uso del código de descuento
+		if order.DiscountCodeID != nil && s.discountService != nil {
+			if err := s.discountService.ReleaseOrderRedemptions(ctx, order.ID); err != nil {
+				log.Printf("[Order] Error liberando el código de descuento de %s: %v", order.OrderNumber, err)
+			}
+		}
 	}
 
 	// 5. Asignar nuevo estado en memoria para la notificación
@@ -48,11 +48,10 @@ func (s *PasswordResetService) ForgotPassword(ctx context.Context, email string)
 	// Eliminar tokens previos del usuario (ignorar error si no hay tokens)
 	_ = s.resetRepo.DeleteByUserID(ctx, user.ID)
 
-	// Generar token JWT con role "password_reset" para distinguirlo de tokens de sesion
-	token, err := utils.GenerateToken(
+	// Generar token JWT con audiencia propia: no sirve como token de sesion
+	token, err := utils.GeneratePasswordResetToken(
 		user.ID,
 		user.Email,
-		"password_reset",
 		s.cfg.JWTSecret,
 		15*time.Minute,
 	)
@@ -93,7 +92,7 @@ func (s *PasswordResetService) ForgotPassword(ctx context.Context, email string)
 // ResetPassword valida el token y actualiza la contrasena del usuario
 func (s *PasswordResetService) ResetPassword(ctx context.Context, token string, newPassword string) error {
 	// 1. Validar el token JWT
-	claims, err := utils.ValidateToken(token, s.cfg.JWTSecret)
+	claims, err := utils.ValidatePasswordResetToken(token, s.cfg.JWTSecret)
 	if err != nil {
 		return errors.New("token invalido o expirado")
 	}
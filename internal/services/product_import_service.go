@@ -0,0 +1,585 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/cheoscafe/backend/internal/repository"
+	"github.com/cheoscafe/backend/internal/utils"
+	"github.com/google/uuid"
+)
+
+// Columnas del CSV de catálogo. La exportación usa las mismas para que el archivo
+// se pueda editar y volver a importar; has_variants es solo informativa.
+var productCSVColumns = []string{
+	"id", "sku", "name", "slug", "description", "category",
+	"price", "weight", "stock", "reorder_threshold",
+	"images", "tasting_notes", "meta_title", "meta_description",
+	"is_active", "is_featured",
+}
+
+// productCSVFields traduce el campo del request a su columna, para los errores de validación
+var productCSVFields = map[string]string{
+	"Name":             "name",
+	"Slug":             "slug",
+	"SKU":              "sku",
+	"Description":      "description",
+	"Price":            "price",
+	"Weight":           "weight",
+	"Stock":            "stock",
+	"ReorderThreshold": "reorder_threshold",
+	"CategoryID":       "category",
+	"Images":           "images",
+	"TastingNotes":     "tasting_notes",
+	"MetaTitle":        "meta_title",
+	"MetaDescription":  "meta_description",
+}
+
+const (
+	csvListSeparator   = "|" // Separa imágenes y notas de cata dentro de una celda
+	maxProductCSVRows  = 2000
+	productCSVStockRef = "Importación CSV"
+)
+
+// ProductImportService importa y exporta el catálogo en CSV.
+// Las altas y ediciones pasan por ProductService (mismas reglas que la API)
+// y los cambios de stock por InventoryService, para que queden en el ledger.
+type ProductImportService struct {
+	productService   *ProductService
+	inventoryService *InventoryService
+	productRepo      *repository.ProductRepository
+	categoryRepo     *repository.CategoryRepository
+}
+
+func NewProductImportService(
+	productService *ProductService,
+	inventoryService *InventoryService,
+	productRepo *repository.ProductRepository,
+	categoryRepo *repository.CategoryRepository,
+) *ProductImportService {
+	return &ProductImportService{
+		productService:   productService,
+		inventoryService: inventoryService,
+		productRepo:      productRepo,
+		categoryRepo:     categoryRepo,
+	}
+}
+
+// productImportPlan es lo que se aplicará para una fila válida
+type productImportPlan struct {
+	result     *models.ProductImportRow
+	create     *models.CreateProductRequest
+	update     *models.UpdateProductRequest
+	productID  uuid.UUID
+	stock      *int // Conteo a registrar como STOCKTAKE (solo productos existentes)
+	deactivate bool // Alta con is_active=false: se crea y luego se desactiva
+}
+
+// ExportProductsCSV escribe el catálogo completo, incluidos los productos inactivos
+func (s *ProductImportService) ExportProductsCSV(ctx context.Context, w io.Writer) error {
+	products, err := s.productRepo.ListAll(ctx)
+	if err != nil {
+		return fmt.Errorf("error al obtener productos: %w", err)
+	}
+
+	sort.Slice(products, func(i, j int) bool {
+		if products[i].Category != products[j].Category {
+			return products[i].Category < products[j].Category
+		}
+		return products[i].Name < products[j].Name
+	})
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append(append([]string{}, productCSVColumns...), "has_variants")); err != nil {
+		return err
+	}
+
+	for _, p := range products {
+		record := []string{
+			p.ID.String(),
+			p.SKU,
+			p.Name,
+			p.Slug,
+			p.Description,
+			p.Category,
+			strconv.FormatFloat(p.Price, 'f', -1, 64),
+			strconv.Itoa(p.Weight),
+			strconv.Itoa(p.Stock),
+			strconv.Itoa(p.ReorderThreshold),
+			strings.Join(p.Images, csvListSeparator),
+			strings.Join(p.TastingNotes, csvListSeparator),
+			p.MetaTitle,
+			p.MetaDescription,
+			strconv.FormatBool(p.IsActive),
+			strconv.FormatBool(p.IsFeatured),
+			strconv.FormatBool(p.HasVariants()),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// ImportProductsCSV valida todas las filas y, si ninguna tiene errores y no es dry run, las aplica.
+// Cada fila actualiza el producto indicado por id o sku; si no existe se crea.
+// En una actualización las celdas vacías dejan el valor actual.
+func (s *ProductImportService) ImportProductsCSV(ctx context.Context, r io.Reader, dryRun bool, actorID *uuid.UUID) (*models.ProductImportReport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("el archivo está vacío o no es un CSV válido")
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))) // BOM de Excel
+		columns[name] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		if _, ok := columns["id"]; !ok {
+			if _, ok := columns["sku"]; !ok {
+				return nil, errors.New("el CSV debe tener al menos una de las columnas id, sku o name")
+			}
+		}
+	}
+
+	categories, err := s.categoryRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener categorías: %w", err)
+	}
+
+	report := &models.ProductImportReport{DryRun: dryRun, Rows: []models.ProductImportRow{}}
+	plans := []*productImportPlan{}
+	seenIDs := make(map[uuid.UUID]int)
+	seenSKUs := make(map[string]int)
+	seenSlugs := make(map[string]int)
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error leyendo la línea %d: %w", line, err)
+		}
+		if report.TotalRows >= maxProductCSVRows {
+			return nil, fmt.Errorf("el archivo supera el máximo de %d filas", maxProductCSVRows)
+		}
+		report.TotalRows++
+
+		cell := func(column string) (string, bool) {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return "", false
+			}
+			value := strings.TrimSpace(record[i])
+			return value, value != ""
+		}
+
+		plan := s.planRow(ctx, line, cell, categories)
+		row := plan.result
+
+		// Duplicados dentro del mismo archivo
+		if row.ProductID != nil {
+			if first, ok := seenIDs[*row.ProductID]; ok {
+				row.Errors = append(row.Errors, fmt.Sprintf("el producto ya aparece en la fila %d", first))
+			} else {
+				seenIDs[*row.ProductID] = line
+			}
+		}
+		if row.SKU != "" {
+			if first, ok := seenSKUs[row.SKU]; ok {
+				row.Errors = append(row.Errors, fmt.Sprintf("sku: el SKU %s ya aparece en la fila %d", row.SKU, first))
+			} else {
+				seenSKUs[row.SKU] = line
+			}
+		}
+		if slug, ok := cell("slug"); ok {
+			slug = utils.Slugify(slug)
+			if first, ok := seenSlugs[slug]; ok {
+				row.Errors = append(row.Errors, fmt.Sprintf("slug: el slug %s ya aparece en la fila %d", slug, first))
+			} else {
+				seenSlugs[slug] = line
+			}
+		}
+
+		if len(row.Errors) > 0 {
+			row.Action = ""
+			report.Failed++
+		} else if row.Action == models.ProductImportCreate {
+			report.Created++
+		} else {
+			report.Updated++
+		}
+		plans = append(plans, plan)
+	}
+
+	for _, plan := range plans {
+		report.Rows = append(report.Rows, *plan.result)
+	}
+	if dryRun || report.Failed > 0 || report.TotalRows == 0 {
+		return report, nil
+	}
+
+	// Aplicar. Las filas ya fueron validadas, pero un error aquí (p. ej. un SKU tomado
+	// entre la validación y la escritura) se reporta en la fila sin detener el resto.
+	report.Applied = true
+	report.Created, report.Updated = 0, 0
+	for i, plan := range plans {
+		row := &report.Rows[i]
+		if err := s.applyPlan(ctx, plan, row, actorID); err != nil {
+			row.Errors = append(row.Errors, err.Error())
+			report.Failed++
+			continue
+		}
+		if row.Action == models.ProductImportCreate {
+			report.Created++
+		} else {
+			report.Updated++
+		}
+	}
+
+	return report, nil
+}
+
+// planRow valida una fila y arma el request de alta o edición
+func (s *ProductImportService) planRow(ctx context.Context, line int, cell func(string) (string, bool), categories []*models.Category) *productImportPlan {
+	row := &models.ProductImportRow{Row: line}
+	plan := &productImportPlan{result: row}
+	addError := func(format string, args ...interface{}) {
+		row.Errors = append(row.Errors, fmt.Sprintf(format, args...))
+	}
+
+	// Identificar el producto: por id, o por sku si no hay id
+	var existing *models.Product
+	if rawID, ok := cell("id"); ok {
+		id, err := uuid.Parse(rawID)
+		if err != nil {
+			addError("id: ID inválido")
+			return plan
+		}
+		existing, err = s.productRepo.GetByID(ctx, id)
+		if errors.Is(err, repository.ErrProductNotFound) {
+			addError("id: producto no encontrado")
+			return plan
+		}
+		if err != nil {
+			addError("id: error al buscar el producto: %v", err)
+			return plan
+		}
+	}
+
+	sku, hasSKU := cell("sku")
+	if hasSKU {
+		sku = strings.ToUpper(sku)
+		row.SKU = sku
+		// Un error de lectura no se toma como "SKU libre": la fila se crearía duplicada
+		owner, err := s.productRepo.GetBySKU(ctx, sku)
+		if err != nil && !errors.Is(err, repository.ErrProductNotFound) {
+			addError("sku: error al buscar el SKU %s: %v", sku, err)
+			return plan
+		}
+		if err == nil {
+			switch {
+			case owner.SKU != sku:
+				addError("sku: el SKU %s pertenece a una variante de %s", sku, owner.Name)
+			case existing != nil && owner.ID != existing.ID:
+				addError("sku: el SKU %s ya está asignado al producto %s", sku, owner.Name)
+			case existing == nil:
+				existing = owner
+			}
+		}
+	}
+
+	// Valores comunes a alta y edición
+	name, hasName := cell("name")
+	row.Name = name
+
+	var categoryID *uuid.UUID
+	if raw, ok := cell("category"); ok {
+		category := findCategory(categories, raw)
+		if category == nil {
+			addError("category: la categoría %q no existe", raw)
+		} else {
+			categoryID = &category.ID
+		}
+	}
+
+	price := parseCSVFloat(cell, "price", addError)
+	weight := parseCSVInt(cell, "weight", addError)
+	stock := parseCSVInt(cell, "stock", addError)
+	threshold := parseCSVInt(cell, "reorder_threshold", addError)
+	isActive := parseCSVBool(cell, "is_active", addError)
+	isFeatured := parseCSVBool(cell, "is_featured", addError)
+
+	images := splitCSVList(cell, "images")
+	for _, image := range images {
+		if u, err := url.ParseRequestURI(image); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			addError("images: %q no es una URL válida", image)
+		}
+	}
+	tastingNotes := splitCSVList(cell, "tasting_notes")
+
+	slug, hasSlug := cell("slug")
+	if hasSlug {
+		if normalized := utils.Slugify(slug); normalized == "" {
+			addError("slug: slug inválido")
+		} else if owner, err := s.productRepo.GetBySlug(ctx, normalized); err != nil && !errors.Is(err, repository.ErrProductNotFound) {
+			addError("slug: error al buscar el slug %s: %v", normalized, err)
+		} else if err == nil && (existing == nil || owner.ID != existing.ID) {
+			addError("slug: el slug %s ya está en uso por %s", normalized, owner.Name)
+		}
+	}
+
+	description, _ := cell("description")
+	metaTitle, hasMetaTitle := cell("meta_title")
+	metaDescription, hasMetaDescription := cell("meta_description")
+
+	if existing == nil {
+		// Alta
+		req := &models.CreateProductRequest{
+			Name:            name,
+			Slug:            slug,
+			SKU:             sku,
+			Description:     description,
+			Images:          images,
+			TastingNotes:    tastingNotes,
+			MetaTitle:       metaTitle,
+			MetaDescription: metaDescription,
+		}
+		if categoryID != nil {
+			req.CategoryID = *categoryID
+		}
+		if price != nil {
+			req.Price = *price
+		}
+		if weight != nil {
+			req.Weight = *weight
+		}
+		if stock != nil {
+			req.Stock = *stock
+		}
+		if threshold != nil {
+			req.ReorderThreshold = *threshold
+		}
+		if isFeatured != nil {
+			req.IsFeatured = *isFeatured
+		}
+		plan.deactivate = isActive != nil && !*isActive
+
+		row.Errors = append(row.Errors, csvValidationErrors(req)...)
+		row.Action = models.ProductImportCreate
+		plan.create = req
+		return plan
+	}
+
+	// Edición: solo las celdas con valor
+	productID := existing.ID
+	row.ProductID = &productID
+	if row.Name == "" {
+		row.Name = existing.Name
+	}
+	req := &models.UpdateProductRequest{
+		Images:       images,
+		TastingNotes: tastingNotes,
+		CategoryID:   categoryID,
+		IsActive:     isActive,
+		IsFeatured:   isFeatured,
+	}
+	if hasName {
+		req.Name = &name
+	}
+	if hasSlug {
+		req.Slug = &slug
+	}
+	if hasSKU {
+		req.SKU = &sku
+	}
+	if description != "" {
+		req.Description = &description
+	}
+	if hasMetaTitle {
+		req.MetaTitle = &metaTitle
+	}
+	if hasMetaDescription {
+		req.MetaDescription = &metaDescription
+	}
+	if threshold != nil {
+		req.ReorderThreshold = threshold
+	}
+
+	if existing.HasVariants() {
+		for column, value := range map[string]bool{"price": price != nil, "weight": weight != nil, "stock": stock != nil} {
+			if value {
+				row.Warnings = append(row.Warnings, fmt.Sprintf("%s: se ignora, el producto se gestiona por variantes", column))
+			}
+		}
+		sort.Strings(row.Warnings)
+	} else {
+		req.Price = price
+		req.Weight = weight
+		if existing.IsBundle() {
+			if stock != nil {
+				row.Warnings = append(row.Warnings, "stock: se ignora, el stock del kit se deriva de sus componentes")
+			}
+		} else if stock != nil && *stock != existing.Stock {
+			if *stock < 0 {
+				addError("stock: Debe ser mayor o igual a 0")
+			}
+			plan.stock = stock
+		}
+	}
+
+	row.Errors = append(row.Errors, csvValidationErrors(req)...)
+	row.Action = models.ProductImportUpdate
+	plan.update = req
+	plan.productID = productID
+	return plan
+}
+
+// applyPlan escribe una fila ya validada
+func (s *ProductImportService) applyPlan(ctx context.Context, plan *productImportPlan, row *models.ProductImportRow, actorID *uuid.UUID) error {
+	if plan.create != nil {
+		product, err := s.productService.CreateProduct(ctx, plan.create, actorID)
+		if err != nil {
+			return err
+		}
+		row.ProductID = &product.ID
+
+		if plan.deactivate {
+			inactive := false
+			if _, err := s.productService.UpdateProduct(ctx, product.ID.String(), &models.UpdateProductRequest{IsActive: &inactive}, actorID); err != nil {
+				return fmt.Errorf("producto creado pero no se pudo desactivar: %w", err)
+			}
+		}
+		return nil
+	}
+
+	if _, err := s.productService.UpdateProduct(ctx, plan.productID.String(), plan.update, actorID); err != nil {
+		return err
+	}
+
+	if plan.stock != nil {
+		_, err := s.inventoryService.ApplyStockChange(ctx, &models.StockChange{
+			ProductID: plan.productID,
+			SetTo:     plan.stock,
+			Reason:    models.InventoryStocktake,
+			Reference: productCSVStockRef,
+			ActorID:   actorID,
+		})
+		if err != nil {
+			return fmt.Errorf("producto actualizado pero no se pudo ajustar el stock: %w", err)
+		}
+	}
+	return nil
+}
+
+// findCategory busca una categoría por ID, slug o nombre (sin distinguir mayúsculas ni tildes)
+func findCategory(categories []*models.Category, value string) *models.Category {
+	slug := utils.Slugify(value)
+	normalized := utils.NormalizeText(value)
+	for _, c := range categories {
+		if c.ID.String() == value || c.Slug == slug || utils.NormalizeText(c.Name) == normalized {
+			return c
+		}
+	}
+	return nil
+}
+
+// csvValidationErrors convierte los errores de los tags validate en mensajes por columna
+func csvValidationErrors(req interface{}) []string {
+	err := utils.ValidateStruct(req)
+	if err == nil {
+		return nil
+	}
+
+	fields := utils.FormatValidationErrors(err)
+	if len(fields) == 0 {
+		return []string{err.Error()}
+	}
+
+	messages := make([]string, 0, len(fields))
+	for field, msg := range fields {
+		base := field
+		if i := strings.Index(base, "["); i >= 0 {
+			base = base[:i] // TastingNotes[0] → TastingNotes
+		}
+		column, ok := productCSVFields[base]
+		if !ok {
+			column = field
+		}
+		messages = append(messages, column+": "+msg)
+	}
+	sort.Strings(messages)
+	return messages
+}
+
+func splitCSVList(cell func(string) (string, bool), column string) []string {
+	raw, ok := cell(column)
+	if !ok {
+		return nil
+	}
+	items := []string{}
+	for _, item := range strings.Split(raw, csvListSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseCSVFloat(cell func(string) (string, bool), column string, addError func(string, ...interface{})) *float64 {
+	raw, ok := cell(column)
+	if !ok {
+		return nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		addError("%s: %q no es un número válido", column, raw)
+		return nil
+	}
+	return &value
+}
+
+func parseCSVInt(cell func(string) (string, bool), column string, addError func(string, ...interface{})) *int {
+	raw, ok := cell(column)
+	if !ok {
+		return nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		addError("%s: %q no es un número entero válido", column, raw)
+		return nil
+	}
+	return &value
+}
+
+func parseCSVBool(cell func(string) (string, bool), column string, addError func(string, ...interface{})) *bool {
+	raw, ok := cell(column)
+	if !ok {
+		return nil
+	}
+	switch utils.NormalizeText(raw) {
+	case "true", "1", "si", "yes":
+		value := true
+		return &value
+	case "false", "0", "no":
+		value := false
+		return &value
+	}
+	addError("%s: %q debe ser true o false", column, raw)
+	return nil
+}
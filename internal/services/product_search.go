@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/cheoscafe/backend/internal/repository"
+	"github.com/cheoscafe/backend/internal/utils"
+	"github.com/google/uuid"
+)
+
+// Peso de cada campo del producto en el puntaje de relevancia
+const (
+	searchWeightName        = 5.0
+	searchWeightCategory    = 3.0
+	searchWeightTasting     = 2.0
+	searchWeightVariant     = 2.0
+	searchWeightCoffee      = 2.0 // Origen, finca, variedad, proceso, tueste y métodos
+	searchWeightDescription = 1.0
+)
+
+// Factor aplicado según el tipo de coincidencia del término
+const (
+	searchMatchExact  = 1.0
+	searchMatchPrefix = 0.7
+	searchMatchFuzzy  = 0.4
+)
+
+// searchIndexTTL es cada cuánto se reconstruye el índice completo desde Firestore.
+// Los cambios hechos en esta instancia se aplican al instante; la reconstrucción
+// recoge los hechos por otras instancias o directamente en la base de datos.
+const searchIndexTTL = 10 * time.Minute
+
+// searchDoc es la representación indexada de un producto
+type searchDoc struct {
+	active bool
+	terms  map[string]float64 // término → peso acumulado en el producto
+}
+
+// ProductSearchIndex es un índice invertido en memoria sobre el catálogo.
+// Soporta búsqueda sin tildes ni mayúsculas, coincidencia por prefijo
+// y tolerancia a errores de tipeo, ordenando los resultados por relevancia.
+type ProductSearchIndex struct {
+	productRepo *repository.ProductRepository
+
+	mu       sync.RWMutex
+	docs     map[uuid.UUID]*searchDoc
+	postings map[string]map[uuid.UUID]float64 // término → producto → peso
+	terms    []string                         // términos ordenados, para buscar por prefijo
+	dirty    bool                             // terms debe reordenarse
+	loadedAt time.Time
+}
+
+func NewProductSearchIndex(productRepo *repository.ProductRepository) *ProductSearchIndex {
+	return &ProductSearchIndex{
+		productRepo: productRepo,
+		docs:        make(map[uuid.UUID]*searchDoc),
+		postings:    make(map[string]map[uuid.UUID]float64),
+	}
+}
+
+// Search retorna los IDs de los productos activos que coinciden con la consulta,
+// ordenados por relevancia. Cada palabra de la consulta puede coincidir de forma
+// exacta, por prefijo o con hasta 1-2 errores de tipeo según su longitud.
+func (idx *ProductSearchIndex) Search(ctx context.Context, query string, limit int) ([]uuid.UUID, error) {
+	if err := idx.ensureLoaded(ctx); err != nil {
+		return nil, err
+	}
+
+	queryTokens := utils.Tokenize(query)
+	if len(queryTokens) == 0 {
+		return []uuid.UUID{}, nil
+	}
+
+	idx.mu.Lock()
+	if idx.dirty {
+		idx.sortTerms()
+	}
+	idx.mu.Unlock()
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	scores := make(map[uuid.UUID]float64)
+	matched := make(map[uuid.UUID]int)
+
+	for _, token := range queryTokens {
+		tokenScores := make(map[uuid.UUID]float64)
+		for term, factor := range idx.expandToken(token) {
+			for productID, weight := range idx.postings[term] {
+				if score := weight * factor; score > tokenScores[productID] {
+					tokenScores[productID] = score
+				}
+			}
+		}
+		for productID, score := range tokenScores {
+			scores[productID] += score
+			matched[productID]++
+		}
+	}
+
+	ids := make([]uuid.UUID, 0, len(scores))
+	for productID := range scores {
+		if doc, ok := idx.docs[productID]; ok && doc.active {
+			ids = append(ids, productID)
+		}
+	}
+
+	// Primero los que coinciden con más palabras de la consulta, luego por puntaje
+	sort.Slice(ids, func(i, j int) bool {
+		if matched[ids[i]] != matched[ids[j]] {
+			return matched[ids[i]] > matched[ids[j]]
+		}
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i].String() < ids[j].String()
+	})
+
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
+// Upsert indexa (o reindexa) un producto
+func (idx *ProductSearchIndex) Upsert(product *models.Product) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(product.ID)
+	idx.addLocked(product)
+}
+
+// Remove saca un producto del índice
+func (idx *ProductSearchIndex) Remove(productID uuid.UUID) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(productID)
+}
+
+// Invalidate fuerza la reconstrucción del índice en la próxima búsqueda
+func (idx *ProductSearchIndex) Invalidate() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.loadedAt = time.Time{}
+}
+
+// ensureLoaded reconstruye el índice si nunca se cargó o si venció su TTL
+func (idx *ProductSearchIndex) ensureLoaded(ctx context.Context) error {
+	idx.mu.RLock()
+	fresh := !idx.loadedAt.IsZero() && time.Since(idx.loadedAt) < searchIndexTTL
+	idx.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	products, err := idx.productRepo.ListAll(ctx)
+	if err != nil {
+		idx.mu.RLock()
+		loaded := !idx.loadedAt.IsZero()
+		idx.mu.RUnlock()
+		if loaded {
+			// Seguir con el índice anterior antes que fallar la búsqueda
+			log.Printf("Warning: no se pudo reconstruir el índice de búsqueda: %v", err)
+			return nil
+		}
+		return err
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.docs = make(map[uuid.UUID]*searchDoc, len(products))
+	idx.postings = make(map[string]map[uuid.UUID]float64)
+	for _, product := range products {
+		idx.addLocked(product)
+	}
+	idx.sortTerms()
+	idx.loadedAt = time.Now()
+
+	return nil
+}
+
+func (idx *ProductSearchIndex) addLocked(product *models.Product) {
+	doc := &searchDoc{
+		active: product.IsActive,
+		terms:  make(map[string]float64),
+	}
+
+	addField := func(text string, weight float64) {
+		for _, token := range utils.Tokenize(text) {
+			doc.terms[token] += weight
+		}
+	}
+
+	addField(product.Name, searchWeightName)
+	addField(product.Category, searchWeightCategory)
+	addField(strings.Join(product.TastingNotes, " "), searchWeightTasting)
+	addField(product.Description, searchWeightDescription)
+	addField(product.SKU, searchWeightVariant)
+	if c := product.Coffee; c != nil {
+		addField(c.OriginRegion+" "+c.Farm, searchWeightCoffee)
+		addField(strings.Join(c.Varieties, " "), searchWeightCoffee)
+		addField(string(c.Process)+" "+models.CoffeeProcessLabels[c.Process], searchWeightCoffee)
+		addField(models.RoastLevelLabels[c.RoastLevel], searchWeightCoffee)
+		for _, m := range c.BrewMethods {
+			addField(models.BrewMethodLabels[m], searchWeightCoffee)
+		}
+	}
+	for _, v := range product.Variants {
+		addField(product.VariantLabel(&v), searchWeightVariant)
+		addField(v.SKU, searchWeightVariant)
+	}
+
+	for term, weight := range doc.terms {
+		postings, ok := idx.postings[term]
+		if !ok {
+			postings = make(map[uuid.UUID]float64)
+			idx.postings[term] = postings
+			idx.dirty = true
+		}
+		postings[product.ID] = weight
+	}
+	idx.docs[product.ID] = doc
+}
+
+func (idx *ProductSearchIndex) removeLocked(productID uuid.UUID) {
+	doc, ok := idx.docs[productID]
+	if !ok {
+		return
+	}
+	for term := range doc.terms {
+		postings := idx.postings[term]
+		delete(postings, productID)
+		if len(postings) == 0 {
+			delete(idx.postings, term)
+			idx.dirty = true
+		}
+	}
+	delete(idx.docs, productID)
+}
+
+func (idx *ProductSearchIndex) sortTerms() {
+	idx.terms = make([]string, 0, len(idx.postings))
+	for term := range idx.postings {
+		idx.terms = append(idx.terms, term)
+	}
+	sort.Strings(idx.terms)
+	idx.dirty = false
+}
+
+// expandToken retorna los términos del índice que coinciden con el token
+// de la consulta junto con el factor de su tipo de coincidencia.
+func (idx *ProductSearchIndex) expandToken(token string) map[string]float64 {
+	matches := make(map[string]float64)
+	if _, ok := idx.postings[token]; ok {
+		matches[token] = searchMatchExact
+	}
+
+	// Prefijo: "colom" → "colombia", "colombiano"
+	if len([]rune(token)) >= 2 {
+		start := sort.SearchStrings(idx.terms, token)
+		for i := start; i < len(idx.terms) && strings.HasPrefix(idx.terms[i], token); i++ {
+			if _, ok := matches[idx.terms[i]]; !ok {
+				matches[idx.terms[i]] = searchMatchPrefix
+			}
+		}
+	}
+
+	// Errores de tipeo: "cafw" → "cafe"
+	maxEdits := typoTolerance(token)
+	if maxEdits == 0 {
+		return matches
+	}
+	for _, term := range idx.terms {
+		if _, ok := matches[term]; ok {
+			continue
+		}
+		if abs(len(term)-len(token)) > maxEdits {
+			continue
+		}
+		if levenshtein(token, term, maxEdits) <= maxEdits {
+			matches[term] = searchMatchFuzzy
+		}
+	}
+	return matches
+}
+
+// typoTolerance define cuántos errores se aceptan según la longitud de la palabra
+func typoTolerance(token string) int {
+	switch n := len([]rune(token)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// levenshtein calcula la distancia de edición entre a y b.
+// Corta en cuanto la distancia supera max para no recorrer términos lejanos.
+func levenshtein(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if curr[j] < rowMin {
+				rowMin = curr[j]
+			}
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -4,7 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/cheoscafe/backend/internal/config"
 	"github.com/cheoscafe/backend/internal/models"
 	"github.com/cheoscafe/backend/internal/repository"
 	"github.com/cheoscafe/backend/internal/utils"
@@ -12,24 +17,50 @@ import (
 )
 
 type ProductService struct {
-	productRepo *repository.ProductRepository
+	productRepo      *repository.ProductRepository
+	categoryRepo     *repository.CategoryRepository
+	dashboardRepo    *repository.DashboardRepository
+	priceHistoryRepo *repository.PriceHistoryRepository
+	inventoryService *InventoryService
+	wishlistService  *WishlistService
+	searchIndex      *ProductSearchIndex
+	cache            *CacheService
+	cfg              *config.Config
 }
 
-func NewProductService(productRepo *repository.ProductRepository) *ProductService {
+func NewProductService(
+	productRepo *repository.ProductRepository,
+	categoryRepo *repository.CategoryRepository,
+	dashboardRepo *repository.DashboardRepository,
+	priceHistoryRepo *repository.PriceHistoryRepository,
+	inventoryService *InventoryService,
+	wishlistService *WishlistService,
+	searchIndex *ProductSearchIndex,
+	cache *CacheService,
+	cfg *config.Config,
+) *ProductService {
 	return &ProductService{
-		productRepo: productRepo,
+		productRepo:      productRepo,
+		categoryRepo:     categoryRepo,
+		dashboardRepo:    dashboardRepo,
+		priceHistoryRepo: priceHistoryRepo,
+		inventoryService: inventoryService,
+		wishlistService:  wishlistService,
+		searchIndex:      searchIndex,
+		cache:            cache,
+		cfg:              cfg,
 	}
 }
 
 // CreateProduct crea un nuevo producto (solo admin)
-func (s *ProductService) CreateProduct(ctx context.Context, req *models.CreateProductRequest) (*models.Product, error) {
+func (s *ProductService) CreateProduct(ctx context.Context, req *models.CreateProductRequest, actorID *uuid.UUID) (*models.Product, error) {
 	// Validar request
 	if err := utils.ValidateStruct(req); err != nil {
 		return nil, err
 	}
 
-	// Validar precio
-	if req.Price <= 0 {
+	// Validar precio (con variantes, el precio sale de ellas)
+	if len(req.Variants) == 0 && req.Price <= 0 {
 		return nil, errors.New("el precio debe ser mayor a 0")
 	}
 
@@ -37,22 +68,115 @@ func (s *ProductService) CreateProduct(ctx context.Context, req *models.CreatePr
 	if req.Stock < 0 {
 		return nil, errors.New("el stock no puede ser negativo")
 	}
+	if len(req.BundleItems) > 0 {
+		if len(req.Variants) > 0 {
+			return nil, errors.New("un kit no puede tener variantes")
+		}
+		if req.Stock != 0 {
+			return nil, errors.New("el stock de un kit se deriva de sus componentes")
+		}
+	}
+
+	category, err := s.categoryRepo.GetByID(ctx, req.CategoryID)
+	if err != nil {
+		return nil, err
+	}
 
 	product := &models.Product{
-		Name:        req.Name,
-		Description: req.Description,
-		Price:       req.Price,
-		Stock:       req.Stock,
-		Category:    req.Category,
-		Weight:      req.Weight,
-		Images:      req.Images,
-		IsFeatured:  req.IsFeatured,
-		IsActive:    true,
+		ID:               uuid.New(),
+		Name:             req.Name,
+		SKU:              strings.ToUpper(strings.TrimSpace(req.SKU)),
+		Description:      req.Description,
+		Price:            req.Price,
+		Stock:            req.Stock,
+		ReorderThreshold: req.ReorderThreshold,
+		CategoryID:       &category.ID,
+		Category:         category.Name,
+		Weight:           req.Weight,
+		Images:           req.Images,
+		TastingNotes:     req.TastingNotes,
+		MetaTitle:        req.MetaTitle,
+		MetaDescription:  req.MetaDescription,
+		IsFeatured:       req.IsFeatured,
+		IsActive:         true,
+		SalePrice:        req.SalePrice,
+		SaleStartsAt:     req.SaleStartsAt,
+		SaleEndsAt:       req.SaleEndsAt,
+	}
+
+	if req.Coffee != nil {
+		coffee, err := coffeeFromRequest(req.Coffee)
+		if err != nil {
+			return nil, err
+		}
+		product.Coffee = coffee
+	}
+	if req.PurchaseRules != nil {
+		rules, err := purchaseRulesFromRequest(req.PurchaseRules)
+		if err != nil {
+			return nil, err
+		}
+		product.PurchaseRules = rules
+	}
+
+	slug, err := s.resolveProductSlug(ctx, product.ID, req.Slug, product.Name)
+	if err != nil {
+		return nil, err
+	}
+	product.Slug = slug
+	s.applySEODefaults(product, "", "")
+
+	if len(req.Variants) > 0 {
+		options, variants, err := buildVariants(req.Options, req.Variants, nil)
+		if err != nil {
+			return nil, err
+		}
+		product.Options = options
+		product.Variants = variants
+		applyVariantAggregates(product)
+	}
+	if len(req.BundleItems) > 0 {
+		if err := s.applyBundle(ctx, product, req.BundleItems); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateProductSale(product); err != nil {
+		return nil, err
+	}
+
+	if err := s.ensureUniqueSKUs(ctx, product); err != nil {
+		return nil, err
+	}
+
+	// El producto nace sin stock; el inicial entra como movimiento para que quede en el ledger.
+	// Un kit no tiene stock propio: se guarda el derivado de sus componentes.
+	var openingStock []*models.StockChange
+	if !product.IsBundle() {
+		openingStock = stockTargets(product, actorID, "Stock inicial")
+		product.Stock = 0
+		for i := range product.Variants {
+			product.Variants[i].Stock = 0
+		}
 	}
 
 	if err := s.productRepo.Create(ctx, product); err != nil {
 		return nil, fmt.Errorf("error al crear producto: %w", err)
 	}
+	if err := s.applyStockTargets(ctx, product, openingStock); err != nil {
+		return nil, fmt.Errorf("producto creado pero no se pudo registrar el stock inicial: %w", err)
+	}
+	if product.IsBundle() && s.inventoryService != nil {
+		// Recalcula el kit por si un componente se movió entre applyBundle y el guardado
+		components := make([]uuid.UUID, 0, len(product.BundleItems))
+		for _, item := range product.BundleItems {
+			components = append(components, item.ProductID)
+		}
+		s.inventoryService.RefreshBundles(ctx, components)
+	}
+	product.ApplyPricing(time.Now())
+	s.recordPriceChanges(ctx, nil, product, actorID)
+	s.searchIndex.Upsert(product)
+	s.cache.InvalidateCatalog(ctx)
 
 	return product, nil
 }
@@ -64,60 +188,187 @@ func (s *ProductService) GetProduct(ctx context.Context, productID string) (*mod
 		return nil, errors.New("ID de producto inválido")
 	}
 
+	key := cacheKeyProduct(id)
+	var cached models.Product
+	if s.cache.Get(ctx, key, &cached) {
+		cached.ApplyPricing(time.Now())
+		return &cached, nil
+	}
+
 	product, err := s.productRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	s.cache.Set(ctx, key, product, s.cache.ProductTTL)
 
 	return product, nil
 }
 
-// GetAllProducts obtiene todos los productos con paginación
-func (s *ProductService) GetAllProducts(ctx context.Context, page, pageSize int) (*models.PaginatedProductsResponse, error) {
-	if page < 1 {
-		page = 1
+// GetProductBySlug obtiene un producto por su slug. Si el slug es uno anterior
+// (el producto fue renombrado), retorna el producto y su slug actual para redirigir.
+func (s *ProductService) GetProductBySlug(ctx context.Context, slug string) (*models.Product, string, error) {
+	key := cacheKeySlug(slug)
+	var cached cachedSlugLookup
+	if s.cache.Get(ctx, key, &cached) && cached.Product != nil {
+		cached.Product.ApplyPricing(time.Now())
+		return cached.Product, cached.CurrentSlug, nil
 	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 10
+
+	product, err := s.productRepo.GetBySlug(ctx, slug)
+	if err == nil {
+		s.cache.Set(ctx, key, cachedSlugLookup{Product: product}, s.cache.ProductTTL)
+		return product, "", nil
 	}
 
-	offset := (page - 1) * pageSize
+	product, err = s.productRepo.GetByPreviousSlug(ctx, slug)
+	if err != nil {
+		return nil, "", err
+	}
+	s.cache.Set(ctx, key, cachedSlugLookup{Product: product, CurrentSlug: product.Slug}, s.cache.ProductTTL)
+	return product, product.Slug, nil
+}
 
-	products, err := s.productRepo.GetAll(ctx, pageSize, offset)
+// cachedSlugLookup es la entrada de caché de GetProductBySlug (incluye la redirección)
+type cachedSlugLookup struct {
+	Product     *models.Product `json:"product"`
+	CurrentSlug string          `json:"current_slug"`
+}
+
+// BackfillSlugs genera slug, meta tags y URL canónica para los productos creados antes
+// de que existieran. Es idempotente: los productos con slug se omiten.
+func (s *ProductService) BackfillSlugs(ctx context.Context) (int, error) {
+	products, err := s.productRepo.ListAll(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error al obtener productos: %w", err)
+		return 0, fmt.Errorf("error al obtener productos: %w", err)
+	}
+
+	updated := 0
+	for _, product := range products {
+		if product.Slug != "" {
+			continue
+		}
+
+		slug, err := s.resolveProductSlug(ctx, product.ID, "", product.Name)
+		if err != nil {
+			return updated, fmt.Errorf("producto %s: %w", product.ID, err)
+		}
+		product.Slug = slug
+		s.applySEODefaults(product, "", "")
+
+		if err := s.productRepo.Update(ctx, product); err != nil {
+			return updated, fmt.Errorf("error al actualizar producto %s: %w", product.ID, err)
+		}
+		updated++
+	}
+	if updated > 0 {
+		s.cache.InvalidateCatalog(ctx)
+	}
+
+	return updated, nil
+}
+
+// GetAllProducts obtiene el catálogo filtrado, ordenado y paginado, junto con
+// las facetas por categoría y rango de precio.
+// El filtrado se hace en memoria para no depender de índices compuestos en Firestore.
+func (s *ProductService) GetAllProducts(ctx context.Context, filter *models.ProductFilter) (*models.PaginatedProductsResponse, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 10
+	}
+	if filter.Sort == "" {
+		filter.Sort = models.ProductSortNewest
+	}
+	if !validProductSort(filter.Sort) {
+		return nil, fmt.Errorf("ordenamiento inválido: %s", filter.Sort)
 	}
 
-	total, err := s.productRepo.CountProducts(ctx)
+	key := cacheKey(cacheCatalogListPrefix, filter)
+	var cached models.PaginatedProductsResponse
+	if s.cache.Get(ctx, key, &cached) {
+		for _, p := range cached.Products {
+			p.ApplyPricing(time.Now())
+		}
+		return &cached, nil
+	}
+
+	categories, err := s.categoryRepo.GetAll(ctx)
 	if err != nil {
-		total = int64(len(products)) // Fallback
+		return nil, fmt.Errorf("error al obtener categorías: %w", err)
+	}
+	if filter.Category != "" {
+		categoryIDs, err := resolveCategoryFilter(categories, filter.Category)
+		if err != nil {
+			return nil, err
+		}
+		filter.CategoryIDs = categoryIDs
 	}
 
-	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	all, err := s.productRepo.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener productos: %w", err)
+	}
+
+	products := make([]*models.Product, 0, len(all))
+	for _, p := range all {
+		if matchesProductFilter(p, filter, true, true) {
+			products = append(products, p)
+		}
+	}
+
+	if err := s.sortProducts(ctx, products, filter.Sort); err != nil {
+		return nil, err
+	}
+
+	total := len(products)
+	totalPages := (total + filter.PageSize - 1) / filter.PageSize
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if offset > total {
+		offset = total
+	}
+	limit := offset + filter.PageSize
+	if limit > total {
+		limit = total
+	}
 
-	return &models.PaginatedProductsResponse{
-		Products:    products,
-		Total:       int(total),
-		Page:        page,
-		PageSize:    pageSize,
+	response := &models.PaginatedProductsResponse{
+		Products:    products[offset:limit],
+		Total:       total,
+		Page:        filter.Page,
+		PageSize:    filter.PageSize,
 		TotalPages:  totalPages,
-		HasNext:     page < totalPages,
-		HasPrevious: page > 1,
-	}, nil
+		HasNext:     filter.Page < totalPages,
+		HasPrevious: filter.Page > 1,
+		Facets:      buildProductFacets(all, categories, filter),
+	}
+	s.cache.Set(ctx, key, response, s.cache.CatalogTTL)
+
+	return response, nil
 }
 
 // GetFeaturedProducts obtiene productos destacados
 func (s *ProductService) GetFeaturedProducts(ctx context.Context) ([]*models.Product, error) {
+	var cached []*models.Product
+	if s.cache.Get(ctx, cacheKeyFeatured, &cached) {
+		for _, p := range cached {
+			p.ApplyPricing(time.Now())
+		}
+		return cached, nil
+	}
+
 	products, err := s.productRepo.GetFeatured(ctx, 8)
 	if err != nil {
 		return nil, fmt.Errorf("error al obtener productos destacados: %w", err)
 	}
+	s.cache.Set(ctx, cacheKeyFeatured, products, s.cache.CatalogTTL)
 
 	return products, nil
 }
 
 // UpdateProduct actualiza un producto (solo admin)
-func (s *ProductService) UpdateProduct(ctx context.Context, productID string, req *models.UpdateProductRequest) (*models.Product, error) {
+func (s *ProductService) UpdateProduct(ctx context.Context, productID string, req *models.UpdateProductRequest, actorID *uuid.UUID) (*models.Product, error) {
 	id, err := uuid.Parse(productID)
 	if err != nil {
 		return nil, errors.New("ID de producto inválido")
@@ -134,6 +385,13 @@ func (s *ProductService) UpdateProduct(ctx context.Context, productID string, re
 		return nil, err
 	}
 
+	previousPrices := productPriceStates(product)
+	wasBundle := product.IsBundle()
+
+	// Los meta tags generados automáticamente se regeneran si cambia el nombre o la descripción
+	prevMetaTitle, prevMetaDescription := defaultMetaTitle(product), defaultMetaDescription(product)
+	oldName := product.Name
+
 	// Actualizar campos
 	if req.Name != nil {
 		product.Name = *req.Name
@@ -141,27 +399,97 @@ func (s *ProductService) UpdateProduct(ctx context.Context, productID string, re
 	if req.Description != nil {
 		product.Description = *req.Description
 	}
+	if req.SKU != nil {
+		product.SKU = strings.ToUpper(strings.TrimSpace(*req.SKU))
+	}
+	if req.MetaTitle != nil {
+		product.MetaTitle = *req.MetaTitle
+		prevMetaTitle = ""
+	}
+	if req.MetaDescription != nil {
+		product.MetaDescription = *req.MetaDescription
+		prevMetaDescription = ""
+	}
+
+	// Renombrar regenera el slug; el anterior queda como redirección
+	if req.Slug != nil || product.Slug == "" || product.Name != oldName {
+		requested := ""
+		if req.Slug != nil {
+			requested = *req.Slug
+		}
+		slug, err := s.resolveProductSlug(ctx, product.ID, requested, product.Name)
+		if err != nil {
+			return nil, err
+		}
+		changeProductSlug(product, slug)
+	}
+	s.applySEODefaults(product, prevMetaTitle, prevMetaDescription)
 	if req.Price != nil {
 		if *req.Price <= 0 {
 			return nil, errors.New("el precio debe ser mayor a 0")
 		}
 		product.Price = *req.Price
 	}
+	if req.RemoveSale {
+		product.SalePrice, product.SaleStartsAt, product.SaleEndsAt = nil, nil, nil
+	}
+	if req.SalePrice != nil {
+		product.SalePrice = req.SalePrice
+	}
+	if req.SaleStartsAt != nil {
+		product.SaleStartsAt = req.SaleStartsAt
+	}
+	if req.SaleEndsAt != nil {
+		product.SaleEndsAt = req.SaleEndsAt
+	}
 	if req.Stock != nil {
 		if *req.Stock < 0 {
 			return nil, errors.New("el stock no puede ser negativo")
 		}
+		if product.HasVariants() && req.Variants == nil {
+			return nil, errors.New("el stock de un producto con variantes se gestiona por variante")
+		}
+		if (product.IsBundle() && req.BundleItems == nil) || len(req.BundleItems) > 0 {
+			return nil, errors.New("el stock de un kit se deriva de sus componentes")
+		}
 		product.Stock = *req.Stock
 	}
-	if req.Category != nil {
-		product.Category = *req.Category
+	if req.CategoryID != nil {
+		category, err := s.categoryRepo.GetByID(ctx, *req.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+		product.CategoryID = &category.ID
+		product.Category = category.Name
 	}
 	if req.Weight != nil {
 		product.Weight = *req.Weight
 	}
+	if req.ReorderThreshold != nil {
+		product.ReorderThreshold = *req.ReorderThreshold
+	}
 	if req.Images != nil {
 		product.Images = req.Images
 	}
+	if req.TastingNotes != nil {
+		product.TastingNotes = req.TastingNotes
+	}
+	if req.RemoveCoffee {
+		product.Coffee = nil
+	} else if req.Coffee != nil {
+		coffee, err := coffeeFromRequest(req.Coffee)
+		if err != nil {
+			return nil, err
+		}
+		product.Coffee = coffee
+	}
+	if req.PurchaseRules != nil {
+		rules, err := purchaseRulesFromRequest(req.PurchaseRules)
+		if err != nil {
+			return nil, err
+		}
+		product.PurchaseRules = rules
+	}
 	if req.IsFeatured != nil {
 		product.IsFeatured = *req.IsFeatured
 	}
@@ -169,9 +497,80 @@ func (s *ProductService) UpdateProduct(ctx context.Context, productID string, re
 		product.IsActive = *req.IsActive
 	}
 
+	if req.Variants != nil {
+		options := req.Options
+		if options == nil {
+			options = optionsToRequest(product.Options)
+		}
+		newOptions, variants, err := buildVariants(options, req.Variants, product.Variants)
+		if err != nil {
+			return nil, err
+		}
+		product.Options = newOptions
+		product.Variants = variants
+	}
+	if req.BundleItems != nil {
+		if len(req.BundleItems) == 0 {
+			// Deja de ser kit: sin stock propio salvo que venga en el request
+			product.BundleItems = nil
+			if req.Stock == nil {
+				product.Stock = 0
+			}
+		} else if err := s.applyBundle(ctx, product, req.BundleItems); err != nil {
+			return nil, err
+		}
+	}
+	if product.IsBundle() && product.HasVariants() {
+		return nil, errors.New("un kit no puede tener variantes")
+	}
+	if product.HasVariants() {
+		applyVariantAggregates(product)
+		if req.SalePrice == nil {
+			// Al pasar a variantes la oferta del producto deja de aplicar
+			product.SalePrice, product.SaleStartsAt, product.SaleEndsAt = nil, nil, nil
+		}
+	}
+	if err := validateProductSale(product); err != nil {
+		return nil, err
+	}
+	if err := s.ensureUniqueSKUs(ctx, product); err != nil {
+		return nil, err
+	}
+
+	// El stock pedido se aplica como toma de inventario después de guardar: Update conserva
+	// el stock guardado y solo el ledger lo cambia
+	var stockCount []*models.StockChange
+	switch {
+	case product.IsBundle():
+		// Su stock lo deriva RefreshBundles de los componentes
+	case product.HasVariants():
+		if req.Variants != nil {
+			stockCount = stockTargets(product, actorID, "")
+		}
+	case req.Stock != nil || (wasBundle && req.BundleItems != nil):
+		stockCount = stockTargets(product, actorID, "")
+	}
+
 	if err := s.productRepo.Update(ctx, product); err != nil {
 		return nil, fmt.Errorf("error al actualizar producto: %w", err)
 	}
+	if err := s.applyStockTargets(ctx, product, stockCount); err != nil {
+		return nil, fmt.Errorf("producto actualizado pero no se pudo ajustar el stock: %w", err)
+	}
+	product.ApplyPricing(time.Now())
+	s.recordPriceChanges(ctx, previousPrices, product, actorID)
+	s.searchIndex.Upsert(product)
+
+	// Activar, desactivar o cambiar variantes de un componente cambia el stock de sus kits
+	if s.inventoryService != nil {
+		s.inventoryService.RefreshBundles(ctx, []uuid.UUID{product.ID})
+	}
+	s.cache.InvalidateCatalog(ctx)
+
+	// Si la edición dejó el producto en oferta, avisar a quienes lo tienen en su lista
+	if product.OnSale && s.wishlistService != nil {
+		go s.wishlistService.NotifySales(context.Background(), product.ID)
+	}
 
 	return product, nil
 }
@@ -186,25 +585,17 @@ func (s *ProductService) DeleteProduct(ctx context.Context, productID string) er
 	if err := s.productRepo.Delete(ctx, id); err != nil {
 		return fmt.Errorf("error al eliminar producto: %w", err)
 	}
-
-	return nil
-}
-
-// UpdateStock actualiza el stock de un producto
-func (s *ProductService) UpdateStock(ctx context.Context, productID string, quantity int) error {
-	id, err := uuid.Parse(productID)
-	if err != nil {
-		return errors.New("ID de producto inválido")
-	}
-
-	if err := s.productRepo.UpdateStock(ctx, id, quantity); err != nil {
-		return fmt.Errorf("error al actualizar stock: %w", err)
+	s.searchIndex.Remove(id)
+	if s.inventoryService != nil {
+		s.inventoryService.RefreshBundles(ctx, []uuid.UUID{id})
 	}
+	s.cache.InvalidateCatalog(ctx)
 
 	return nil
 }
 
-// SearchProducts busca productos
+// SearchProducts busca productos activos por nombre, descripción, categoría,
+// notas de cata y variantes, ordenados por relevancia
 func (s *ProductService) SearchProducts(ctx context.Context, searchTerm string, limit int) ([]*models.Product, error) {
 	if searchTerm == "" {
 		return nil, errors.New("término de búsqueda requerido")
@@ -214,10 +605,894 @@ func (s *ProductService) SearchProducts(ctx context.Context, searchTerm string,
 		limit = 10
 	}
 
-	products, err := s.productRepo.Search(ctx, searchTerm, limit)
+	ids, err := s.searchIndex.Search(ctx, searchTerm, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error al buscar productos: %w", err)
+	}
+
+	// El índice solo guarda términos; precio y stock se leen frescos de Firestore
+	products, err := s.productRepo.GetByIDs(ctx, ids)
 	if err != nil {
 		return nil, fmt.Errorf("error al buscar productos: %w", err)
 	}
 
 	return products, nil
 }
+
+// ============================================================
+// Slugs y SEO
+// ============================================================
+
+// resolveProductSlug normaliza el slug pedido (o lo genera desde el nombre) y verifica que
+// no lo use otro producto, ni como slug actual ni como redirección.
+// Un slug explícito repetido es un error; uno generado recibe sufijo -2, -3...
+func (s *ProductService) resolveProductSlug(ctx context.Context, productID uuid.UUID, requested, name string) (string, error) {
+	if strings.TrimSpace(requested) != "" {
+		slug := utils.Slugify(requested)
+		if slug == "" {
+			return "", errors.New("slug inválido")
+		}
+		taken, err := s.productSlugTaken(ctx, productID, slug)
+		if err != nil {
+			return "", err
+		}
+		if taken {
+			return "", fmt.Errorf("el slug %s ya está en uso", slug)
+		}
+		return slug, nil
+	}
+
+	base := utils.Slugify(name)
+	if base == "" {
+		return "", errors.New("no se pudo generar un slug a partir del nombre")
+	}
+	slug := base
+	for n := 2; ; n++ {
+		taken, err := s.productSlugTaken(ctx, productID, slug)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+// productSlugTaken indica si otro producto usa el slug, como actual o como redirección.
+// Un error de Firestore se retorna: no se puede asumir que el slug está libre.
+func (s *ProductService) productSlugTaken(ctx context.Context, productID uuid.UUID, slug string) (bool, error) {
+	lookups := []func(context.Context, string) (*models.Product, error){
+		s.productRepo.GetBySlug,
+		s.productRepo.GetByPreviousSlug,
+	}
+	for _, lookup := range lookups {
+		existing, err := lookup(ctx, slug)
+		if errors.Is(err, repository.ErrProductNotFound) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("error al verificar el slug %s: %w", slug, err)
+		}
+		if existing.ID != productID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// changeProductSlug asigna el nuevo slug y guarda el anterior como redirección.
+// Volver a un slug previo lo saca de la lista de redirecciones.
+func changeProductSlug(product *models.Product, slug string) {
+	if product.Slug == slug {
+		return
+	}
+
+	previous := make([]string, 0, len(product.PreviousSlugs)+1)
+	for _, old := range product.PreviousSlugs {
+		if old != slug {
+			previous = append(previous, old)
+		}
+	}
+	if product.Slug != "" {
+		previous = append(previous, product.Slug)
+	}
+
+	product.PreviousSlugs = previous
+	product.Slug = slug
+}
+
+// applySEODefaults completa los meta tags vacíos (o que seguían el valor generado
+// anterior) y recalcula la URL canónica a partir del slug
+func (s *ProductService) applySEODefaults(product *models.Product, prevMetaTitle, prevMetaDescription string) {
+	if product.MetaTitle == "" || product.MetaTitle == prevMetaTitle {
+		product.MetaTitle = defaultMetaTitle(product)
+	}
+	if product.MetaDescription == "" || product.MetaDescription == prevMetaDescription {
+		product.MetaDescription = defaultMetaDescription(product)
+	}
+	product.CanonicalURL = fmt.Sprintf("%s/productos/%s", strings.TrimRight(s.cfg.FrontendURL, "/"), product.Slug)
+}
+
+func defaultMetaTitle(product *models.Product) string {
+	return product.Name + " | Cheos Cafe"
+}
+
+// defaultMetaDescription toma la descripción en una sola línea, cortada a 160 caracteres
+func defaultMetaDescription(product *models.Product) string {
+	description := []rune(strings.Join(strings.Fields(product.Description), " "))
+	if len(description) <= 160 {
+		return string(description)
+	}
+	cut := string(description[:157])
+	if i := strings.LastIndex(cut, " "); i > 100 {
+		cut = cut[:i]
+	}
+	return cut + "..."
+}
+
+// ============================================================
+// Catálogo: filtros, orden y facetas
+// ============================================================
+
+// productPriceBuckets son los rangos de precio (COP) de la faceta de precio
+var productPriceBuckets = []struct {
+	Label string
+	Min   float64
+	Max   float64 // 0 = sin tope
+}{
+	{"Menos de $30.000", 0, 30000},
+	{"$30.000 - $60.000", 30000, 60000},
+	{"$60.000 - $100.000", 60000, 100000},
+	{"Más de $100.000", 100000, 0},
+}
+
+func validProductSort(sortBy string) bool {
+	switch sortBy {
+	case models.ProductSortNewest, models.ProductSortPriceAsc, models.ProductSortPriceDesc,
+		models.ProductSortBestSelling, models.ProductSortRating:
+		return true
+	}
+	return false
+}
+
+// matchesProductFilter evalúa el filtro sobre un producto. withCategory y withPrice
+// permiten ignorar esos filtros al calcular sus propias facetas.
+func matchesProductFilter(p *models.Product, f *models.ProductFilter, withCategory, withPrice bool) bool {
+	if withCategory && f.CategoryIDs != nil && (p.CategoryID == nil || !f.CategoryIDs[*p.CategoryID]) {
+		return false
+	}
+	if withPrice && f.MinPrice != nil && p.EffectivePrice < *f.MinPrice {
+		return false
+	}
+	if withPrice && f.MaxPrice != nil && p.EffectivePrice > *f.MaxPrice {
+		return false
+	}
+	if f.InStock && p.Stock <= 0 {
+		return false
+	}
+	if f.Featured != nil && p.IsFeatured != *f.Featured {
+		return false
+	}
+	if f.MinRating > 0 && p.Rating < f.MinRating {
+		return false
+	}
+	return matchesCoffeeFilter(p.Coffee, f)
+}
+
+// matchesCoffeeFilter evalúa los filtros de atributos de café.
+// Sin filtros de café activos, los productos que no son café también pasan.
+func matchesCoffeeFilter(c *models.CoffeeAttributes, f *models.ProductFilter) bool {
+	if f.Origin == "" && f.Process == "" && f.RoastLevel == "" && f.Variety == "" && f.BrewMethod == "" && f.MinAltitude == 0 {
+		return true
+	}
+	if c == nil {
+		return false
+	}
+	if f.Origin != "" && !strings.Contains(utils.NormalizeText(c.OriginRegion+" "+c.Farm), utils.NormalizeText(f.Origin)) {
+		return false
+	}
+	if f.Process != "" && c.Process != f.Process {
+		return false
+	}
+	if f.RoastLevel != "" && c.RoastLevel != f.RoastLevel {
+		return false
+	}
+	if f.Variety != "" {
+		found := false
+		for _, v := range c.Varieties {
+			if utils.NormalizeText(v) == utils.NormalizeText(f.Variety) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.BrewMethod != "" {
+		found := false
+		for _, m := range c.BrewMethods {
+			if m == f.BrewMethod {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.MinAltitude > 0 && c.AltitudeMax < f.MinAltitude {
+		return false
+	}
+	return true
+}
+
+// coffeeFromRequest normaliza los atributos de café. Una altitud única se guarda como rango [n, n].
+func coffeeFromRequest(req *models.CoffeeAttributesRequest) (*models.CoffeeAttributes, error) {
+	coffee := &models.CoffeeAttributes{
+		OriginRegion: strings.TrimSpace(req.OriginRegion),
+		Farm:         strings.TrimSpace(req.Farm),
+		AltitudeMin:  req.AltitudeMin,
+		AltitudeMax:  req.AltitudeMax,
+		Varieties:    []string{},
+		Process:      req.Process,
+		RoastLevel:   req.RoastLevel,
+		BrewMethods:  req.BrewMethods,
+		RoastDate:    req.RoastDate,
+	}
+	for _, v := range req.Varieties {
+		if v = strings.TrimSpace(v); v != "" {
+			coffee.Varieties = append(coffee.Varieties, v)
+		}
+	}
+	if coffee.BrewMethods == nil {
+		coffee.BrewMethods = []models.BrewMethod{}
+	}
+
+	if coffee.AltitudeMax == 0 {
+		coffee.AltitudeMax = coffee.AltitudeMin
+	}
+	if coffee.AltitudeMin == 0 {
+		coffee.AltitudeMin = coffee.AltitudeMax
+	}
+	if coffee.AltitudeMin > coffee.AltitudeMax {
+		return nil, errors.New("la altitud mínima no puede ser mayor a la máxima")
+	}
+	if coffee.RoastDate != nil && coffee.RoastDate.After(time.Now()) {
+		return nil, errors.New("la fecha de tueste no puede ser futura")
+	}
+
+	return coffee, nil
+}
+
+// resolveCategoryFilter traduce el ID o slug de la categoría filtrada al conjunto
+// de IDs que incluye sus subcategorías
+func resolveCategoryFilter(all []*models.Category, idOrSlug string) (map[uuid.UUID]bool, error) {
+	id, parseErr := uuid.Parse(idOrSlug)
+	for _, c := range all {
+		if (parseErr == nil && c.ID == id) || c.Slug == idOrSlug {
+			return categoryWithDescendants(all, c.ID), nil
+		}
+	}
+
+	return nil, errors.New("categoría no encontrada")
+}
+
+// sortProducts ordena en sitio según el criterio pedido.
+// best_selling usa las unidades vendidas del año en curso (métricas del dashboard).
+func (s *ProductService) sortProducts(ctx context.Context, products []*models.Product, sortBy string) error {
+	newest := func(i, j int) bool {
+		return products[i].CreatedAt.After(products[j].CreatedAt)
+	}
+
+	switch sortBy {
+	case models.ProductSortPriceAsc:
+		sort.SliceStable(products, func(i, j int) bool {
+			if products[i].EffectivePrice != products[j].EffectivePrice {
+				return products[i].EffectivePrice < products[j].EffectivePrice
+			}
+			return newest(i, j)
+		})
+	case models.ProductSortPriceDesc:
+		sort.SliceStable(products, func(i, j int) bool {
+			if products[i].EffectivePrice != products[j].EffectivePrice {
+				return products[i].EffectivePrice > products[j].EffectivePrice
+			}
+			return newest(i, j)
+		})
+	case models.ProductSortRating:
+		sort.SliceStable(products, func(i, j int) bool {
+			if products[i].Rating != products[j].Rating {
+				return products[i].Rating > products[j].Rating
+			}
+			if products[i].ReviewCount != products[j].ReviewCount {
+				return products[i].ReviewCount > products[j].ReviewCount
+			}
+			return newest(i, j)
+		})
+	case models.ProductSortBestSelling:
+		sold := make(map[string]int)
+		metrics, err := s.dashboardRepo.GetTopProductsYearly(ctx, time.Now().Year())
+		if err != nil {
+			return fmt.Errorf("error al obtener ventas por producto: %w", err)
+		}
+		if metrics != nil {
+			for pid, stats := range metrics.AllProducts {
+				sold[pid] = stats.Quantity
+			}
+		}
+		sort.SliceStable(products, func(i, j int) bool {
+			qi, qj := sold[products[i].ID.String()], sold[products[j].ID.String()]
+			if qi != qj {
+				return qi > qj
+			}
+			return newest(i, j)
+		})
+	default:
+		sort.SliceStable(products, newest)
+	}
+
+	return nil
+}
+
+// buildProductFacets cuenta productos por categoría y por rango de precio.
+// Cada producto suma también en los ancestros de su categoría, igual que el
+// filtro por categoría incluye las subcategorías.
+func buildProductFacets(products []*models.Product, categories []*models.Category, f *models.ProductFilter) *models.ProductFacets {
+	categoryCounts := make(map[uuid.UUID]int)
+	categoryNames := make(map[uuid.UUID]string)
+	bucketCounts := make([]int, len(productPriceBuckets))
+
+	parents := make(map[uuid.UUID]*uuid.UUID, len(categories))
+	for _, c := range categories {
+		parents[c.ID] = c.ParentID
+		categoryNames[c.ID] = c.Name
+	}
+
+	for _, p := range products {
+		if matchesProductFilter(p, f, false, true) && p.CategoryID != nil {
+			if _, ok := categoryNames[*p.CategoryID]; !ok {
+				categoryNames[*p.CategoryID] = p.Category
+			}
+			visited := make(map[uuid.UUID]bool)
+			for id := p.CategoryID; id != nil && !visited[*id]; id = parents[*id] {
+				visited[*id] = true
+				categoryCounts[*id]++
+			}
+		}
+		if matchesProductFilter(p, f, true, false) {
+			for i, b := range productPriceBuckets {
+				if p.EffectivePrice >= b.Min && (b.Max == 0 || p.EffectivePrice < b.Max) {
+					bucketCounts[i]++
+					break
+				}
+			}
+		}
+	}
+
+	facets := &models.ProductFacets{
+		Categories:   make([]models.CategoryFacet, 0, len(categoryCounts)),
+		PriceBuckets: make([]models.PriceBucketFacet, 0, len(productPriceBuckets)),
+		Origins:      coffeeFacet(products, f, func(ff *models.ProductFilter) { ff.Origin = "" }, originFacetValue),
+		Processes: coffeeFacet(products, f, func(ff *models.ProductFilter) { ff.Process = "" }, func(c *models.CoffeeAttributes) (string, string) {
+			return string(c.Process), models.CoffeeProcessLabels[c.Process]
+		}),
+		RoastLevels: coffeeFacet(products, f, func(ff *models.ProductFilter) { ff.RoastLevel = "" }, func(c *models.CoffeeAttributes) (string, string) {
+			return string(c.RoastLevel), models.RoastLevelLabels[c.RoastLevel]
+		}),
+	}
+	for id, count := range categoryCounts {
+		id := id
+		facets.Categories = append(facets.Categories, models.CategoryFacet{
+			CategoryID: &id,
+			Category:   categoryNames[id],
+			Count:      count,
+		})
+	}
+	sort.Slice(facets.Categories, func(i, j int) bool {
+		return facets.Categories[i].Category < facets.Categories[j].Category
+	})
+
+	for i, b := range productPriceBuckets {
+		bucket := models.PriceBucketFacet{Label: b.Label, Min: b.Min, Count: bucketCounts[i]}
+		if b.Max > 0 {
+			max := b.Max
+			bucket.Max = &max
+		}
+		facets.PriceBuckets = append(facets.PriceBuckets, bucket)
+	}
+
+	return facets
+}
+
+// ============================================================
+// Variantes
+// ============================================================
+
+// stockTargets arma las tomas de inventario que llevan el producto (o cada variante)
+// al stock que tiene en memoria
+func stockTargets(product *models.Product, actorID *uuid.UUID, note string) []*models.StockChange {
+	if !product.HasVariants() {
+		stock := product.Stock
+		return []*models.StockChange{{
+			ProductID: product.ID,
+			SetTo:     &stock,
+			Reason:    models.InventoryStocktake,
+			Note:      note,
+			ActorID:   actorID,
+		}}
+	}
+
+	changes := make([]*models.StockChange, 0, len(product.Variants))
+	for _, v := range product.Variants {
+		variantID, stock := v.ID, v.Stock
+		changes = append(changes, &models.StockChange{
+			ProductID: product.ID,
+			VariantID: &variantID,
+			SetTo:     &stock,
+			Reason:    models.InventoryStocktake,
+			Note:      note,
+			ActorID:   actorID,
+		})
+	}
+	return changes
+}
+
+// applyStockTargets aplica por el ledger las tomas de inventario cuyo stock difiere del
+// guardado y deja en product el stock resultante
+func (s *ProductService) applyStockTargets(ctx context.Context, product *models.Product, changes []*models.StockChange) error {
+	if s.inventoryService == nil {
+		return nil
+	}
+
+	pending := make([]*models.StockChange, 0, len(changes))
+	for _, change := range changes {
+		current := product.Stock
+		if change.VariantID != nil {
+			variant := product.FindVariant(*change.VariantID)
+			if variant == nil {
+				continue
+			}
+			current = variant.Stock
+		}
+		if current != *change.SetTo {
+			pending = append(pending, change)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	movements, err := s.inventoryService.ApplyStockChanges(ctx, pending)
+	if err != nil {
+		return err
+	}
+	for _, movement := range movements {
+		if movement.VariantID != nil {
+			product.FindVariant(*movement.VariantID).Stock = movement.Balance
+		}
+		product.Stock += movement.Delta
+	}
+	return nil
+}
+
+// buildVariants valida las opciones y variantes recibidas y las convierte al modelo.
+// Las variantes con ID conocido conservan su ID; las nuevas reciben uno nuevo.
+func buildVariants(optionReqs []models.ProductOptionRequest, variantReqs []models.ProductVariantRequest, existing []models.ProductVariant) ([]models.ProductOption, []models.ProductVariant, error) {
+	if len(variantReqs) == 0 {
+		return nil, []models.ProductVariant{}, nil
+	}
+	if len(optionReqs) == 0 {
+		return nil, nil, errors.New("debe definir las opciones (tamaño, molienda...) de las variantes")
+	}
+
+	options := make([]models.ProductOption, 0, len(optionReqs))
+	allowed := make(map[string]map[string]bool, len(optionReqs))
+	for _, o := range optionReqs {
+		name := strings.TrimSpace(o.Name)
+		if _, dup := allowed[name]; dup {
+			return nil, nil, fmt.Errorf("la opción %q está repetida", name)
+		}
+		values := make(map[string]bool, len(o.Values))
+		for _, v := range o.Values {
+			values[strings.TrimSpace(v)] = true
+		}
+		allowed[name] = values
+		options = append(options, models.ProductOption{Name: name, Values: o.Values})
+	}
+
+	existingIDs := make(map[uuid.UUID]bool, len(existing))
+	for _, v := range existing {
+		existingIDs[v.ID] = true
+	}
+
+	seenSKUs := make(map[string]bool, len(variantReqs))
+	seenCombos := make(map[string]bool, len(variantReqs))
+	variants := make([]models.ProductVariant, 0, len(variantReqs))
+
+	for _, vr := range variantReqs {
+		sku := strings.ToUpper(strings.TrimSpace(vr.SKU))
+		if seenSKUs[sku] {
+			return nil, nil, fmt.Errorf("el SKU %s está repetido", sku)
+		}
+		seenSKUs[sku] = true
+
+		if len(vr.Options) != len(options) {
+			return nil, nil, fmt.Errorf("la variante %s debe definir un valor para cada opción", sku)
+		}
+		combo := make([]string, 0, len(options))
+		for _, o := range options {
+			value, ok := vr.Options[o.Name]
+			if !ok || !allowed[o.Name][strings.TrimSpace(value)] {
+				return nil, nil, fmt.Errorf("la variante %s tiene un valor inválido para la opción %q", sku, o.Name)
+			}
+			combo = append(combo, strings.TrimSpace(value))
+		}
+		key := strings.Join(combo, "|")
+		if seenCombos[key] {
+			return nil, nil, fmt.Errorf("la combinación %s está repetida", strings.Join(combo, " / "))
+		}
+		seenCombos[key] = true
+
+		id := uuid.New()
+		if vr.ID != nil && existingIDs[*vr.ID] {
+			id = *vr.ID
+		}
+
+		isActive := true
+		if vr.IsActive != nil {
+			isActive = *vr.IsActive
+		}
+
+		variants = append(variants, models.ProductVariant{
+			ID:       id,
+			SKU:      sku,
+			Options:  vr.Options,
+			Price:    vr.Price,
+			Weight:   vr.Weight,
+			Stock:    vr.Stock,
+			IsActive: isActive,
+
+			SalePrice:    vr.SalePrice,
+			SaleStartsAt: vr.SaleStartsAt,
+			SaleEndsAt:   vr.SaleEndsAt,
+		})
+		if err := validateSale(vr.SalePrice, vr.SaleStartsAt, vr.SaleEndsAt, vr.Price); err != nil {
+			return nil, nil, fmt.Errorf("variante %s: %w", sku, err)
+		}
+	}
+
+	return options, variants, nil
+}
+
+// applyVariantAggregates deriva precio, peso y stock del producto a partir de sus variantes:
+// precio y peso de la variante activa más barata ("desde $X") y stock como la suma total.
+func applyVariantAggregates(product *models.Product) {
+	stock := 0
+	var cheapest *models.ProductVariant
+	for i := range product.Variants {
+		v := &product.Variants[i]
+		stock += v.Stock
+		if !v.IsActive {
+			continue
+		}
+		if cheapest == nil || v.Price < cheapest.Price {
+			cheapest = v
+		}
+	}
+	product.Stock = stock
+	if cheapest != nil {
+		product.Price = cheapest.Price
+		product.Weight = cheapest.Weight
+	}
+}
+
+// applyBundle valida los componentes del kit, denormaliza sus nombres y deriva el stock.
+// Los componentes deben ser productos simples o variantes concretas; no se anidan kits.
+func (s *ProductService) applyBundle(ctx context.Context, product *models.Product, itemReqs []models.BundleItemRequest) error {
+	ids := make([]uuid.UUID, 0, len(itemReqs))
+	for _, req := range itemReqs {
+		if req.ProductID == product.ID {
+			return errors.New("un kit no puede incluirse a sí mismo")
+		}
+		ids = append(ids, req.ProductID)
+	}
+
+	components, err := s.productRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("error al obtener componentes: %w", err)
+	}
+	byID := make(map[uuid.UUID]*models.Product, len(components))
+	for _, c := range components {
+		byID[c.ID] = c
+	}
+
+	items := make([]models.BundleComponent, 0, len(itemReqs))
+	seen := make(map[string]bool)
+	for _, req := range itemReqs {
+		component, ok := byID[req.ProductID]
+		if !ok {
+			return fmt.Errorf("componente %s no encontrado", req.ProductID)
+		}
+		if component.IsBundle() {
+			return fmt.Errorf("%s es un kit y no puede ser componente de otro", component.Name)
+		}
+
+		item := models.BundleComponent{
+			ProductID:   component.ID,
+			ProductName: component.Name,
+			Quantity:    req.Quantity,
+		}
+		if component.HasVariants() {
+			if req.VariantID == nil {
+				return fmt.Errorf("debe seleccionar una variante de %s", component.Name)
+			}
+			variant := component.FindVariant(*req.VariantID)
+			if variant == nil {
+				return fmt.Errorf("variante de %s no encontrada", component.Name)
+			}
+			variantID := variant.ID
+			item.VariantID = &variantID
+			item.VariantName = component.VariantLabel(variant)
+		} else if req.VariantID != nil {
+			return fmt.Errorf("el producto %s no tiene variantes", component.Name)
+		}
+
+		key := stockKey(item.ProductID, item.VariantID)
+		if seen[key] {
+			return fmt.Errorf("%s está repetido en el kit", component.Name)
+		}
+		seen[key] = true
+		items = append(items, item)
+	}
+
+	// Un producto que ya es componente de otro kit no puede convertirse en kit
+	if !product.IsBundle() {
+		containing, err := s.productRepo.ListBundlesContaining(ctx, product.ID)
+		if err != nil {
+			return fmt.Errorf("error al verificar kits: %w", err)
+		}
+		if len(containing) > 0 {
+			return fmt.Errorf("el producto es componente del kit %s y no puede ser un kit", containing[0].Name)
+		}
+	}
+
+	product.BundleItems = items
+	product.Stock = product.BundleStock(byID)
+	return nil
+}
+
+// ensureUniqueSKUs verifica que ningún SKU del producto pertenezca a otro producto
+func (s *ProductService) ensureUniqueSKUs(ctx context.Context, product *models.Product) error {
+	skus := make([]string, 0, len(product.Variants)+1)
+	if product.SKU != "" {
+		skus = append(skus, product.SKU)
+	}
+	for _, v := range product.Variants {
+		if product.SKU != "" && v.SKU == product.SKU {
+			return fmt.Errorf("el SKU %s está repetido", v.SKU)
+		}
+		skus = append(skus, v.SKU)
+	}
+
+	for _, sku := range skus {
+		existing, err := s.productRepo.GetBySKU(ctx, sku)
+		if errors.Is(err, repository.ErrProductNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error al verificar el SKU %s: %w", sku, err)
+		}
+		if existing.ID != product.ID {
+			return fmt.Errorf("el SKU %s ya está asignado al producto %s", sku, existing.Name)
+		}
+	}
+	return nil
+}
+
+func optionsToRequest(options []models.ProductOption) []models.ProductOptionRequest {
+	reqs := make([]models.ProductOptionRequest, 0, len(options))
+	for _, o := range options {
+		reqs = append(reqs, models.ProductOptionRequest{Name: o.Name, Values: o.Values})
+	}
+	return reqs
+}
+
+// purchasable agrupa los datos vendibles de un producto o de una de sus variantes
+type purchasable struct {
+	Price       float64
+	Weight      int
+	Stock       int
+	VariantName string
+	SKU         string
+}
+
+// resolvePurchasable obtiene precio y stock del producto o de la variante indicada.
+// Un producto con variantes exige variantID; un producto simple lo rechaza.
+func resolvePurchasable(product *models.Product, variantID *uuid.UUID) (*purchasable, error) {
+	if !product.HasVariants() {
+		if variantID != nil {
+			return nil, fmt.Errorf("el producto %s no tiene variantes", product.Name)
+		}
+		return &purchasable{
+			Price:  product.PriceAt(time.Now()),
+			Weight: product.Weight,
+			Stock:  product.Stock,
+		}, nil
+	}
+
+	if variantID == nil {
+		return nil, fmt.Errorf("debe seleccionar una variante de %s", product.Name)
+	}
+	variant := product.FindVariant(*variantID)
+	if variant == nil || !variant.IsActive {
+		return nil, fmt.Errorf("la variante seleccionada de %s no está disponible", product.Name)
+	}
+
+	return &purchasable{
+		Price:       variant.PriceAt(time.Now()),
+		Weight:      variant.Weight,
+		Stock:       variant.Stock,
+		VariantName: product.VariantLabel(variant),
+		SKU:         variant.SKU,
+	}, nil
+}
+
+// displayName arma el nombre del producto con su variante. Ej: "Café Origen (500 g / Molido)"
+func displayName(product *models.Product, item *purchasable) string {
+	if item.VariantName == "" {
+		return product.Name
+	}
+	return fmt.Sprintf("%s (%s)", product.Name, item.VariantName)
+}
+
+// coffeeFacet cuenta los valores de un atributo de café con todos los filtros activos
+// excepto el del propio atributo (clear lo quita de una copia del filtro).
+func coffeeFacet(products []*models.Product, f *models.ProductFilter, clear func(*models.ProductFilter), value func(*models.CoffeeAttributes) (string, string)) []models.ValueFacet {
+	without := *f
+	clear(&without)
+
+	counts := make(map[string]*models.ValueFacet)
+	for _, p := range products {
+		if p.Coffee == nil || !matchesProductFilter(p, &without, true, true) {
+			continue
+		}
+		key, label := value(p.Coffee)
+		if key == "" {
+			continue
+		}
+		if facet, ok := counts[key]; ok {
+			facet.Count++
+		} else {
+			counts[key] = &models.ValueFacet{Value: key, Label: label, Count: 1}
+		}
+	}
+
+	facets := make([]models.ValueFacet, 0, len(counts))
+	for _, facet := range counts {
+		facets = append(facets, *facet)
+	}
+	sort.Slice(facets, func(i, j int) bool {
+		return facets[i].Label < facets[j].Label
+	})
+	return facets
+}
+
+// originFacetValue agrupa los orígenes sin distinguir mayúsculas ni tildes
+func originFacetValue(c *models.CoffeeAttributes) (string, string) {
+	return utils.NormalizeText(c.OriginRegion), c.OriginRegion
+}
+
+// validateProductSale valida la oferta del producto. En productos con variantes
+// la oferta se define por variante (buildVariants valida cada una).
+func validateProductSale(product *models.Product) error {
+	if product.HasVariants() {
+		if product.SalePrice != nil {
+			return errors.New("en productos con variantes la oferta se define en cada variante")
+		}
+		return nil
+	}
+	return validateSale(product.SalePrice, product.SaleStartsAt, product.SaleEndsAt, product.Price)
+}
+
+func validateSale(salePrice *float64, startsAt, endsAt *time.Time, regular float64) error {
+	if salePrice == nil {
+		if startsAt != nil || endsAt != nil {
+			return errors.New("las fechas de la oferta requieren un precio de oferta")
+		}
+		return nil
+	}
+	if *salePrice >= regular {
+		return errors.New("el precio de oferta debe ser menor al precio regular")
+	}
+	if startsAt != nil && endsAt != nil && !endsAt.After(*startsAt) {
+		return errors.New("la fecha de fin de la oferta debe ser posterior a la de inicio")
+	}
+	return nil
+}
+
+// priceState es la configuración de precio de un producto simple o de una variante
+type priceState struct {
+	price    float64
+	sale     *float64
+	startsAt *time.Time
+	endsAt   *time.Time
+}
+
+func (a priceState) equal(b priceState) bool {
+	floatEq := func(x, y *float64) bool { return (x == nil && y == nil) || (x != nil && y != nil && *x == *y) }
+	timeEq := func(x, y *time.Time) bool { return (x == nil && y == nil) || (x != nil && y != nil && x.Equal(*y)) }
+	return a.price == b.price && floatEq(a.sale, b.sale) && timeEq(a.startsAt, b.startsAt) && timeEq(a.endsAt, b.endsAt)
+}
+
+// productPriceStates indexa la configuración de precios por variante ("" = producto simple).
+// El precio agregado de un producto con variantes no se registra: se deriva de ellas.
+func productPriceStates(product *models.Product) map[string]priceState {
+	states := make(map[string]priceState)
+	if !product.HasVariants() {
+		states[""] = priceState{product.Price, product.SalePrice, product.SaleStartsAt, product.SaleEndsAt}
+		return states
+	}
+	for _, v := range product.Variants {
+		states[v.ID.String()] = priceState{v.Price, v.SalePrice, v.SaleStartsAt, v.SaleEndsAt}
+	}
+	return states
+}
+
+// recordPriceChanges guarda en el historial cada precio u oferta que cambió respecto de before
+// (nil en el alta). Un error al registrar no revierte el cambio de precio.
+func (s *ProductService) recordPriceChanges(ctx context.Context, before map[string]priceState, product *models.Product, actorID *uuid.UUID) {
+	changes := []*models.PriceChange{}
+	for key, current := range productPriceStates(product) {
+		previous, existed := before[key]
+		if existed && previous.equal(current) {
+			continue
+		}
+
+		change := &models.PriceChange{
+			ProductID:    product.ID,
+			ProductName:  product.Name,
+			SKU:          product.SKU,
+			Price:        current.price,
+			SalePrice:    current.sale,
+			SaleStartsAt: current.startsAt,
+			SaleEndsAt:   current.endsAt,
+			ActorID:      actorID,
+		}
+		if existed {
+			price := previous.price
+			change.PreviousPrice = &price
+			change.PreviousSalePrice = previous.sale
+		}
+		if key != "" {
+			variant := product.FindVariant(uuid.MustParse(key))
+			change.VariantID = &variant.ID
+			change.VariantName = product.VariantLabel(variant)
+			change.SKU = variant.SKU
+		}
+		changes = append(changes, change)
+	}
+
+	if err := s.priceHistoryRepo.CreateMany(ctx, changes); err != nil {
+		log.Printf("Warning: no se pudo registrar el historial de precios de %s: %v", product.ID, err)
+	}
+}
+
+// GetPriceHistory obtiene el historial de precios de un producto (solo admin)
+func (s *ProductService) GetPriceHistory(ctx context.Context, productID string) ([]*models.PriceChange, error) {
+	id, err := uuid.Parse(productID)
+	if err != nil {
+		return nil, errors.New("ID de producto inválido")
+	}
+
+	if _, err := s.productRepo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	changes, err := s.priceHistoryRepo.ListByProduct(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener historial de precios: %w", err)
+	}
+	return changes, nil
+}
@@ -0,0 +1,426 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+	_ "time/tzdata" // STORE_TIMEZONE must resolve even on images without zoneinfo
+
+	"github.com/cheoscafe/backend/internal/config"
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/cheoscafe/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+const cacheKeyActivePromotions = "cache:promotions:active"
+
+type PromotionService struct {
+	promotionRepo *repository.PromotionRepository
+	categoryRepo  *repository.CategoryRepository
+	cache         *CacheService
+	location      *time.Location
+}
+
+func NewPromotionService(promotionRepo *repository.PromotionRepository, categoryRepo *repository.CategoryRepository, cache *CacheService, cfg *config.Config) *PromotionService {
+	location, err := time.LoadLocation(cfg.StoreTimezone)
+	if err != nil {
+		log.Printf("[Promotions] Invalid STORE_TIMEZONE %q, using server time: %v", cfg.StoreTimezone, err)
+		location = time.Local
+	}
+
+	return &PromotionService{
+		promotionRepo: promotionRepo,
+		categoryRepo:  categoryRepo,
+		cache:         cache,
+		location:      location,
+	}
+}
+
+// promotionResult is the outcome of evaluating the promotions on a set of lines
+type promotionResult struct {
+	applied       []models.AppliedPromotion
+	lineDiscounts []float64 // Parallel to the evaluated lines
+	total         float64
+}
+
+// CreatePromotion creates a new promotion
+func (s *PromotionService) CreatePromotion(ctx context.Context, req *models.PromotionRequest) (*models.Promotion, error) {
+	promotion := promotionFromRequest(req)
+	if err := s.validatePromotion(ctx, promotion); err != nil {
+		return nil, err
+	}
+
+	if err := s.promotionRepo.Create(ctx, promotion); err != nil {
+		return nil, err
+	}
+	s.cache.Delete(ctx, cacheKeyActivePromotions)
+
+	return promotion, nil
+}
+
+// GetPromotionByID gets a promotion by ID
+func (s *PromotionService) GetPromotionByID(ctx context.Context, id uuid.UUID) (*models.Promotion, error) {
+	return s.promotionRepo.GetByID(ctx, id)
+}
+
+// GetAllPromotions gets every promotion, highest priority first
+func (s *PromotionService) GetAllPromotions(ctx context.Context) (*models.PromotionListResponse, error) {
+	promotions, err := s.promotionRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]models.Promotion, 0, len(promotions))
+	for _, p := range promotions {
+		list = append(list, *p)
+	}
+
+	return &models.PromotionListResponse{
+		Promotions: list,
+		Total:      len(list),
+	}, nil
+}
+
+// UpdatePromotion replaces a promotion
+func (s *PromotionService) UpdatePromotion(ctx context.Context, id uuid.UUID, req *models.PromotionRequest) (*models.Promotion, error) {
+	existing, err := s.promotionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	promotion := promotionFromRequest(req)
+	promotion.ID = existing.ID
+	promotion.CreatedAt = existing.CreatedAt
+	if err := s.validatePromotion(ctx, promotion); err != nil {
+		return nil, err
+	}
+
+	if err := s.promotionRepo.Update(ctx, promotion); err != nil {
+		return nil, err
+	}
+	s.cache.Delete(ctx, cacheKeyActivePromotions)
+
+	return promotion, nil
+}
+
+// DeletePromotion deletes a promotion
+func (s *PromotionService) DeletePromotion(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.promotionRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.promotionRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.cache.Delete(ctx, cacheKeyActivePromotions)
+
+	return nil
+}
+
+func promotionFromRequest(req *models.PromotionRequest) *models.Promotion {
+	tiers := append([]models.PromotionTier{}, req.Tiers...)
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].MinSubtotal < tiers[j].MinSubtotal })
+
+	return &models.Promotion{
+		Name:              req.Name,
+		Description:       req.Description,
+		Type:              req.Type,
+		Priority:          req.Priority,
+		Stackable:         req.Stackable,
+		CombinesWithCodes: req.CombinesWithCodes,
+		ProductIDs:        req.ProductIDs,
+		CategoryIDs:       req.CategoryIDs,
+		BuyQuantity:       req.BuyQuantity,
+		GetQuantity:       req.GetQuantity,
+		GetPercent:        req.GetPercent,
+		Tiers:             tiers,
+		BundleQuantity:    req.BundleQuantity,
+		BundlePrice:       req.BundlePrice,
+		Percent:           req.Percent,
+		Weekdays:          req.Weekdays,
+		StartHour:         req.StartHour,
+		EndHour:           req.EndHour,
+		StartDate:         req.StartDate,
+		EndDate:           req.EndDate,
+		IsActive:          req.IsActive,
+	}
+}
+
+// validatePromotion checks the settings required by each promotion type
+func (s *PromotionService) validatePromotion(ctx context.Context, p *models.Promotion) error {
+	if p.EndDate.Before(p.StartDate) {
+		return errors.New("end date must be after start date")
+	}
+
+	switch p.Type {
+	case models.PromotionBuyXGetY:
+		if p.BuyQuantity < 1 || p.GetQuantity < 1 || p.GetPercent <= 0 {
+			return errors.New("buy X get Y needs buy_quantity, get_quantity and get_percent")
+		}
+	case models.PromotionTieredSpend:
+		if len(p.Tiers) == 0 {
+			return errors.New("tiered spend needs at least one tier")
+		}
+		for _, tier := range p.Tiers {
+			if tier.Percent <= 0 || tier.Percent > 100 {
+				return errors.New("tier percent must be between 0 and 100")
+			}
+		}
+	case models.PromotionBundlePrice:
+		if p.BundleQuantity < 2 || p.BundlePrice <= 0 {
+			return errors.New("bundle price needs bundle_quantity (2 or more) and bundle_price")
+		}
+	case models.PromotionHappyHour:
+		if p.Percent <= 0 {
+			return errors.New("happy hour needs a percent")
+		}
+		if p.EndHour <= p.StartHour {
+			return errors.New("end hour must be after start hour")
+		}
+	}
+
+	if len(p.CategoryIDs) > 0 {
+		categories, err := s.categoryRepo.GetAll(ctx)
+		if err != nil {
+			return err
+		}
+		known := make(map[uuid.UUID]bool, len(categories))
+		for _, c := range categories {
+			known[c.ID] = true
+		}
+		for _, id := range p.CategoryIDs {
+			if !known[id] {
+				return fmt.Errorf("category %s not found", id)
+			}
+		}
+	}
+
+	return nil
+}
+
+// activePromotions gets the promotions running at t: active, within their dates and,
+// for happy hours, within their weekdays and hours. Served from cache when available.
+func (s *PromotionService) activePromotions(ctx context.Context, t time.Time) ([]*models.Promotion, error) {
+	var promotions []*models.Promotion
+	if !s.cache.Get(ctx, cacheKeyActivePromotions, &promotions) {
+		var err error
+		promotions, err = s.promotionRepo.GetActive(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.cache.Set(ctx, cacheKeyActivePromotions, promotions, s.cache.ConfigTTL)
+	}
+
+	local := t.In(s.location)
+	running := make([]*models.Promotion, 0, len(promotions))
+	for _, p := range promotions {
+		if t.Before(p.StartDate) || t.After(p.EndDate) {
+			continue
+		}
+		if p.Type == models.PromotionHappyHour && !happyHourAt(p, local) {
+			continue
+		}
+		running = append(running, p)
+	}
+	return running, nil
+}
+
+// happyHourAt reports whether t (store local time) falls on the promotion's days and hours
+func happyHourAt(p *models.Promotion, t time.Time) bool {
+	if len(p.Weekdays) > 0 {
+		today := false
+		for _, d := range p.Weekdays {
+			if time.Weekday(d) == t.Weekday() {
+				today = true
+				break
+			}
+		}
+		if !today {
+			return false
+		}
+	}
+	return t.Hour() >= p.StartHour && t.Hour() < p.EndHour
+}
+
+// Evaluate applies the running promotions to the lines. Promotions are tried by priority;
+// each one works on what earlier ones left of every line, so discounts never overlap.
+// A non-stackable promotion only applies alone, and with withCode only the promotions
+// that combine with discount codes are considered.
+func (s *PromotionService) Evaluate(ctx context.Context, lines []models.PromotionLine, withCode bool) (*promotionResult, error) {
+	result := &promotionResult{
+		applied:       []models.AppliedPromotion{},
+		lineDiscounts: make([]float64, len(lines)),
+	}
+	if s == nil || len(lines) == 0 {
+		return result, nil
+	}
+
+	promotions, err := s.activePromotions(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if len(promotions) == 0 {
+		return result, nil
+	}
+
+	var categories []*models.Category
+	for _, p := range promotions {
+		if len(p.CategoryIDs) > 0 {
+			if categories, err = s.categoryRepo.GetAll(ctx); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	remaining := make([]float64, len(lines))
+	for i, line := range lines {
+		remaining[i] = line.UnitPrice * float64(line.Quantity)
+	}
+
+	for _, p := range promotions {
+		if withCode && !p.CombinesWithCodes {
+			continue
+		}
+		if len(result.applied) > 0 && !p.Stackable {
+			continue
+		}
+
+		discounts := promotionDiscounts(p, lines, remaining, promotionScope(p, lines, categories))
+		amount := 0.0
+		for i, d := range discounts {
+			d = math.Min(d, remaining[i])
+			discounts[i] = d
+			amount += d
+		}
+		if amount <= 0 {
+			continue
+		}
+
+		for i, d := range discounts {
+			remaining[i] -= d
+			result.lineDiscounts[i] += d
+		}
+		result.total += amount
+		result.applied = append(result.applied, models.AppliedPromotion{
+			PromotionID: p.ID,
+			Name:        p.Name,
+			Type:        p.Type,
+			Amount:      amount,
+		})
+
+		if !p.Stackable {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// promotionScope marks the lines a promotion applies to
+func promotionScope(p *models.Promotion, lines []models.PromotionLine, categories []*models.Category) []bool {
+	eligible := make([]bool, len(lines))
+	if len(p.ProductIDs) == 0 && len(p.CategoryIDs) == 0 {
+		for i := range eligible {
+			eligible[i] = true
+		}
+		return eligible
+	}
+
+	products := make(map[uuid.UUID]bool, len(p.ProductIDs))
+	for _, id := range p.ProductIDs {
+		products[id] = true
+	}
+	inCategory := make(map[uuid.UUID]bool)
+	for _, id := range p.CategoryIDs {
+		for c := range categoryWithDescendants(categories, id) {
+			inCategory[c] = true
+		}
+	}
+
+	for i, line := range lines {
+		eligible[i] = products[line.ProductID] || (line.CategoryID != nil && inCategory[*line.CategoryID])
+	}
+	return eligible
+}
+
+// promotionUnit is one eligible unit, priced with what's left of its line
+type promotionUnit struct {
+	line  int
+	price float64
+}
+
+// promotionDiscounts computes the discount of one promotion per line
+func promotionDiscounts(p *models.Promotion, lines []models.PromotionLine, remaining []float64, eligible []bool) []float64 {
+	discounts := make([]float64, len(lines))
+
+	var units []promotionUnit
+	eligibleTotal := 0.0
+	for i, line := range lines {
+		if !eligible[i] || line.Quantity <= 0 || remaining[i] <= 0 {
+			continue
+		}
+		eligibleTotal += remaining[i]
+		unitPrice := remaining[i] / float64(line.Quantity)
+		for n := 0; n < line.Quantity; n++ {
+			units = append(units, promotionUnit{line: i, price: unitPrice})
+		}
+	}
+	if len(units) == 0 {
+		return discounts
+	}
+
+	switch p.Type {
+	case models.PromotionBuyXGetY:
+		discounted := len(units) / (p.BuyQuantity + p.GetQuantity) * p.GetQuantity
+		sort.SliceStable(units, func(i, j int) bool { return units[i].price < units[j].price })
+		for _, u := range units[:discounted] {
+			discounts[u.line] += u.price * p.GetPercent / 100
+		}
+
+	case models.PromotionTieredSpend:
+		percent := 0.0
+		for _, tier := range p.Tiers { // Sorted by min_subtotal
+			if eligibleTotal >= tier.MinSubtotal {
+				percent = tier.Percent
+			}
+		}
+		for i := range lines {
+			if eligible[i] && remaining[i] > 0 {
+				discounts[i] = remaining[i] * percent / 100
+			}
+		}
+
+	case models.PromotionBundlePrice:
+		bundles := len(units) / p.BundleQuantity
+		sort.SliceStable(units, func(i, j int) bool { return units[i].price > units[j].price })
+		bundled := units[:bundles*p.BundleQuantity]
+		regular := 0.0
+		for _, u := range bundled {
+			regular += u.price
+		}
+		saving := regular - float64(bundles)*p.BundlePrice
+		if saving <= 0 {
+			return discounts
+		}
+		for _, u := range bundled {
+			discounts[u.line] += saving * u.price / regular
+		}
+
+	case models.PromotionHappyHour:
+		for i := range lines {
+			if eligible[i] && remaining[i] > 0 {
+				discounts[i] = remaining[i] * p.Percent / 100
+			}
+		}
+	}
+
+	for i := range discounts {
+		discounts[i] = math.Round(discounts[i])
+	}
+	return discounts
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/cheoscafe/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+// purchaseCustomer identifica al cliente para los límites por cliente.
+// Sin usuario ni email (p. ej. el carrito de un invitado) esos límites no se pueden
+// verificar todavía; se verifican al crear la orden.
+type purchaseCustomer struct {
+	userID *uuid.UUID
+	email  string
+}
+
+// purchaseRulesFromRequest valida las reglas de compra. Todo en 0 retorna nil (sin reglas).
+func purchaseRulesFromRequest(req *models.PurchaseRulesRequest) (*models.PurchaseRules, error) {
+	rules := &models.PurchaseRules{
+		MinQuantity:        req.MinQuantity,
+		QuantityStep:       req.QuantityStep,
+		MaxPerOrder:        req.MaxPerOrder,
+		MaxPerCustomer:     req.MaxPerCustomer,
+		CustomerWindowDays: req.CustomerWindowDays,
+	}
+	if *rules == (models.PurchaseRules{}) {
+		return nil, nil
+	}
+
+	if rules.CustomerWindowDays > 0 && rules.MaxPerCustomer == 0 {
+		return nil, errors.New("la ventana por cliente requiere un máximo por cliente")
+	}
+	if rules.MaxPerOrder > 0 && rules.MinQuantity > rules.MaxPerOrder {
+		return nil, errors.New("el mínimo de compra no puede ser mayor al máximo por orden")
+	}
+	if rules.MaxPerCustomer > 0 && rules.MinQuantity > rules.MaxPerCustomer {
+		return nil, errors.New("el mínimo de compra no puede ser mayor al máximo por cliente")
+	}
+	if rules.QuantityStep > 1 {
+		if rules.MinQuantity%rules.QuantityStep != 0 {
+			return nil, fmt.Errorf("el mínimo de compra debe ser múltiplo de %d", rules.QuantityStep)
+		}
+		if rules.MaxPerOrder > 0 && rules.MaxPerOrder < rules.QuantityStep {
+			return nil, fmt.Errorf("el máximo por orden debe ser al menos %d", rules.QuantityStep)
+		}
+	}
+
+	return rules, nil
+}
+
+// checkPurchaseRules valida la cantidad total de un producto (todas sus variantes)
+// en el carrito o la orden. purchased es lo que el cliente ya compró dentro de la
+// ventana del límite por cliente.
+func checkPurchaseRules(product *models.Product, quantity, purchased int) error {
+	rules := product.PurchaseRules
+	if rules == nil || quantity <= 0 {
+		return nil
+	}
+
+	if rules.MinQuantity > 0 && quantity < rules.MinQuantity {
+		return fmt.Errorf("la compra mínima de %s es de %d unidad(es)", product.Name, rules.MinQuantity)
+	}
+	if rules.QuantityStep > 1 && quantity%rules.QuantityStep != 0 {
+		return fmt.Errorf("%s se vende en múltiplos de %d unidades", product.Name, rules.QuantityStep)
+	}
+	if rules.MaxPerOrder > 0 && quantity > rules.MaxPerOrder {
+		return fmt.Errorf("puedes comprar máximo %d unidad(es) de %s por orden", rules.MaxPerOrder, product.Name)
+	}
+	if rules.MaxPerCustomer > 0 && purchased+quantity > rules.MaxPerCustomer {
+		period := "en total"
+		if rules.CustomerWindowDays > 0 {
+			period = fmt.Sprintf("cada %d días", rules.CustomerWindowDays)
+		}
+		if purchased >= rules.MaxPerCustomer {
+			return fmt.Errorf("ya alcanzaste el máximo de %d unidad(es) de %s %s", rules.MaxPerCustomer, product.Name, period)
+		}
+		return fmt.Errorf("puedes comprar máximo %d unidad(es) de %s %s; ya compraste %d",
+			rules.MaxPerCustomer, product.Name, period, purchased)
+	}
+
+	return nil
+}
+
+// purchasedQuantities suma lo que el cliente ya compró de cada producto con límite
+// por cliente, contando solo las órdenes dentro de la ventana de ese producto.
+// Es una lectura previa (carrito, cotización); al crear la orden el límite se
+// vuelve a verificar en la transacción del stock (ver ProductRepository.ApplyStockChanges).
+func purchasedQuantities(ctx context.Context, orderRepo *repository.OrderRepository, customer purchaseCustomer, products []*models.Product) (map[uuid.UUID]int, error) {
+	purchased := make(map[uuid.UUID]int)
+	if customer.userID == nil && customer.email == "" {
+		return purchased, nil
+	}
+
+	now := time.Now()
+	windowStart := make(map[uuid.UUID]time.Time)
+	var since time.Time
+	first := true
+	for _, p := range products {
+		if !p.PurchaseRules.HasCustomerLimit() {
+			continue
+		}
+		var start time.Time // Sin ventana: desde siempre
+		if days := p.PurchaseRules.CustomerWindowDays; days > 0 {
+			start = now.AddDate(0, 0, -days)
+		}
+		windowStart[p.ID] = start
+		if first || start.Before(since) {
+			since = start
+			first = false
+		}
+	}
+	if len(windowStart) == 0 {
+		return purchased, nil
+	}
+
+	orders, err := orderRepo.ListByCustomerSince(ctx, customer.userID, customer.email, since)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener compras anteriores: %w", err)
+	}
+	if len(orders) == 0 {
+		return purchased, nil
+	}
+
+	createdAt := make(map[uuid.UUID]time.Time, len(orders))
+	orderIDs := make([]uuid.UUID, 0, len(orders))
+	for _, o := range orders {
+		createdAt[o.ID] = o.CreatedAt
+		orderIDs = append(orderIDs, o.ID)
+	}
+
+	items, err := orderRepo.GetItemsByOrderIDs(ctx, orderIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener compras anteriores: %w", err)
+	}
+	for _, item := range items {
+		start, ok := windowStart[item.ProductID]
+		if !ok || createdAt[item.OrderID].Before(start) {
+			continue
+		}
+		purchased[item.ProductID] += item.Quantity
+	}
+
+	return purchased, nil
+}
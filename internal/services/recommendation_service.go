@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cheoscafe/backend/internal/config"
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/cheoscafe/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+// minCoPurchases es la cantidad mínima de pedidos en común para considerar
+// que dos productos "se compran juntos"; por debajo es ruido.
+const minCoPurchases = 2
+
+// RecommendationService arma las recomendaciones de "comprados juntos" a partir
+// de los items de los pedidos de los últimos RECOMMENDATIONS_WINDOW_DAYS.
+// El modelo vive en memoria (como el índice de búsqueda) y se recalcula cada
+// RECOMMENDATIONS_REFRESH_HOURS. Cuando faltan datos se completa con productos
+// de la misma categoría, los más vendidos primero.
+type RecommendationService struct {
+	orderRepo   *repository.OrderRepository
+	productRepo *repository.ProductRepository
+	cartRepo    *repository.CartRepository
+	cfg         *config.Config
+
+	mu       sync.RWMutex
+	pairs    map[uuid.UUID]map[uuid.UUID]int // producto → producto → pedidos en que aparecen juntos
+	orders   map[uuid.UUID]int               // producto → pedidos en que aparece
+	loadedAt time.Time
+}
+
+func NewRecommendationService(
+	orderRepo *repository.OrderRepository,
+	productRepo *repository.ProductRepository,
+	cartRepo *repository.CartRepository,
+	cfg *config.Config,
+) *RecommendationService {
+	return &RecommendationService{
+		orderRepo:   orderRepo,
+		productRepo: productRepo,
+		cartRepo:    cartRepo,
+		cfg:         cfg,
+		pairs:       make(map[uuid.UUID]map[uuid.UUID]int),
+		orders:      make(map[uuid.UUID]int),
+	}
+}
+
+// Refresh recalcula el modelo de co-compra. Los pedidos cancelados no cuentan
+// y cada producto cuenta una vez por pedido aunque se compren varias variantes.
+func (s *RecommendationService) Refresh(ctx context.Context) error {
+	days := s.cfg.RecommendationsWindowDays
+	if days < 1 {
+		days = 180
+	}
+
+	now := time.Now()
+	orders, err := s.orderRepo.GetOrdersByDateRange(ctx, now.AddDate(0, 0, -days), now)
+	if err != nil {
+		return fmt.Errorf("error al obtener órdenes: %w", err)
+	}
+
+	orderIDs := make([]uuid.UUID, 0, len(orders))
+	for _, order := range orders {
+		if order.Status != models.OrderCancelled {
+			orderIDs = append(orderIDs, order.ID)
+		}
+	}
+
+	items, err := s.orderRepo.GetItemsByOrderIDs(ctx, orderIDs)
+	if err != nil {
+		return fmt.Errorf("error al obtener items de órdenes: %w", err)
+	}
+
+	baskets := make(map[uuid.UUID]map[uuid.UUID]bool)
+	for _, item := range items {
+		if baskets[item.OrderID] == nil {
+			baskets[item.OrderID] = make(map[uuid.UUID]bool)
+		}
+		baskets[item.OrderID][item.ProductID] = true
+	}
+
+	pairs := make(map[uuid.UUID]map[uuid.UUID]int)
+	counts := make(map[uuid.UUID]int)
+	for _, basket := range baskets {
+		for a := range basket {
+			counts[a]++
+			for b := range basket {
+				if a == b {
+					continue
+				}
+				if pairs[a] == nil {
+					pairs[a] = make(map[uuid.UUID]int)
+				}
+				pairs[a][b]++
+			}
+		}
+	}
+
+	s.mu.Lock()
+	s.pairs = pairs
+	s.orders = counts
+	s.loadedAt = now
+	s.mu.Unlock()
+
+	log.Printf("[Recommendations] Modelo recalculado: %d pedidos, %d productos", len(baskets), len(counts))
+	return nil
+}
+
+// StartRefresh recalcula el modelo al arrancar y luego cada RECOMMENDATIONS_REFRESH_HOURS.
+// Bloquea hasta que ctx se cancele; lanzar en una goroutine.
+func (s *RecommendationService) StartRefresh(ctx context.Context) {
+	hours := s.cfg.RecommendationsRefreshHours
+	if hours < 1 {
+		hours = 6
+	}
+
+	if err := s.Refresh(ctx); err != nil {
+		log.Printf("[Recommendations] Error recalculando el modelo: %v", err)
+	}
+
+	ticker := time.NewTicker(time.Duration(hours) * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil {
+				log.Printf("[Recommendations] Error recalculando el modelo: %v", err)
+			}
+		}
+	}
+}
+
+// GetRelated obtiene los productos que se suelen comprar junto al producto dado
+func (s *RecommendationService) GetRelated(ctx context.Context, productID string, limit int) ([]*models.ProductRecommendation, error) {
+	id, err := uuid.Parse(productID)
+	if err != nil {
+		return nil, errors.New("ID de producto inválido")
+	}
+	if _, err := s.productRepo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return s.recommend(ctx, []uuid.UUID{id}, limit)
+}
+
+// GetCartSuggestions sugiere productos para completar el carrito del usuario
+// o invitado, combinando las co-compras de todo lo que ya tiene
+func (s *RecommendationService) GetCartSuggestions(ctx context.Context, owner models.CartOwner, limit int) ([]*models.ProductRecommendation, error) {
+	cart, err := s.cartRepo.GetByOwner(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener carrito: %w", err)
+	}
+
+	seeds := make([]uuid.UUID, 0, len(cart.Items))
+	seen := make(map[uuid.UUID]bool)
+	for _, item := range cart.Items {
+		if !seen[item.ProductID] {
+			seen[item.ProductID] = true
+			seeds = append(seeds, item.ProductID)
+		}
+	}
+	if len(seeds) == 0 {
+		return []*models.ProductRecommendation{}, nil
+	}
+
+	return s.recommend(ctx, seeds, limit)
+}
+
+// recommend ordena los candidatos por pedidos en común con los productos de referencia
+// y completa con la misma categoría hasta llegar al límite. Solo sugiere productos
+// activos y con stock.
+func (s *RecommendationService) recommend(ctx context.Context, seeds []uuid.UUID, limit int) ([]*models.ProductRecommendation, error) {
+	if limit <= 0 || limit > 20 {
+		limit = 4
+	}
+
+	if s.isStale() {
+		if err := s.Refresh(ctx); err != nil {
+			// Sin modelo igual se puede responder con la categoría
+			log.Printf("[Recommendations] Error recalculando el modelo: %v", err)
+		}
+	}
+
+	exclude := make(map[uuid.UUID]bool, len(seeds))
+	for _, id := range seeds {
+		exclude[id] = true
+	}
+
+	s.mu.RLock()
+	scores := make(map[uuid.UUID]int)
+	for _, seed := range seeds {
+		for other, count := range s.pairs[seed] {
+			if !exclude[other] {
+				scores[other] += count
+			}
+		}
+	}
+	popularity := s.orders
+	s.mu.RUnlock()
+
+	candidates := make([]uuid.UUID, 0, len(scores))
+	for id, score := range scores {
+		if score >= minCoPurchases {
+			candidates = append(candidates, id)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if scores[candidates[i]] != scores[candidates[j]] {
+			return scores[candidates[i]] > scores[candidates[j]]
+		}
+		return popularity[candidates[i]] > popularity[candidates[j]]
+	})
+
+	results := make([]*models.ProductRecommendation, 0, limit)
+	products, err := s.productRepo.GetByIDs(ctx, candidates)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener productos: %w", err)
+	}
+	for _, p := range products {
+		if len(results) == limit {
+			break
+		}
+		if !p.IsActive || p.Stock <= 0 {
+			continue
+		}
+		exclude[p.ID] = true
+		results = append(results, &models.ProductRecommendation{
+			Product:     p,
+			Source:      models.RecommendationBoughtTogether,
+			CoPurchases: scores[p.ID],
+		})
+	}
+	if len(results) == limit {
+		return results, nil
+	}
+
+	// Relleno: misma categoría que los productos de referencia
+	seedProducts, err := s.productRepo.GetByIDs(ctx, seeds)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener productos: %w", err)
+	}
+	categories := make(map[uuid.UUID]bool)
+	for _, p := range seedProducts {
+		if p.CategoryID != nil {
+			categories[*p.CategoryID] = true
+		}
+	}
+	if len(categories) == 0 {
+		return results, nil
+	}
+
+	all, err := s.productRepo.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener productos: %w", err)
+	}
+	fallback := make([]*models.Product, 0)
+	for _, p := range all {
+		if exclude[p.ID] || !p.IsActive || p.Stock <= 0 || p.CategoryID == nil || !categories[*p.CategoryID] {
+			continue
+		}
+		fallback = append(fallback, p)
+	}
+	sort.SliceStable(fallback, func(i, j int) bool {
+		if popularity[fallback[i].ID] != popularity[fallback[j].ID] {
+			return popularity[fallback[i].ID] > popularity[fallback[j].ID]
+		}
+		return fallback[i].Rating > fallback[j].Rating
+	})
+
+	for _, p := range fallback {
+		if len(results) == limit {
+			break
+		}
+		results = append(results, &models.ProductRecommendation{
+			Product:     p,
+			Source:      models.RecommendationSameCategory,
+			CoPurchases: scores[p.ID],
+		})
+	}
+
+	return results, nil
+}
+
+// isStale indica si el modelo nunca se calculó o ya pasó el intervalo de recálculo
+// (p. ej. si el job no está corriendo en esta instancia)
+func (s *RecommendationService) isStale() bool {
+	hours := s.cfg.RecommendationsRefreshHours
+	if hours < 1 {
+		hours = 6
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.loadedAt.IsZero() || time.Since(s.loadedAt) > time.Duration(hours)*time.Hour
+}
@@ -2,6 +2,8 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"log"
 	"math"
 
 	"github.com/cheoscafe/backend/internal/models"
@@ -12,12 +14,14 @@ import (
 type ReviewService struct {
 	reviewRepo  *repository.ReviewRepository
 	productRepo *repository.ProductRepository
+	cache       *CacheService
 }
 
-func NewReviewService(reviewRepo *repository.ReviewRepository, productRepo *repository.ProductRepository) *ReviewService {
+func NewReviewService(reviewRepo *repository.ReviewRepository, productRepo *repository.ProductRepository, cache *CacheService) *ReviewService {
 	return &ReviewService{
 		reviewRepo:  reviewRepo,
 		productRepo: productRepo,
+		cache:       cache,
 	}
 }
 
@@ -147,10 +151,94 @@ func (s *ReviewService) UpdateReview(ctx context.Context, id uuid.UUID, req *mod
 		return nil, err
 	}
 
+	s.refreshProductRating(ctx, review.ProductID)
+
 	return review, nil
 }
 
 // DeleteReview deletes a review
 func (s *ReviewService) DeleteReview(ctx context.Context, id uuid.UUID) error {
-	return s.reviewRepo.Delete(ctx, id)
+	review, err := s.reviewRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.reviewRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.refreshProductRating(ctx, review.ProductID)
+
+	return nil
+}
+
+// refreshProductRating recomputes the product's denormalized rating from its approved
+// reviews, so the catalog can filter and sort by rating without reading reviews.
+// Failures are logged: the review change itself already succeeded.
+func (s *ReviewService) refreshProductRating(ctx context.Context, productID uuid.UUID) {
+	reviews, err := s.reviewRepo.GetAllByProductID(ctx, productID)
+	if err != nil {
+		log.Printf("Warning: could not load reviews for product %s: %v", productID, err)
+		return
+	}
+
+	average, count := productRating(reviews)
+	if err := s.productRepo.UpdateRating(ctx, productID, average, count); err != nil {
+		log.Printf("Warning: could not update rating for product %s: %v", productID, err)
+		return
+	}
+	s.cache.InvalidateCatalog(ctx)
+}
+
+// productRating returns the average (one decimal) and count of the approved reviews
+func productRating(reviews []*models.Review) (float64, int) {
+	var total float64
+	count := 0
+	for _, review := range reviews {
+		if review.IsApproved {
+			total += float64(review.Rating)
+			count++
+		}
+	}
+
+	if count == 0 {
+		return 0, 0
+	}
+	return math.Round(total/float64(count)*10) / 10, count
+}
+
+// BackfillRatings recomputes the rating of every product from its approved reviews,
+// for products reviewed before ratings were denormalized. It is idempotent: only
+// products whose stored rating differs are updated. Returns how many were updated.
+func (s *ReviewService) BackfillRatings(ctx context.Context) (int, error) {
+	reviews, err := s.reviewRepo.ListAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+	byProduct := make(map[uuid.UUID][]*models.Review)
+	for _, review := range reviews {
+		byProduct[review.ProductID] = append(byProduct[review.ProductID], review)
+	}
+
+	products, err := s.productRepo.ListAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, product := range products {
+		average, count := productRating(byProduct[product.ID])
+		if product.Rating == average && product.ReviewCount == count {
+			continue
+		}
+		if err := s.productRepo.UpdateRating(ctx, product.ID, average, count); err != nil {
+			return updated, fmt.Errorf("error updating rating for product %s: %w", product.ID, err)
+		}
+		updated++
+	}
+	if updated > 0 {
+		s.cache.InvalidateCatalog(ctx)
+	}
+
+	return updated, nil
 }
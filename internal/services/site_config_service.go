@@ -9,16 +9,27 @@ import (
 )
 
 type SiteConfigService struct {
-	repo *repository.SiteConfigRepository
+	repo  *repository.SiteConfigRepository
+	cache *CacheService
 }
 
-func NewSiteConfigService(repo *repository.SiteConfigRepository) *SiteConfigService {
-	return &SiteConfigService{repo: repo}
+func NewSiteConfigService(repo *repository.SiteConfigRepository, cache *CacheService) *SiteConfigService {
+	return &SiteConfigService{repo: repo, cache: cache}
 }
 
 // GetCarouselImages obtiene las imágenes del carrusel
 func (s *SiteConfigService) GetCarouselImages(ctx context.Context) ([]string, error) {
-	return s.repo.GetCarouselImages(ctx)
+	var cached []string
+	if s.cache.Get(ctx, cacheKeyCarousel, &cached) {
+		return cached, nil
+	}
+
+	images, err := s.repo.GetCarouselImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.Set(ctx, cacheKeyCarousel, images, s.cache.ConfigTTL)
+	return images, nil
 }
 
 // SetCarouselImages guarda las imágenes del carrusel (máximo 6)
@@ -26,19 +37,30 @@ func (s *SiteConfigService) SetCarouselImages(ctx context.Context, images []stri
 	if len(images) > 6 {
 		return errors.New("máximo 6 imágenes en el carrusel")
 	}
-	return s.repo.SetCarouselImages(ctx, images)
+	if err := s.repo.SetCarouselImages(ctx, images); err != nil {
+		return err
+	}
+	s.cache.Delete(ctx, cacheKeyCarousel)
+	return nil
 }
 
 // GetAboutUs obtiene la configuración de "Sobre Nosotros"
 func (s *SiteConfigService) GetAboutUs(ctx context.Context) (*models.AboutUsConfig, error) {
+	var cached models.AboutUsConfig
+	if s.cache.Get(ctx, cacheKeyAboutUs, &cached) {
+		return &cached, nil
+	}
+
 	description, images, err := s.repo.GetAboutUs(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return &models.AboutUsConfig{
+	about := &models.AboutUsConfig{
 		Description: description,
 		Images:      images,
-	}, nil
+	}
+	s.cache.Set(ctx, cacheKeyAboutUs, about, s.cache.ConfigTTL)
+	return about, nil
 }
 
 // SetAboutUs actualiza la configuración de "Sobre Nosotros"
@@ -62,5 +84,9 @@ func (s *SiteConfigService) SetAboutUs(ctx context.Context, description *string,
 		newImages = images
 	}
 
-	return s.repo.SetAboutUs(ctx, newDesc, newImages)
+	if err := s.repo.SetAboutUs(ctx, newDesc, newImages); err != nil {
+		return err
+	}
+	s.cache.Delete(ctx, cacheKeyAboutUs)
+	return nil
 }
@@ -0,0 +1,376 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cheoscafe/backend/internal/config"
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/cheoscafe/backend/internal/repository"
+	"github.com/cheoscafe/backend/internal/utils"
+	"github.com/google/uuid"
+)
+
+// StockAlertService gestiona los avisos de "volvió a estar disponible".
+// Los suscriptores se avisan en orden de llegada y por tandas: en cada tanda se
+// avisa como máximo a tantos clientes como unidades haya (y nunca más de
+// RESTOCK_ALERT_BATCH_SIZE), y las tandas de un mismo producto se separan
+// RESTOCK_ALERT_INTERVAL_MINUTES, para que una reposición pequeña no genere una estampida.
+type StockAlertService struct {
+	subscriptionRepo    *repository.StockSubscriptionRepository
+	productRepo         *repository.ProductRepository
+	userRepo            *repository.UserRepository
+	notificationService *NotificationService
+	emailService        *EmailService
+	cfg                 *config.Config
+
+	mu        sync.Mutex           // Evita que el disparo por reposición y el job avisen dos veces
+	lastBatch map[string]time.Time // stockKey → última tanda enviada
+}
+
+func NewStockAlertService(
+	subscriptionRepo *repository.StockSubscriptionRepository,
+	productRepo *repository.ProductRepository,
+	userRepo *repository.UserRepository,
+	notificationService *NotificationService,
+	emailService *EmailService,
+	cfg *config.Config,
+) *StockAlertService {
+	return &StockAlertService{
+		subscriptionRepo:    subscriptionRepo,
+		productRepo:         productRepo,
+		userRepo:            userRepo,
+		notificationService: notificationService,
+		emailService:        emailService,
+		cfg:                 cfg,
+		lastBatch:           make(map[string]time.Time),
+	}
+}
+
+// Subscribe registra al cliente para que le avisen cuando el producto (o la variante) vuelva a tener stock.
+// Los usuarios autenticados usan el email de su cuenta; los invitados deben enviarlo.
+// Si ya existe una suscripción pendiente para el mismo email se retorna esa.
+func (s *StockAlertService) Subscribe(ctx context.Context, productID uuid.UUID, req *models.CreateStockSubscriptionRequest, userID *uuid.UUID) (*models.StockSubscription, error) {
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	product, err := s.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if !product.IsActive {
+		return nil, errors.New("producto no disponible")
+	}
+
+	sub := &models.StockSubscription{
+		ProductID:   product.ID,
+		ProductName: product.Name,
+		Email:       strings.ToLower(strings.TrimSpace(req.Email)),
+		Name:        strings.TrimSpace(req.Name),
+	}
+
+	stock := product.Stock
+	if product.HasVariants() {
+		if req.VariantID == nil {
+			return nil, errors.New("seleccione la variante de la que quiere recibir el aviso")
+		}
+		variant := product.FindVariant(*req.VariantID)
+		if variant == nil || !variant.IsActive {
+			return nil, errors.New("variante no encontrada")
+		}
+		variantID := variant.ID
+		sub.VariantID = &variantID
+		sub.VariantName = product.VariantLabel(variant)
+		stock = variant.Stock
+	}
+	if stock > 0 {
+		return nil, errors.New("el producto tiene stock disponible")
+	}
+
+	if userID != nil {
+		user, err := s.userRepo.GetByID(ctx, *userID)
+		if err != nil {
+			return nil, err
+		}
+		sub.UserID = userID
+		sub.Email = strings.ToLower(user.Email)
+		sub.Name = user.Name
+	}
+	if sub.Email == "" {
+		return nil, errors.New("el email es requerido")
+	}
+
+	pending, err := s.subscriptionRepo.ListPendingByProduct(ctx, product.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener suscripciones: %w", err)
+	}
+	key := stockKey(product.ID, sub.VariantID)
+	for _, existing := range pending {
+		if existing.Email == sub.Email && stockKey(existing.ProductID, existing.VariantID) == key {
+			sub = existing
+			break
+		}
+	}
+
+	if sub.ID == uuid.Nil {
+		if err := s.subscriptionRepo.Create(ctx, sub); err != nil {
+			return nil, fmt.Errorf("error al crear suscripción: %w", err)
+		}
+	}
+
+	// Los invitados no pueden cancelar desde su cuenta: se les entrega el token del enlace
+	sub.CancelToken, err = utils.GenerateStockAlertToken(sub.ID, s.cfg.JWTSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// GetUserSubscriptions obtiene las suscripciones pendientes del usuario
+func (s *StockAlertService) GetUserSubscriptions(ctx context.Context, userID uuid.UUID) ([]*models.StockSubscription, error) {
+	subs, err := s.subscriptionRepo.ListPendingByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener suscripciones: %w", err)
+	}
+	return subs, nil
+}
+
+// GetProductSubscriptions obtiene la cola de suscripciones pendientes de un producto (solo admin)
+func (s *StockAlertService) GetProductSubscriptions(ctx context.Context, productID uuid.UUID) ([]*models.StockSubscription, error) {
+	subs, err := s.subscriptionRepo.ListPendingByProduct(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener suscripciones: %w", err)
+	}
+	return subs, nil
+}
+
+// Unsubscribe cancela una suscripción del usuario
+func (s *StockAlertService) Unsubscribe(ctx context.Context, id, userID uuid.UUID) error {
+	sub, err := s.subscriptionRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if sub.UserID == nil || *sub.UserID != userID {
+		return errors.New("suscripción no encontrada")
+	}
+
+	return s.subscriptionRepo.Delete(ctx, id)
+}
+
+// CancelByToken cancela una suscripción con el token del enlace (no requiere sesión)
+func (s *StockAlertService) CancelByToken(ctx context.Context, token string) error {
+	id, err := utils.ValidateStockAlertToken(token, s.cfg.JWTSecret)
+	if err != nil {
+		return errors.New("enlace de cancelación inválido")
+	}
+	if _, err := s.subscriptionRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+
+	return s.subscriptionRepo.Delete(ctx, id)
+}
+
+// NotifyRestock avisa la primera tanda de suscriptores de un producto (o variante)
+// que acaba de pasar de 0 a stock positivo. Las tandas siguientes las envía el job.
+func (s *StockAlertService) NotifyRestock(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID) {
+	subs, err := s.subscriptionRepo.ListPendingByProduct(ctx, productID)
+	if err != nil {
+		log.Printf("[StockAlert] Error obteniendo suscripciones de %s: %v", productID, err)
+		return
+	}
+
+	key := stockKey(productID, variantID)
+	queue := make([]*models.StockSubscription, 0, len(subs))
+	for _, sub := range subs {
+		if stockKey(sub.ProductID, sub.VariantID) == key {
+			queue = append(queue, sub)
+		}
+	}
+	if len(queue) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	batch := s.takeBatch(ctx, productID, variantID, queue)
+	s.mu.Unlock()
+
+	s.sendBatch(ctx, batch)
+}
+
+// ProcessPending recorre todas las colas pendientes y envía la siguiente tanda
+// de las que tienen stock y ya cumplieron el intervalo desde la anterior.
+func (s *StockAlertService) ProcessPending(ctx context.Context) error {
+	subs, err := s.subscriptionRepo.ListPending(ctx)
+	if err != nil {
+		return fmt.Errorf("error al obtener suscripciones: %w", err)
+	}
+
+	// subs viene en orden FIFO, así que cada cola conserva el orden de llegada
+	keys := []string{}
+	queues := make(map[string][]*models.StockSubscription)
+	for _, sub := range subs {
+		key := stockKey(sub.ProductID, sub.VariantID)
+		if _, ok := queues[key]; !ok {
+			keys = append(keys, key)
+		}
+		queues[key] = append(queues[key], sub)
+	}
+
+	// Las tandas se eligen con el lock tomado y los emails se envían después de soltarlo
+	s.mu.Lock()
+	batches := make([]*restockBatch, 0, len(keys))
+	for _, key := range keys {
+		first := queues[key][0]
+		if batch := s.takeBatch(ctx, first.ProductID, first.VariantID, queues[key]); batch != nil {
+			batches = append(batches, batch)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, batch := range batches {
+		s.sendBatch(ctx, batch)
+	}
+	return nil
+}
+
+// StartRestockAlerts envía las tandas pendientes cada RESTOCK_ALERT_INTERVAL_MINUTES.
+// Bloquea hasta que ctx se cancele; lanzar en una goroutine.
+func (s *StockAlertService) StartRestockAlerts(ctx context.Context) {
+	ticker := time.NewTicker(s.batchInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ProcessPending(ctx); err != nil {
+				log.Printf("[StockAlert] Error procesando avisos pendientes: %v", err)
+			}
+		}
+	}
+}
+
+// restockBatch es una tanda de suscriptores ya marcados como avisados, lista para enviar
+type restockBatch struct {
+	key        string
+	product    *models.Product
+	productURL string
+	subs       []*models.StockSubscription
+	queued     int
+	stock      int
+}
+
+// takeBatch elige en orden FIFO a los primeros de la cola y los marca como avisados
+// antes de enviar: ante un fallo es preferible no avisar a avisar dos veces. Si una marca
+// falla la tanda se corta ahí. Debe llamarse con s.mu tomado; retorna nil si no toca tanda.
+func (s *StockAlertService) takeBatch(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, queue []*models.StockSubscription) *restockBatch {
+	key := stockKey(productID, variantID)
+	if last, ok := s.lastBatch[key]; ok && time.Since(last) < s.batchInterval() {
+		return nil
+	}
+
+	product, err := s.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		log.Printf("[StockAlert] Error obteniendo producto %s: %v", productID, err)
+		return nil
+	}
+	if !product.IsActive {
+		return nil
+	}
+
+	stock := product.Stock
+	if variantID != nil {
+		variant := product.FindVariant(*variantID)
+		if variant == nil || !variant.IsActive {
+			return nil
+		}
+		stock = variant.Stock
+	}
+	if stock <= 0 {
+		return nil
+	}
+
+	limit := min(stock, len(queue))
+	if s.cfg.RestockAlertBatchSize > 0 {
+		limit = min(limit, s.cfg.RestockAlertBatchSize)
+	}
+
+	productURL := product.CanonicalURL
+	if productURL == "" {
+		productURL = s.cfg.FrontendURL + "/productos/" + product.Slug
+	}
+
+	batch := &restockBatch{key: key, product: product, productURL: productURL, queued: len(queue), stock: stock}
+	now := time.Now()
+	for _, sub := range queue[:limit] {
+		if err := s.subscriptionRepo.MarkSent(ctx, sub.ID, now); err != nil {
+			log.Printf("[StockAlert] Error marcando suscripción %s como avisada, se corta la tanda: %v", sub.ID, err)
+			break
+		}
+		batch.subs = append(batch.subs, sub)
+	}
+
+	s.lastBatch[key] = now
+	return batch
+}
+
+// sendBatch avisa a los suscriptores de una tanda. Se llama sin s.mu tomado.
+func (s *StockAlertService) sendBatch(ctx context.Context, batch *restockBatch) {
+	if batch == nil {
+		return
+	}
+
+	sent := 0
+	for _, sub := range batch.subs {
+		if s.notify(ctx, batch.product, sub, batch.productURL) {
+			sent++
+		}
+	}
+
+	log.Printf("[StockAlert] %s: %d de %d suscriptores avisados (stock %d)", batch.key, sent, batch.queued, batch.stock)
+}
+
+// notify envía el email y, si el suscriptor está registrado, la notificación in-app.
+// Retorna true si al menos uno de los dos canales funcionó.
+func (s *StockAlertService) notify(ctx context.Context, product *models.Product, sub *models.StockSubscription, productURL string) bool {
+	name := product.Name
+	if sub.VariantName != "" {
+		name = fmt.Sprintf("%s (%s)", product.Name, sub.VariantName)
+	}
+
+	delivered := false
+	if sub.UserID != nil && s.notificationService != nil {
+		if err := s.notificationService.CreateBackInStockNotification(ctx, *sub.UserID, product.ID, name); err != nil {
+			log.Printf("[StockAlert] Error creando notificación para %s: %v", sub.UserID, err)
+		} else {
+			delivered = true
+		}
+	}
+
+	if s.emailService != nil && sub.Email != "" {
+		// Sin enlace para cancelar: el aviso ya se cumplió con este email
+		if err := s.emailService.SendBackInStockEmail(sub.Email, sub.Name, name, productURL); err != nil {
+			log.Printf("[StockAlert] Error enviando aviso de reposición a %s: %v", sub.Email, err)
+		} else {
+			delivered = true
+		}
+	}
+
+	return delivered
+}
+
+func (s *StockAlertService) batchInterval() time.Duration {
+	minutes := s.cfg.RestockAlertIntervalMinutes
+	if minutes < 1 {
+		minutes = 15
+	}
+	return time.Duration(minutes) * time.Minute
+}
@@ -0,0 +1,445 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/cheoscafe/backend/internal/config"
+	"github.com/cheoscafe/backend/internal/models"
+	"github.com/cheoscafe/backend/internal/repository"
+	"github.com/cheoscafe/backend/internal/utils"
+	"github.com/google/uuid"
+)
+
+const maxWishlistItems = 100
+
+// WishlistService gestiona las listas de deseos. Además de guardarlas, avisa al
+// dueño (notificación in-app) cuando un producto de su lista entra en oferta o
+// vuelve a tener stock.
+type WishlistService struct {
+	wishlistRepo        *repository.WishlistRepository
+	productRepo         *repository.ProductRepository
+	userRepo            *repository.UserRepository
+	notificationService *NotificationService
+	cfg                 *config.Config
+}
+
+func NewWishlistService(
+	wishlistRepo *repository.WishlistRepository,
+	productRepo *repository.ProductRepository,
+	userRepo *repository.UserRepository,
+	notificationService *NotificationService,
+	cfg *config.Config,
+) *WishlistService {
+	return &WishlistService{
+		wishlistRepo:        wishlistRepo,
+		productRepo:         productRepo,
+		userRepo:            userRepo,
+		notificationService: notificationService,
+		cfg:                 cfg,
+	}
+}
+
+// GetWishlist obtiene la lista del usuario con precio y stock actuales
+func (s *WishlistService) GetWishlist(ctx context.Context, userID uuid.UUID) (*models.WishlistResponse, error) {
+	wishlist, err := s.wishlistRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener lista de deseos: %w", err)
+	}
+	return s.buildResponse(ctx, wishlist)
+}
+
+// AddItem agrega un producto (o una variante concreta) a la lista. Agregarlo dos veces no lo duplica.
+func (s *WishlistService) AddItem(ctx context.Context, userID uuid.UUID, req *models.AddWishlistItemRequest) (*models.WishlistResponse, error) {
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	product, err := s.productRepo.GetByID(ctx, req.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if !product.IsActive {
+		return nil, errors.New("producto no disponible")
+	}
+	if req.VariantID != nil {
+		if !product.HasVariants() {
+			return nil, fmt.Errorf("el producto %s no tiene variantes", product.Name)
+		}
+		if variant := product.FindVariant(*req.VariantID); variant == nil || !variant.IsActive {
+			return nil, errors.New("variante no encontrada")
+		}
+	}
+
+	wishlist, err := s.wishlistRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener lista de deseos: %w", err)
+	}
+
+	for _, item := range wishlist.Items {
+		if item.Matches(req.ProductID, req.VariantID) {
+			return s.buildResponse(ctx, wishlist)
+		}
+	}
+	if len(wishlist.Items) >= maxWishlistItems {
+		return nil, fmt.Errorf("la lista de deseos admite máximo %d productos", maxWishlistItems)
+	}
+
+	wishlist.Items = append(wishlist.Items, models.WishlistItem{
+		ProductID: req.ProductID,
+		VariantID: req.VariantID,
+		AddedAt:   time.Now(),
+	})
+	if err := s.wishlistRepo.Save(ctx, wishlist); err != nil {
+		return nil, fmt.Errorf("error al guardar lista de deseos: %w", err)
+	}
+
+	return s.buildResponse(ctx, wishlist)
+}
+
+// RemoveItem quita un producto (o variante) de la lista
+func (s *WishlistService) RemoveItem(ctx context.Context, userID, productID uuid.UUID, variantID *uuid.UUID) (*models.WishlistResponse, error) {
+	wishlist, err := s.wishlistRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener lista de deseos: %w", err)
+	}
+
+	items := make([]models.WishlistItem, 0, len(wishlist.Items))
+	for _, item := range wishlist.Items {
+		if !item.Matches(productID, variantID) {
+			items = append(items, item)
+		}
+	}
+	if len(items) == len(wishlist.Items) {
+		return nil, errors.New("el producto no está en la lista de deseos")
+	}
+
+	wishlist.Items = items
+	if err := s.wishlistRepo.Save(ctx, wishlist); err != nil {
+		return nil, fmt.Errorf("error al guardar lista de deseos: %w", err)
+	}
+
+	return s.buildResponse(ctx, wishlist)
+}
+
+// EnableSharing genera el enlace público de la lista. Si ya estaba compartida conserva el enlace.
+func (s *WishlistService) EnableSharing(ctx context.Context, userID uuid.UUID) (*models.WishlistResponse, error) {
+	wishlist, err := s.wishlistRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener lista de deseos: %w", err)
+	}
+
+	if wishlist.ShareToken == "" {
+		token, err := newShareToken()
+		if err != nil {
+			return nil, err
+		}
+		wishlist.ShareToken = token
+		if err := s.wishlistRepo.Save(ctx, wishlist); err != nil {
+			return nil, fmt.Errorf("error al guardar lista de deseos: %w", err)
+		}
+	}
+
+	return s.buildResponse(ctx, wishlist)
+}
+
+// DisableSharing revoca el enlace público; el enlace anterior deja de funcionar
+func (s *WishlistService) DisableSharing(ctx context.Context, userID uuid.UUID) error {
+	wishlist, err := s.wishlistRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error al obtener lista de deseos: %w", err)
+	}
+	if wishlist.ShareToken == "" {
+		return nil
+	}
+
+	wishlist.ShareToken = ""
+	if err := s.wishlistRepo.Save(ctx, wishlist); err != nil {
+		return fmt.Errorf("error al guardar lista de deseos: %w", err)
+	}
+	return nil
+}
+
+// GetSharedWishlist obtiene una lista compartida por su enlace público.
+// Del dueño solo se expone el primer nombre.
+func (s *WishlistService) GetSharedWishlist(ctx context.Context, token string) (*models.WishlistResponse, error) {
+	if token == "" {
+		return nil, errors.New("lista de deseos no encontrada")
+	}
+
+	wishlist, err := s.wishlistRepo.GetByShareToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.buildResponse(ctx, wishlist)
+	if err != nil {
+		return nil, err
+	}
+
+	if user, err := s.userRepo.GetByID(ctx, wishlist.UserID); err == nil {
+		if fields := strings.Fields(user.Name); len(fields) > 0 {
+			response.OwnerName = fields[0]
+		}
+	}
+	return response, nil
+}
+
+// NotifyBackInStock avisa a quienes tienen el producto en su lista que volvió a tener stock.
+// Los items de una variante se avisan cuando esa variante se repone; los del producto
+// completo, solo si el producto estaba agotado (productRestocked).
+func (s *WishlistService) NotifyBackInStock(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, productRestocked bool) {
+	if s.notificationService == nil {
+		return
+	}
+
+	wishlists, err := s.wishlistRepo.ListContainingProduct(ctx, productID)
+	if err != nil {
+		log.Printf("[Wishlist] Error obteniendo listas de %s: %v", productID, err)
+		return
+	}
+	if len(wishlists) == 0 {
+		return
+	}
+
+	product, err := s.productRepo.GetByID(ctx, productID)
+	if err != nil || !product.IsActive {
+		return
+	}
+
+	for _, wishlist := range wishlists {
+		for _, item := range wishlist.Items {
+			if item.ProductID != productID {
+				continue
+			}
+			if item.VariantID == nil && !productRestocked {
+				continue
+			}
+			if item.VariantID != nil && (variantID == nil || *item.VariantID != *variantID) {
+				continue
+			}
+
+			if err := s.notificationService.CreateBackInStockNotification(ctx, wishlist.UserID, productID, wishlistItemName(product, &item)); err != nil {
+				log.Printf("[Wishlist] Error avisando reposición a %s: %v", wishlist.UserID, err)
+			}
+		}
+	}
+}
+
+// NotifySales revisa si el producto entró en oferta y avisa a quienes lo tienen en su lista.
+// Se llama al editar un producto; las ofertas programadas las recoge el job.
+func (s *WishlistService) NotifySales(ctx context.Context, productID uuid.UUID) {
+	wishlists, err := s.wishlistRepo.ListContainingProduct(ctx, productID)
+	if err != nil {
+		log.Printf("[Wishlist] Error obteniendo listas de %s: %v", productID, err)
+		return
+	}
+	if len(wishlists) == 0 {
+		return
+	}
+
+	product, err := s.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		return
+	}
+
+	products := map[uuid.UUID]*models.Product{product.ID: product}
+	for _, wishlist := range wishlists {
+		s.applySaleAlerts(ctx, wishlist, products)
+	}
+}
+
+// CheckSales recorre todas las listas y avisa las ofertas que empezaron desde la última revisión
+func (s *WishlistService) CheckSales(ctx context.Context) error {
+	wishlists, err := s.wishlistRepo.ListAll(ctx)
+	if err != nil {
+		return fmt.Errorf("error al obtener listas de deseos: %w", err)
+	}
+
+	ids := []uuid.UUID{}
+	seen := make(map[uuid.UUID]bool)
+	for _, wishlist := range wishlists {
+		for _, item := range wishlist.Items {
+			if !seen[item.ProductID] {
+				seen[item.ProductID] = true
+				ids = append(ids, item.ProductID)
+			}
+		}
+	}
+
+	list, err := s.productRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("error al obtener productos: %w", err)
+	}
+	products := make(map[uuid.UUID]*models.Product, len(list))
+	for _, p := range list {
+		products[p.ID] = p
+	}
+
+	for _, wishlist := range wishlists {
+		s.applySaleAlerts(ctx, wishlist, products)
+	}
+	return nil
+}
+
+// StartSaleAlerts revisa las ofertas cada WISHLIST_SALE_CHECK_MINUTES, para avisar
+// también las programadas (que empiezan sin que nadie edite el producto).
+// Bloquea hasta que ctx se cancele; lanzar en una goroutine.
+func (s *WishlistService) StartSaleAlerts(ctx context.Context) {
+	minutes := s.cfg.WishlistSaleCheckMinutes
+	if minutes < 1 {
+		log.Printf("[Wishlist] Avisos de ofertas programadas desactivados")
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(minutes) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.CheckSales(ctx); err != nil {
+				log.Printf("[Wishlist] Error revisando ofertas: %v", err)
+			}
+		}
+	}
+}
+
+// applySaleAlerts avisa los items en oferta que aún no se avisaron a ese precio y
+// olvida el aviso de los que ya no están en oferta. Guarda solo los avisos que cambiaron.
+func (s *WishlistService) applySaleAlerts(ctx context.Context, wishlist *models.Wishlist, products map[uuid.UUID]*models.Product) {
+	if s.notificationService == nil {
+		return
+	}
+
+	var updates []models.WishlistItem
+	for i := range wishlist.Items {
+		item := &wishlist.Items[i]
+		product, ok := products[item.ProductID]
+		if !ok {
+			continue
+		}
+
+		view := wishlistItemView(product, item)
+		if !view.OnSale || !view.Available {
+			if item.SaleNotifiedFor != nil && !view.OnSale {
+				updates = append(updates, models.WishlistItem{ProductID: item.ProductID, VariantID: item.VariantID})
+			}
+			continue
+		}
+		if item.SaleNotifiedFor != nil && *item.SaleNotifiedFor == view.EffectivePrice {
+			continue
+		}
+
+		if err := s.notificationService.CreateWishlistSaleNotification(ctx, wishlist.UserID, product.ID, wishlistItemName(product, item), view.EffectivePrice); err != nil {
+			log.Printf("[Wishlist] Error avisando oferta a %s: %v", wishlist.UserID, err)
+			continue
+		}
+		price := view.EffectivePrice
+		updates = append(updates, models.WishlistItem{ProductID: item.ProductID, VariantID: item.VariantID, SaleNotifiedFor: &price})
+	}
+
+	// Corre en segundo plano: solo se tocan los avisos, no la lista completa,
+	// para no pisar lo que el usuario agregó o quitó mientras tanto
+	if len(updates) > 0 {
+		if err := s.wishlistRepo.SetSaleNotified(ctx, wishlist.UserID, updates); err != nil {
+			log.Printf("[Wishlist] Error guardando avisos de %s: %v", wishlist.UserID, err)
+		}
+	}
+}
+
+// buildResponse enriquece los items con el estado actual de cada producto.
+// Los productos eliminados se omiten.
+func (s *WishlistService) buildResponse(ctx context.Context, wishlist *models.Wishlist) (*models.WishlistResponse, error) {
+	ids := make([]uuid.UUID, 0, len(wishlist.Items))
+	for _, item := range wishlist.Items {
+		ids = append(ids, item.ProductID)
+	}
+
+	products, err := s.productRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener productos: %w", err)
+	}
+	byID := make(map[uuid.UUID]*models.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+
+	response := &models.WishlistResponse{
+		Items:     []models.WishlistItemView{},
+		UpdatedAt: wishlist.UpdatedAt,
+	}
+	for i := range wishlist.Items {
+		if product, ok := byID[wishlist.Items[i].ProductID]; ok {
+			response.Items = append(response.Items, wishlistItemView(product, &wishlist.Items[i]))
+		}
+	}
+	if wishlist.ShareToken != "" {
+		response.ShareURL = s.cfg.FrontendURL + "/favoritos/" + wishlist.ShareToken
+	}
+
+	return response, nil
+}
+
+// wishlistItemView arma la vista de un item con el precio y stock vigentes.
+// Sin variante, usa los agregados del producto (precio "desde" y stock total).
+func wishlistItemView(product *models.Product, item *models.WishlistItem) models.WishlistItemView {
+	view := models.WishlistItemView{
+		ProductID:      product.ID,
+		VariantID:      item.VariantID,
+		ProductName:    product.Name,
+		Slug:           product.Slug,
+		Price:          product.Price,
+		EffectivePrice: product.EffectivePrice,
+		OnSale:         product.OnSale,
+		Stock:          product.Stock,
+		Available:      product.IsActive && product.Stock > 0,
+		AddedAt:        item.AddedAt,
+	}
+	if len(product.Images) > 0 {
+		view.Image = product.Images[0]
+	}
+
+	if item.VariantID != nil {
+		variant := product.FindVariant(*item.VariantID)
+		if variant == nil {
+			view.Stock = 0
+			view.Available = false
+			return view
+		}
+		view.VariantName = product.VariantLabel(variant)
+		view.Price = variant.Price
+		view.EffectivePrice = variant.EffectivePrice
+		view.OnSale = variant.OnSale
+		view.Stock = variant.Stock
+		view.Available = product.IsActive && variant.IsActive && variant.Stock > 0
+	}
+	return view
+}
+
+// wishlistItemName arma el nombre del item para los avisos. Ej: "Café Origen (500 g / Molido)"
+func wishlistItemName(product *models.Product, item *models.WishlistItem) string {
+	if item.VariantID != nil {
+		if variant := product.FindVariant(*item.VariantID); variant != nil {
+			return fmt.Sprintf("%s (%s)", product.Name, product.VariantLabel(variant))
+		}
+	}
+	return product.Name
+}
+
+// newShareToken genera el token aleatorio del enlace público
+func newShareToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error al generar enlace: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
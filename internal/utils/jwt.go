@@ -2,12 +2,27 @@ package utils
 
 import (
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
+// Audiencias de los tokens firmados con el mismo secreto. Cada validación exige la
+// suya; los tokens emitidos antes de existir las audiencias no traen ninguna y se
+// aceptan donde corresponde hasta que vencen (ver ValidateToken).
+const (
+	audienceSession       = "session"
+	audiencePasswordReset = "password_reset"
+	audienceCart          = "cart"
+	audienceUnsubscribe   = "unsubscribe"
+	audienceStockAlert    = "stock_alert"
+)
+
+// rolePasswordReset es el rol de los tokens de recuperación de contraseña
+const rolePasswordReset = "password_reset"
+
 type Claims struct {
 	UserID uuid.UUID `json:"user_id"`
 	Email  string    `json:"email"`
@@ -17,11 +32,22 @@ type Claims struct {
 
 // GenerateToken genera un token JWT
 func GenerateToken(userID uuid.UUID, email, role, secret string, duration time.Duration) (string, error) {
+	return generateUserToken(userID, email, role, audienceSession, secret, duration)
+}
+
+// GeneratePasswordResetToken genera el token del enlace de recuperación de contraseña.
+// Tiene su propia audiencia: no sirve como token de sesión.
+func GeneratePasswordResetToken(userID uuid.UUID, email, secret string, duration time.Duration) (string, error) {
+	return generateUserToken(userID, email, rolePasswordReset, audiencePasswordReset, secret, duration)
+}
+
+func generateUserToken(userID uuid.UUID, email, role, audience, secret string, duration time.Duration) (string, error) {
 	claims := Claims{
 		UserID: userID,
 		Email:  email,
 		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{audience},
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
@@ -31,8 +57,39 @@ func GenerateToken(userID uuid.UUID, email, role, secret string, duration time.D
 	return token.SignedString([]byte(secret))
 }
 
-// ValidateToken valida un token JWT
+// ValidateToken valida un token JWT de sesión.
+// Los tokens emitidos antes de existir la audiencia no la traen y se aceptan hasta que
+// vencen (los refresh duran días); los de recuperación de contraseña se rechazan siempre.
 func ValidateToken(tokenString, secret string) (*Claims, error) {
+	claims, err := parseUserToken(tokenString, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Role != rolePasswordReset &&
+		(len(claims.Audience) == 0 || slices.Contains(claims.Audience, audienceSession)) {
+		return claims, nil
+	}
+
+	return nil, errors.New("token inválido")
+}
+
+// ValidatePasswordResetToken valida un token de recuperación de contraseña.
+// Los emitidos antes de su audiencia propia se reconocen por el rol (vencen a los 15 minutos).
+func ValidatePasswordResetToken(tokenString, secret string) (*Claims, error) {
+	claims, err := parseUserToken(tokenString, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Role == rolePasswordReset {
+		return claims, nil
+	}
+
+	return nil, errors.New("token de recuperación inválido")
+}
+
+func parseUserToken(tokenString, secret string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("método de firma inválido")
@@ -44,13 +101,138 @@ func ValidateToken(tokenString, secret string) (*Claims, error) {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && claims.UserID != uuid.Nil {
 		return claims, nil
 	}
 
 	return nil, errors.New("token inválido")
 }
 
+// CartClaims identifica el carrito de un invitado
+type CartClaims struct {
+	CartID uuid.UUID `json:"cart_id"`
+	jwt.RegisteredClaims
+}
+
+// GenerateCartToken genera el token firmado de un carrito de invitado
+func GenerateCartToken(cartID uuid.UUID, secret string, duration time.Duration) (string, error) {
+	claims := CartClaims{
+		CartID: cartID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{audienceCart},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+// ValidateCartToken valida un token de carrito y retorna el ID del carrito
+func ValidateCartToken(tokenString, secret string) (uuid.UUID, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &CartClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("método de firma inválido")
+		}
+		return []byte(secret), nil
+	}, jwt.WithAudience(audienceCart))
+
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if claims, ok := token.Claims.(*CartClaims); ok && token.Valid && claims.CartID != uuid.Nil {
+		return claims.CartID, nil
+	}
+
+	return uuid.Nil, errors.New("token de carrito inválido")
+}
+
+// UnsubscribeClaims identifica al usuario en los enlaces de baja de correos comerciales
+type UnsubscribeClaims struct {
+	UnsubscribeUserID uuid.UUID `json:"unsubscribe_user_id"`
+	jwt.RegisteredClaims
+}
+
+// GenerateUnsubscribeToken genera el token del enlace de baja. No vence: el enlace
+// de un correo viejo debe seguir funcionando.
+func GenerateUnsubscribeToken(userID uuid.UUID, secret string) (string, error) {
+	claims := UnsubscribeClaims{
+		UnsubscribeUserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience: jwt.ClaimStrings{audienceUnsubscribe},
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+// ValidateUnsubscribeToken valida un token de baja y retorna el ID del usuario
+func ValidateUnsubscribeToken(tokenString, secret string) (uuid.UUID, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &UnsubscribeClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("método de firma inválido")
+		}
+		return []byte(secret), nil
+	})
+
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	// Los enlaces enviados antes de existir la audiencia no la traen y deben seguir funcionando
+	if claims, ok := token.Claims.(*UnsubscribeClaims); ok && token.Valid && claims.UnsubscribeUserID != uuid.Nil &&
+		(len(claims.Audience) == 0 || slices.Contains(claims.Audience, audienceUnsubscribe)) {
+		return claims.UnsubscribeUserID, nil
+	}
+
+	return uuid.Nil, errors.New("token de baja inválido")
+}
+
+// StockAlertClaims identifica la suscripción en los enlaces para cancelar un aviso de reposición
+type StockAlertClaims struct {
+	StockSubscriptionID uuid.UUID `json:"stock_subscription_id"`
+	jwt.RegisteredClaims
+}
+
+// GenerateStockAlertToken genera el token del enlace para cancelar un aviso de reposición.
+// No vence: el aviso puede quedar pendiente por meses.
+func GenerateStockAlertToken(subscriptionID uuid.UUID, secret string) (string, error) {
+	claims := StockAlertClaims{
+		StockSubscriptionID: subscriptionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience: jwt.ClaimStrings{audienceStockAlert},
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+// ValidateStockAlertToken valida un token de aviso de reposición y retorna el ID de la suscripción
+func ValidateStockAlertToken(tokenString, secret string) (uuid.UUID, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &StockAlertClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("método de firma inválido")
+		}
+		return []byte(secret), nil
+	}, jwt.WithAudience(audienceStockAlert))
+
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if claims, ok := token.Claims.(*StockAlertClaims); ok && token.Valid && claims.StockSubscriptionID != uuid.Nil {
+		return claims.StockSubscriptionID, nil
+	}
+
+	return uuid.Nil, errors.New("token de aviso inválido")
+}
+
 // ParseDuration convierte string a time.Duration
 func ParseDuration(duration string) (time.Duration, error) {
 	return time.ParseDuration(duration)
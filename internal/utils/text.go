@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// accentFold mapea letras acentuadas a su forma base
+var accentFold = map[rune]rune{
+	'á': 'a', 'à': 'a', 'ä': 'a', 'â': 'a', 'ã': 'a',
+	'é': 'e', 'è': 'e', 'ë': 'e', 'ê': 'e',
+	'í': 'i', 'ì': 'i', 'ï': 'i', 'î': 'i',
+	'ó': 'o', 'ò': 'o', 'ö': 'o', 'ô': 'o', 'õ': 'o',
+	'ú': 'u', 'ù': 'u', 'ü': 'u', 'û': 'u',
+	'ñ': 'n', 'ç': 'c',
+}
+
+// spanishStopwords son palabras demasiado comunes para aportar a una búsqueda
+var spanishStopwords = map[string]bool{
+	"a": true, "al": true, "con": true, "de": true, "del": true, "el": true,
+	"en": true, "es": true, "la": true, "las": true, "lo": true, "los": true,
+	"o": true, "para": true, "por": true, "que": true, "se": true, "sin": true,
+	"su": true, "sus": true, "un": true, "una": true, "uno": true, "unos": true,
+	"unas": true, "y": true, "e": true, "u": true,
+}
+
+// NormalizeText pasa el texto a minúsculas y elimina tildes.
+// Ej: "Café Señorial" → "cafe senorial"
+func NormalizeText(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for _, r := range strings.ToLower(text) {
+		if folded, ok := accentFold[r]; ok {
+			r = folded
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Tokenize normaliza el texto y lo divide en palabras, descartando
+// signos de puntuación y stopwords en español.
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(NormalizeText(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	tokens := make([]string, 0, len(words))
+	for _, w := range words {
+		if spanishStopwords[w] {
+			continue
+		}
+		tokens = append(tokens, w)
+	}
+	return tokens
+}
+
+// Slugify convierte un texto en un slug para URLs: minúsculas, sin tildes
+// y con guiones entre palabras. Ej: "Café de Origen" → "cafe-de-origen"
+func Slugify(text string) string {
+	words := strings.FieldsFunc(NormalizeText(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9')
+	})
+	return strings.Join(words, "-")
+}
@@ -84,7 +84,7 @@ func getFieldMessage(field string, tag string, param string) string {
 
 	// Mensajes genéricos por tag (fallback para otros modelos)
 	switch tag {
-	case "required":
+	case "required", "required_with", "required_without":
 		return "Este campo es obligatorio"
 	case "email":
 		return "Debe ser un correo electrónico válido"